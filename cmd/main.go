@@ -1,7 +1,10 @@
 package main
 
 import (
+    "context"
     "database/sql"
+    "errors"
+    "flag"
     "fmt"
     "go.uber.org/zap"
     "log"
@@ -9,14 +12,23 @@ import (
     "music-library/internal/config"
     "music-library/internal/repository"
     "music-library/internal/service"
+    "net/http"
+    "os"
+    "os/signal"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
 
-    _ "github.com/lib/pq"
     "github.com/golang-migrate/migrate/v4"
-    "github.com/golang-migrate/migrate/v4/database/postgres"
-    _ "github.com/golang-migrate/migrate/v4/source/file"
+    _ "github.com/lib/pq"
     _ "music-library/docs" // This line is important for swagger
 )
 
+// shutdownTimeout bounds how long the server waits for requests in flight
+// when it is stopped.
+const shutdownTimeout = 30 * time.Second
+
 // @title           Music Library API
 // @version         1.0
 // @description     A REST API for managing a music library
@@ -24,6 +36,10 @@ import (
 // @BasePath        /api/v1
 
 func main() {
+    storeKind := flag.String("store", "postgres", "song storage backend: postgres or memory")
+    flag.Parse()
+    args := flag.Args()
+
     // Load configuration
     cfg, err := config.LoadConfig()
     if err != nil {
@@ -37,43 +53,178 @@ func main() {
     }
     defer logger.Sync()
 
-    // Connect to database
-    db, err := sql.Open("postgres", cfg.GetDBConnString())
-    if err != nil {
-        logger.Fatal("Failed to connect to database", zap.Error(err))
+    // Run migrations as a standalone command: `main migrate up|down|goto N|version|force N`
+    if len(args) > 0 && args[0] == "migrate" {
+        db := openDatabase(cfg, logger)
+        defer db.Close()
+        if err := runMigrate(db, args[1:], logger); err != nil {
+            logger.Fatal("Failed to run migrations", zap.Error(err))
+        }
+        return
     }
-    defer db.Close()
 
-    // Run migrations
-    driver, err := postgres.WithInstance(db, &postgres.Config{})
-    if err != nil {
-        logger.Fatal("Failed to create migration driver", zap.Error(err))
+    var songRepo repository.SongStore
+    var enrichmentRepo repository.EnrichmentStore
+    var songInfoCacheRepo repository.SongInfoCacheStore
+    var artistRepo repository.ArtistStore
+    var albumRepo repository.AlbumStore
+    var playlistRepo repository.PlaylistStore
+    healthService := service.NewHealthService(logger)
+    switch *storeKind {
+    case "memory":
+        logger.Warn("Using in-memory song store, data will not be persisted")
+        memoryDB := repository.NewMemoryDB()
+        songRepo = repository.NewMemorySongRepository(memoryDB)
+        enrichmentRepo = repository.NewMemoryEnrichmentRepository(memoryDB)
+        artistRepo = repository.NewMemoryArtistRepository(memoryDB)
+        albumRepo = repository.NewMemoryAlbumRepository(memoryDB)
+        playlistRepo = repository.NewMemoryPlaylistRepository(memoryDB)
+    case "postgres":
+        db := openDatabase(cfg, logger)
+        defer db.Close()
+
+        if cfg.AutoMigrate {
+            m, err := newMigrate(db)
+            if err != nil {
+                logger.Fatal("Failed to create migration instance", zap.Error(err))
+            }
+            if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+                logger.Fatal("Failed to run migrations", zap.Error(err))
+            }
+        }
+
+        songRepo = repository.NewSongRepository(db)
+        enrichmentRepo = repository.NewEnrichmentRepository(db)
+        artistRepo = repository.NewArtistRepository(db)
+        albumRepo = repository.NewAlbumRepository(db)
+        playlistRepo = repository.NewPlaylistRepository(db)
+        healthService.Register("database", repository.NewDatabaseHealth(db))
+        if cfg.SongInfoCacheShared {
+            songInfoCacheRepo = repository.NewSongInfoCacheRepository(db)
+        }
+    default:
+        logger.Fatal("Unknown store", zap.String("store", *storeKind))
     }
 
-    m, err := migrate.NewWithDatabaseInstance(
-        "file://D:/zadanie/migrations",
-        "postgres",
-        driver,
-    )
+    // Initialize components
+    musicAPIConfig := service.DefaultMusicAPIConfig()
+    musicAPIConfig.Timeout = cfg.MusicAPITimeout
+    musicAPIConfig.MaxRetries = cfg.MusicAPIMaxRetries
+    musicAPIConfig.BreakerThreshold = cfg.MusicAPIBreakerThreshold
+    musicAPIConfig.BreakerCooldown = cfg.MusicAPIBreakerCooldown
+    musicAPIClient := service.NewMusicAPIClient(cfg.MusicAPIURL, musicAPIConfig)
+    healthService.Register("music_api", musicAPIClient)
+    cachedMusicAPI := service.NewCachedSongInfoClient(musicAPIClient, cfg.SongInfoCacheSize,
+        cfg.SongInfoCacheTTL, cfg.SongInfoCacheNegativeTTL, songInfoCacheRepo, logger)
+    healthService.Register("song_info_cache", cachedMusicAPI)
+    songInfoChain, err := newSongInfoChain(cfg, cachedMusicAPI, logger)
     if err != nil {
-        logger.Fatal("Failed to create migration instance", zap.Error(err))
+        logger.Fatal("Failed to set up song info providers", zap.Error(err))
     }
+    enrichmentService := service.NewEnrichmentService(enrichmentRepo, songInfoChain, cfg.EnrichWorkers, cfg.EnrichMaxAttempts, logger)
+    songService := service.NewSongService(songRepo, enrichmentService, logger)
+    artistService := service.NewArtistService(artistRepo, songService, logger)
+    albumService := service.NewAlbumService(albumRepo, logger)
+    playlistService := service.NewPlaylistService(playlistRepo, logger)
+    handler := api.NewHandler(songService, enrichmentService, artistService, albumService, playlistService, healthService, logger)
+    router := api.SetupRouter(handler, cfg.AdminToken)
 
-    if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-        logger.Fatal("Failed to run migrations", zap.Error(err))
-    }
+    // Stop on SIGINT or SIGTERM
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    var background sync.WaitGroup
 
-    // Initialize components
-    songRepo := repository.NewSongRepository(db)
-    musicAPIClient := service.NewMusicAPIClient(cfg.MusicAPIURL)
-    songService := service.NewSongService(songRepo, musicAPIClient, logger)
-    handler := api.NewHandler(songService, logger)
-    router := api.SetupRouter(handler)
+    // Enrich new songs in the background
+    background.Add(1)
+    go func() {
+        defer background.Done()
+        enrichmentService.Run(ctx)
+    }()
+
+    // Purge songs that have been in the trash for too long
+    if cfg.TrashRetentionDays > 0 {
+        background.Add(1)
+        go func() {
+            defer background.Done()
+            songService.RunTrashPurge(ctx, time.Duration(cfg.TrashRetentionDays)*24*time.Hour, cfg.TrashPurgeInterval)
+        }()
+    }
 
     // Start server
     addr := fmt.Sprintf(":%s", cfg.ServerPort)
+    server := &http.Server{Addr: addr, Handler: router}
+    serveErr := make(chan error, 1)
+    go func() {
+        serveErr <- server.ListenAndServe()
+    }()
     logger.Info("Starting server", zap.String("addr", addr))
-    if err := router.Run(addr); err != nil {
+
+    select {
+    case err = <-serveErr:
+    case <-ctx.Done():
+    }
+    stop()
+
+    // Finish the requests in flight, then let the enrichment workers hand
+    // back the jobs they hold before the database is closed.
+    logger.Info("Shutting down")
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        logger.Error("Failed to shut down server", zap.Error(err))
+    }
+    background.Wait()
+    if err != nil {
         logger.Fatal("Failed to start server", zap.Error(err))
     }
 }
+
+func openDatabase(cfg *config.Config, logger *zap.Logger) *sql.DB {
+    db, err := sql.Open("postgres", cfg.GetDBConnString())
+    if err != nil {
+        logger.Fatal("Failed to connect to database", zap.Error(err))
+    }
+    return db
+}
+
+// newSongInfoChain builds the providers named in SONG_INFO_PROVIDERS, in
+// order, and merges them with the precedence in SONG_INFO_PRECEDENCE.
+func newSongInfoChain(cfg *config.Config, musicAPI service.SongInfoProvider, logger *zap.Logger) (*service.SongInfoChain, error) {
+    var providers []service.SongInfoProvider
+    for _, name := range strings.Split(cfg.SongInfoProviders, ",") {
+        switch name = strings.TrimSpace(name); name {
+        case "":
+            continue
+        case "music_api":
+            providers = append(providers, musicAPI)
+        case "catalogue":
+            if cfg.SongInfoCatalogue == "" {
+                return nil, errors.New("SONG_INFO_CATALOGUE must be set to use the catalogue provider")
+            }
+            catalogue, err := service.NewCatalogueProvider(cfg.SongInfoCatalogue)
+            if err != nil {
+                return nil, err
+            }
+            logger.Info("Loaded song catalogue", zap.String("path", cfg.SongInfoCatalogue), zap.Int("songs", catalogue.Len()))
+            providers = append(providers, catalogue)
+        case "musicbrainz":
+            if cfg.SongInfoMusicBrainz == "" {
+                return nil, errors.New("SONG_INFO_MUSICBRAINZ_DUMP must be set to use the musicbrainz provider")
+            }
+            dump, err := service.NewMusicBrainzProvider(cfg.SongInfoMusicBrainz)
+            if err != nil {
+                return nil, err
+            }
+            logger.Info("Loaded MusicBrainz dump", zap.String("path", cfg.SongInfoMusicBrainz), zap.Int("songs", dump.Len()))
+            providers = append(providers, dump)
+        default:
+            return nil, fmt.Errorf("unknown song info provider %q", name)
+        }
+    }
+
+    precedence, err := service.ParseSongInfoPrecedence(cfg.SongInfoPrecedence)
+    if err != nil {
+        return nil, err
+    }
+    return service.NewSongInfoChain(providers, precedence)
+}
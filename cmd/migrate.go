@@ -0,0 +1,118 @@
+package main
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+
+    "github.com/golang-migrate/migrate/v4"
+    "github.com/golang-migrate/migrate/v4/database"
+    "github.com/golang-migrate/migrate/v4/database/postgres"
+    "github.com/golang-migrate/migrate/v4/source/iofs"
+    "go.uber.org/zap"
+    "music-library/migrations"
+)
+
+const migrateUsage = "usage: migrate up [N] | down [N] | goto V | version | force V (-1 for none)"
+
+func newMigrate(db *sql.DB) (*migrate.Migrate, error) {
+    source, err := iofs.New(migrations.FS, ".")
+    if err != nil {
+        return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+    }
+
+    driver, err := postgres.WithInstance(db, &postgres.Config{})
+    if err != nil {
+        return nil, fmt.Errorf("failed to create migration driver: %w", err)
+    }
+
+    m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create migration instance: %w", err)
+    }
+
+    return m, nil
+}
+
+// runMigrate executes a single migrate subcommand against the database.
+func runMigrate(db *sql.DB, args []string, logger *zap.Logger) error {
+    if len(args) == 0 {
+        return errors.New(migrateUsage)
+    }
+
+    m, err := newMigrate(db)
+    if err != nil {
+        return err
+    }
+
+    switch args[0] {
+    case "up", "down":
+        if len(args) == 1 {
+            if args[0] == "up" {
+                err = m.Up()
+            } else {
+                err = m.Down()
+            }
+            break
+        }
+        n, argErr := parseMigrateArg(args, 0)
+        if argErr != nil {
+            return argErr
+        }
+        if args[0] == "down" {
+            n = -n
+        }
+        err = m.Steps(n)
+    case "goto":
+        v, argErr := parseMigrateArg(args, 0)
+        if argErr != nil {
+            return argErr
+        }
+        err = m.Migrate(uint(v))
+    case "force":
+        // Forcing version -1 marks no migration as applied.
+        v, argErr := parseMigrateArg(args, database.NilVersion)
+        if argErr != nil {
+            return argErr
+        }
+        err = m.Force(v)
+    case "version":
+        version, dirty, err := m.Version()
+        if errors.Is(err, migrate.ErrNilVersion) {
+            logger.Info("No migrations applied")
+            return nil
+        }
+        if err != nil {
+            return fmt.Errorf("failed to read migration version: %w", err)
+        }
+        logger.Info("Current migration version",
+            zap.Uint("version", version),
+            zap.Bool("dirty", dirty))
+        return nil
+    default:
+        return errors.New(migrateUsage)
+    }
+
+    if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+        return fmt.Errorf("migrate %s failed: %w", args[0], err)
+    }
+
+    logger.Info("Migration completed", zap.String("command", args[0]))
+    return nil
+}
+
+// parseMigrateArg reads the number after a subcommand; it must be at least
+// lowest.
+func parseMigrateArg(args []string, lowest int) (int, error) {
+    if len(args) != 2 {
+        return 0, errors.New(migrateUsage)
+    }
+
+    n, err := strconv.Atoi(args[1])
+    if err != nil || n < lowest {
+        return 0, fmt.Errorf("invalid argument %q: %s", args[1], migrateUsage)
+    }
+
+    return n, nil
+}
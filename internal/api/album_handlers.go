@@ -0,0 +1,180 @@
+package api
+
+import (
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+)
+
+// @Summary Create an album
+// @Description Create an album by an artist, optionally with its track listing as song ids in order. The artist is created if it does not exist.
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param album body models.Album true "Album object"
+// @Success 201 {object} models.AlbumWithTracks
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /albums [post]
+func (h *Handler) CreateAlbum(c *gin.Context) {
+    var album models.Album
+    if err := c.ShouldBindJSON(&album); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    result, err := h.albumService.CreateAlbum(&album)
+    if err != nil {
+        h.logger.Error("Failed to create album", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, result)
+}
+
+// @Summary List albums
+// @Description Get a page of albums ordered by title
+// @Tags albums
+// @Produce json
+// @Param artist_id query int false "Filter by artist ID"
+// @Param title query string false "Filter by title"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Success 200 {object} models.AlbumList
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /albums [get]
+func (h *Handler) ListAlbums(c *gin.Context) {
+    var filter models.AlbumFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    list, err := h.albumService.ListAlbums(&filter)
+    if err != nil {
+        h.logger.Error("Failed to list albums", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get an album
+// @Description Get an album with its songs in track order
+// @Tags albums
+// @Produce json
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce audio/x-scpls
+// @Param id path int true "Album ID"
+// @Param format query string false "Download as a playlist file instead of JSON: m3u8, xspf or pls (also selectable with Accept)"
+// @Success 200 {object} models.AlbumWithTracks
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /albums/{id} [get]
+func (h *Handler) GetAlbum(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid album ID", zap.Error(err))
+        respondBadRequest(c, "Invalid album ID")
+        return
+    }
+    format, err := exportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
+    album, err := h.albumService.GetAlbum(id)
+    if err != nil {
+        h.logger.Error("Failed to get album", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    if format != nil {
+        songs := make([]models.Song, len(album.Tracks))
+        for i, track := range album.Tracks {
+            songs[i] = track.Song
+        }
+        writePlaylistFile(c, format, fmt.Sprintf("album-%d", id), album.Artist+" - "+album.Title, songs)
+        return
+    }
+    c.JSON(http.StatusOK, album)
+}
+
+// @Summary Set an album's tracks
+// @Description Replace the track listing of an album with the given song ids, in order. Use it to reorder, add or remove tracks.
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param id path int true "Album ID"
+// @Param tracks body models.AlbumTracks true "Song ids in track order"
+// @Success 200 {object} models.AlbumWithTracks
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /albums/{id}/tracks [put]
+func (h *Handler) SetAlbumTracks(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid album ID", zap.Error(err))
+        respondBadRequest(c, "Invalid album ID")
+        return
+    }
+
+    var tracks models.AlbumTracks
+    if err := c.ShouldBindJSON(&tracks); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    album, err := h.albumService.SetTracks(id, tracks.SongIDs)
+    if err != nil {
+        h.logger.Error("Failed to set album tracks", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, album)
+}
+
+// @Summary Delete an album
+// @Description Delete an album and its track listing. The songs are kept.
+// @Tags albums
+// @Produce json
+// @Param id path int true "Album ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /albums/{id} [delete]
+func (h *Handler) DeleteAlbum(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid album ID", zap.Error(err))
+        respondBadRequest(c, "Invalid album ID")
+        return
+    }
+
+    if err := h.albumService.DeleteAlbum(id); err != nil {
+        h.logger.Error("Failed to delete album", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+)
+
+// @Summary Create an artist
+// @Description Create an artist. Names are unique ignoring case and extra whitespace.
+// @Tags artists
+// @Accept json
+// @Produce json
+// @Param artist body models.Artist true "Artist object"
+// @Success 201 {object} models.Artist
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists [post]
+func (h *Handler) CreateArtist(c *gin.Context) {
+    var artist models.Artist
+    if err := c.ShouldBindJSON(&artist); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    if err := h.artistService.CreateArtist(&artist); err != nil {
+        h.logger.Error("Failed to create artist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, artist)
+}
+
+// @Summary List artists
+// @Description Get a page of artists ordered by name
+// @Tags artists
+// @Produce json
+// @Param name query string false "Filter by name"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Success 200 {object} models.ArtistList
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists [get]
+func (h *Handler) ListArtists(c *gin.Context) {
+    var filter models.ArtistFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    list, err := h.artistService.ListArtists(&filter)
+    if err != nil {
+        h.logger.Error("Failed to list artists", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get an artist
+// @Description Get an artist by its ID
+// @Tags artists
+// @Produce json
+// @Param id path int true "Artist ID"
+// @Success 200 {object} models.Artist
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists/{id} [get]
+func (h *Handler) GetArtist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid artist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid artist ID")
+        return
+    }
+
+    artist, err := h.artistService.GetArtist(id)
+    if err != nil {
+        h.logger.Error("Failed to get artist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, artist)
+}
+
+// @Summary Rename an artist
+// @Description Rename an artist. All of its songs show the new name as their group.
+// @Tags artists
+// @Accept json
+// @Produce json
+// @Param id path int true "Artist ID"
+// @Param artist body models.Artist true "Artist object"
+// @Success 200 {object} models.Artist
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists/{id} [put]
+func (h *Handler) UpdateArtist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid artist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid artist ID")
+        return
+    }
+
+    var artist models.Artist
+    if err := c.ShouldBindJSON(&artist); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    artist.ID = id
+    if err := h.artistService.UpdateArtist(&artist); err != nil {
+        h.logger.Error("Failed to update artist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, artist)
+}
+
+// @Summary Delete an artist
+// @Description Delete an artist that has no songs
+// @Tags artists
+// @Produce json
+// @Param id path int true "Artist ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists/{id} [delete]
+func (h *Handler) DeleteArtist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid artist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid artist ID")
+        return
+    }
+
+    if err := h.artistService.DeleteArtist(id); err != nil {
+        h.logger.Error("Failed to delete artist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Status(http.StatusNoContent)
+}
+
+// @Summary List an artist's songs
+// @Description Get the songs of one artist. Accepts the same filters, sorting and paging as GET /songs.
+// @Tags artists
+// @Produce json
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce audio/x-scpls
+// @Param id path int true "Artist ID"
+// @Param song query string false "Filter by song name"
+// @Param released_after query string false "Only songs released after this date"
+// @Param released_before query string false "Only songs released before this date"
+// @Param year query int false "Only songs released in this year"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Param sort query string false "Comma-separated sort keys, prefix with - for descending"
+// @Param cursor query string false "next_cursor or prev_cursor from a previous page"
+// @Param format query string false "Download every matching song as a playlist file instead of one page of JSON: m3u8, xspf or pls (also selectable with Accept)"
+// @Param include_deleted query bool false "List songs in the trash too (admins only)"
+// @Success 200 {object} models.SongList
+// @Header 200 {string} Link "RFC 8288 links to the first, previous, next and last pages"
+// @Failure 400 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /artists/{id}/songs [get]
+func (h *Handler) ListArtistSongs(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid artist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid artist ID")
+        return
+    }
+    format, err := exportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
+    var filter models.SongFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+    if !allowIncludeDeleted(c, filter.IncludeDeleted) {
+        return
+    }
+
+    // A playlist file holds every matching song, not one page.
+    if format != nil {
+        var songs []models.Song
+        if err := h.artistService.ExportArtistSongs(id, &filter, collectTracks(&songs)); err != nil {
+            h.logger.Error("Failed to export artist songs", zap.Error(err), zap.String("request_id", requestID(c)))
+            respondError(c, err)
+            return
+        }
+        writePlaylistFile(c, format, fmt.Sprintf("artist-%d", id), "", songs)
+        return
+    }
+
+    list, err := h.artistService.ListArtistSongs(id, &filter)
+    if err != nil {
+        h.logger.Error("Failed to list artist songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    setPaginationLinks(c, list)
+    c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// @Summary Get a song's enrichment status
+// @Description Report whether the song's release date, lyrics and link have been fetched from the music info API. While a job is queued it includes the attempts so far, the last error and when the next attempt is due.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} models.Enrichment
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/enrichment [get]
+func (h *Handler) GetSongEnrichment(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    enrichment, err := h.enrichmentService.GetEnrichment(id)
+    if err != nil {
+        h.logger.Error("Failed to get song enrichment", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, enrichment)
+}
+
+// @Summary Enrich a song again
+// @Description Queue the song to be fetched from the music info API again, e.g. after enrichment failed. Fetched values replace the song's release date, lyrics and link unless the field is locked; empty ones leave them as they are. Send Cache-Control: no-cache to bypass cached lookups.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param Cache-Control header string false "no-cache to fetch fresh details"
+// @Success 202 {object} models.Enrichment
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/enrich [post]
+func (h *Handler) EnrichSong(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    enrichment, err := h.enrichmentService.EnrichSong(id, noCacheRequested(c))
+    if err != nil {
+        h.logger.Error("Failed to queue song enrichment", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusAccepted, enrichment)
+}
+
+// noCacheRequested reports whether the request's Cache-Control asks for
+// fresh data.
+func noCacheRequested(c *gin.Context) bool {
+    for _, directive := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+        if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+            return true
+        }
+    }
+    return false
+}
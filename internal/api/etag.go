@@ -0,0 +1,91 @@
+package api
+
+import (
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// songETag tags the song's version and enrichment status. Enrichment status
+// changes leave the version alone but change the song's representation, so
+// If-None-Match must not match across them.
+func songETag(song *models.Song) string {
+    return fmt.Sprintf(`"%d-%s"`, song.Version, song.EnrichmentStatus)
+}
+
+// ifMatchVersion returns the version song id must have for the If-Match
+// header to match. 0 means the header is absent or "*"; -1 means it cannot
+// match any version. A list of tags matches if any of them names the current
+// version, so that version is returned; the write then fails if the song
+// changes in the meantime.
+func (h *Handler) ifMatchVersion(c *gin.Context, id int) int {
+    versions := ifMatchVersions(c)
+    switch {
+    case versions == nil:
+        return 0
+    case len(versions) == 0:
+        return -1
+    case len(versions) == 1:
+        return versions[0]
+    }
+
+    song, err := h.songService.GetSong(id, true)
+    if err != nil {
+        return -1
+    }
+    for _, version := range versions {
+        if version == song.Version {
+            return version
+        }
+    }
+    return -1
+}
+
+// ifMatchVersions returns the song versions listed in the If-Match header,
+// or nil if the header is absent or "*".
+func ifMatchVersions(c *gin.Context) []int {
+    header := strings.TrimSpace(c.GetHeader("If-Match"))
+    if header == "" {
+        return nil
+    }
+
+    versions := []int{}
+    for _, candidate := range strings.Split(header, ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" {
+            return nil
+        }
+        // If-Match uses strong comparison, so weak tags never match.
+        if strings.HasPrefix(candidate, "W/") {
+            continue
+        }
+        // Only the version counts: a change of enrichment status does not
+        // conflict with a write.
+        tag, _, _ := strings.Cut(strings.Trim(candidate, `"`), "-")
+        if version, err := strconv.Atoi(tag); err == nil && version >= 1 {
+            versions = append(versions, version)
+        }
+    }
+    return versions
+}
+
+// notModified writes 304 and returns true when If-None-Match matches etag.
+func notModified(c *gin.Context, etag string) bool {
+    header := c.GetHeader("If-None-Match")
+    if header == "" {
+        return false
+    }
+
+    for _, candidate := range strings.Split(header, ",") {
+        candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+        if candidate == "*" || candidate == etag {
+            c.Header("ETag", etag)
+            c.Status(http.StatusNotModified)
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+    "bufio"
+    "encoding/xml"
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "io"
+    "music-library/internal/models"
+    "net/http"
+    "strings"
+)
+
+// playlistFormat is a playlist file format song lists can be exported as.
+type playlistFormat struct {
+    name        string
+    contentType string
+    accept      []string // media types selecting the format in Accept
+    encode      func(w io.Writer, title string, songs []models.Song) error
+}
+
+var playlistFormats = []playlistFormat{
+    {
+        name:        "m3u8",
+        contentType: "audio/x-mpegurl; charset=utf-8",
+        accept:      []string{"audio/x-mpegurl", "audio/mpegurl", "application/vnd.apple.mpegurl"},
+        encode:      encodeM3U8,
+    },
+    {
+        name:        "xspf",
+        contentType: "application/xspf+xml; charset=utf-8",
+        accept:      []string{"application/xspf+xml"},
+        encode:      encodeXSPF,
+    },
+    {
+        name:        "pls",
+        contentType: "audio/x-scpls; charset=utf-8",
+        accept:      []string{"audio/x-scpls"},
+        encode:      encodePLS,
+    },
+}
+
+// exportFormat picks the playlist format requested with ?format= or, failing
+// that, the Accept header. It returns nil for JSON.
+func exportFormat(c *gin.Context) (*playlistFormat, error) {
+    if name := strings.ToLower(c.Query("format")); name != "" {
+        if name == "json" {
+            return nil, nil
+        }
+        for i := range playlistFormats {
+            if playlistFormats[i].name == name {
+                return &playlistFormats[i], nil
+            }
+        }
+        return nil, &models.ValidationError{Field: "format", Message: "must be one of json, m3u8, xspf, pls"}
+    }
+
+    // JSON is offered first so that */* and a missing Accept keep the default.
+    offered := []string{gin.MIMEJSON}
+    for _, format := range playlistFormats {
+        offered = append(offered, format.accept...)
+    }
+    chosen := c.NegotiateFormat(offered...)
+    for i := range playlistFormats {
+        for _, accept := range playlistFormats[i].accept {
+            if accept == chosen {
+                return &playlistFormats[i], nil
+            }
+        }
+    }
+    return nil, nil
+}
+
+// writePlaylistFile sends songs as a playlist file download named
+// filename plus the format's extension.
+func writePlaylistFile(c *gin.Context, format *playlistFormat, filename, title string, songs []models.Song) {
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format.name))
+    c.Header("Vary", "Accept")
+    c.Header("Content-Type", format.contentType)
+    c.Status(http.StatusOK)
+    if err := format.encode(c.Writer, title, playableSongs(songs)); err != nil {
+        _ = c.Error(err)
+    }
+}
+
+// collectTracks returns an export callback that appends the songs players
+// can locate to songs, without their lyrics, which playlist files leave out.
+func collectTracks(songs *[]models.Song) func(song *models.Song) error {
+    return func(song *models.Song) error {
+        if song.Link != "" {
+            track := *song
+            track.Text = ""
+            *songs = append(*songs, track)
+        }
+        return nil
+    }
+}
+
+// playableSongs drops songs without a link, which players cannot locate.
+func playableSongs(songs []models.Song) []models.Song {
+    var playable []models.Song
+    for _, song := range songs {
+        if song.Link != "" {
+            playable = append(playable, song)
+        }
+    }
+    return playable
+}
+
+// trackTitle is the display title of a song in playlist files.
+func trackTitle(song *models.Song) string {
+    return singleLine(song.GroupName + " - " + song.SongName)
+}
+
+// singleLine keeps values from breaking the line-based M3U and PLS formats.
+func singleLine(s string) string {
+    return strings.Join(strings.Fields(s), " ")
+}
+
+func encodeM3U8(w io.Writer, title string, songs []models.Song) error {
+    b := bufio.NewWriter(w)
+    b.WriteString("#EXTM3U\n")
+    if title != "" {
+        fmt.Fprintf(b, "#PLAYLIST:%s\n", singleLine(title))
+    }
+    for i := range songs {
+        // The duration is unknown, which -1 stands for.
+        fmt.Fprintf(b, "#EXTINF:-1,%s\n%s\n", trackTitle(&songs[i]), singleLine(songs[i].Link))
+    }
+    return b.Flush()
+}
+
+func encodePLS(w io.Writer, title string, songs []models.Song) error {
+    b := bufio.NewWriter(w)
+    b.WriteString("[playlist]\n")
+    for i := range songs {
+        n := i + 1
+        fmt.Fprintf(b, "File%d=%s\n", n, singleLine(songs[i].Link))
+        fmt.Fprintf(b, "Title%d=%s\n", n, trackTitle(&songs[i]))
+        fmt.Fprintf(b, "Length%d=-1\n", n)
+    }
+    fmt.Fprintf(b, "NumberOfEntries=%d\nVersion=2\n", len(songs))
+    return b.Flush()
+}
+
+type xspfPlaylist struct {
+    XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+    Version string      `xml:"version,attr"`
+    Title   string      `xml:"title,omitempty"`
+    Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+    Location string `xml:"location"`
+    Title    string `xml:"title"`
+    Creator  string `xml:"creator"`
+}
+
+func encodeXSPF(w io.Writer, title string, songs []models.Song) error {
+    playlist := xspfPlaylist{Version: "1", Title: title, Tracks: []xspfTrack{}}
+    for i := range songs {
+        playlist.Tracks = append(playlist.Tracks, xspfTrack{
+            Location: songs[i].Link,
+            Title:    trackTitle(&songs[i]),
+            Creator:  songs[i].GroupName,
+        })
+    }
+
+    if _, err := io.WriteString(w, xml.Header); err != nil {
+        return err
+    }
+    enc := xml.NewEncoder(w)
+    enc.Indent("", "  ")
+    if err := enc.Encode(playlist); err != nil {
+        return err
+    }
+    _, err := io.WriteString(w, "\n")
+    return err
+}
@@ -10,41 +10,65 @@ import (
 )
 
 type Handler struct {
-    songService *service.SongService
-    logger      *zap.Logger
+    songService       *service.SongService
+    enrichmentService *service.EnrichmentService
+    artistService     *service.ArtistService
+    albumService      *service.AlbumService
+    playlistService   *service.PlaylistService
+    healthService     *service.HealthService
+    logger            *zap.Logger
 }
 
-func NewHandler(songService *service.SongService, logger *zap.Logger) *Handler {
+func NewHandler(
+    songService *service.SongService,
+    enrichmentService *service.EnrichmentService,
+    artistService *service.ArtistService,
+    albumService *service.AlbumService,
+    playlistService *service.PlaylistService,
+    healthService *service.HealthService,
+    logger *zap.Logger,
+) *Handler {
     return &Handler{
-        songService: songService,
-        logger:      logger,
+        songService:       songService,
+        enrichmentService: enrichmentService,
+        artistService:     artistService,
+        albumService:      albumService,
+        playlistService:   playlistService,
+        healthService:     healthService,
+        logger:            logger,
     }
 }
 
 // @Summary Create a new song
-// @Description Create a new song with the provided information
+// @Description Create a new song with the provided information. The song is stored with enrichment_status "pending" and its details are fetched from the music info API in the background; poll GET /songs/{id}/enrichment to follow progress. Send Cache-Control: no-cache to bypass cached lookups.
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param song body models.Song true "Song object"
+// @Param Cache-Control header string false "no-cache to fetch fresh details"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
 // @Success 201 {object} models.Song
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs [post]
 func (h *Handler) CreateSong(c *gin.Context) {
     var song models.Song
     if err := c.ShouldBindJSON(&song); err != nil {
         h.logger.Error("Failed to bind JSON", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+        respondBindError(c, err, "Invalid request body")
         return
     }
 
-    if err := h.songService.CreateSong(&song); err != nil {
-        h.logger.Error("Failed to create song", zap.Error(err))
-        c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+    song.RefreshInfo = noCacheRequested(c)
+    if err := h.songService.CreateSong(&song, actor(c)); err != nil {
+        h.logger.Error("Failed to create song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
         return
     }
 
+    c.Header("ETag", songETag(&song))
     c.JSON(http.StatusCreated, song)
 }
 
@@ -54,58 +78,130 @@ func (h *Handler) CreateSong(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Song ID"
+// @Param If-Match header string false "ETag the song must still match, or a list of them"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
 // @Param song body models.Song true "Song object"
 // @Success 200 {object} models.Song
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs/{id} [put]
 func (h *Handler) UpdateSong(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil {
         h.logger.Error("Invalid song ID", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid song ID"})
+        respondBadRequest(c, "Invalid song ID")
         return
     }
 
     var song models.Song
     if err := c.ShouldBindJSON(&song); err != nil {
         h.logger.Error("Failed to bind JSON", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+        respondBindError(c, err, "Invalid request body")
         return
     }
 
     song.ID = id
-    if err := h.songService.UpdateSong(&song); err != nil {
-        h.logger.Error("Failed to update song", zap.Error(err))
-        c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+    if err := h.songService.UpdateSong(&song, h.ifMatchVersion(c, id), actor(c)); err != nil {
+        h.logger.Error("Failed to update song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
         return
     }
 
+    c.Header("ETag", songETag(&song))
+    c.JSON(http.StatusOK, song)
+}
+
+// @Summary Patch a song
+// @Description Update only the provided fields of a song. Accepts a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) document.
+// @Tags songs
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param If-Match header string false "ETag the song must still match, or a list of them"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Success 200 {object} models.Song
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id} [patch]
+func (h *Handler) PatchSong(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    body, err := c.GetRawData()
+    if err != nil {
+        h.logger.Error("Failed to read request body", zap.Error(err))
+        respondBadRequest(c, "Invalid request body")
+        return
+    }
+
+    var song *models.Song
+    switch c.ContentType() {
+    case "application/json-patch+json":
+        var ops []service.JSONPatchOperation
+        if ops, err = service.ParseJSONPatch(body); err == nil {
+            song, err = h.songService.ApplyJSONPatch(id, ops, h.ifMatchVersion(c, id), actor(c))
+        }
+    case "application/merge-patch+json", "application/json":
+        var patch *models.SongPatch
+        if patch, err = service.ParseMergePatch(body); err == nil {
+            song, err = h.songService.PatchSong(id, patch, h.ifMatchVersion(c, id), actor(c))
+        }
+    default:
+        respondUnsupportedMediaType(c, "Use application/merge-patch+json or application/json-patch+json")
+        return
+    }
+    if err != nil {
+        h.logger.Error("Failed to patch song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Header("ETag", songETag(song))
     c.JSON(http.StatusOK, song)
 }
 
 // @Summary Delete a song
-// @Description Delete a song by its ID
+// @Description Move a song to the trash. It can be restored until the trash purge removes it for good.
 // @Tags songs
 // @Produce json
 // @Param id path int true "Song ID"
+// @Param If-Match header string false "ETag the song must still match, or a list of them"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
 // @Success 204 "No Content"
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs/{id} [delete]
 func (h *Handler) DeleteSong(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil {
         h.logger.Error("Invalid song ID", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid song ID"})
+        respondBadRequest(c, "Invalid song ID")
         return
     }
 
-    if err := h.songService.DeleteSong(id); err != nil {
-        h.logger.Error("Failed to delete song", zap.Error(err))
-        c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+    if err := h.songService.DeleteSong(id, h.ifMatchVersion(c, id), actor(c)); err != nil {
+        h.logger.Error("Failed to delete song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
         return
     }
 
@@ -113,30 +209,52 @@ func (h *Handler) DeleteSong(c *gin.Context) {
 }
 
 // @Summary Get a song
-// @Description Get a song by its ID
+// @Description Get a song by its ID. Songs in the trash are not found unless an admin sets include_deleted.
 // @Tags songs
 // @Produce json
 // @Param id path int true "Song ID"
+// @Param include_deleted query bool false "Find the song even if it is in the trash (admins only)"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} models.Song
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs/{id} [get]
 func (h *Handler) GetSong(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil {
         h.logger.Error("Invalid song ID", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid song ID"})
+        respondBadRequest(c, "Invalid song ID")
         return
     }
 
-    song, err := h.songService.GetSong(id)
+    includeDeleted := false
+    if value := c.Query("include_deleted"); value != "" {
+        if includeDeleted, err = strconv.ParseBool(value); err != nil {
+            respondBadRequest(c, "include_deleted must be true or false")
+            return
+        }
+    }
+    if !allowIncludeDeleted(c, includeDeleted) {
+        return
+    }
+
+    song, err := h.songService.GetSong(id, includeDeleted)
     if err != nil {
-        h.logger.Error("Failed to get song", zap.Error(err))
-        c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+        h.logger.Error("Failed to get song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
         return
     }
 
+    etag := songETag(song)
+    if notModified(c, etag) {
+        return
+    }
+
+    c.Header("ETag", etag)
     c.JSON(http.StatusOK, song)
 }
 
@@ -145,25 +263,29 @@ func (h *Handler) GetSong(c *gin.Context) {
 // @Tags songs
 // @Produce json
 // @Param id path int true "Song ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Param verse_page query int false "Verse page number (default: 1)"
 // @Param verse_size query int false "Verses per page (default: 4)"
 // @Success 200 {object} models.SongWithVerses
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs/{id}/verses [get]
 func (h *Handler) GetSongVerses(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil {
         h.logger.Error("Invalid song ID", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid song ID"})
+        respondBadRequest(c, "Invalid song ID")
         return
     }
 
     var pagination models.VersePagination
     if err := c.ShouldBindQuery(&pagination); err != nil {
         h.logger.Error("Failed to bind query parameters", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid query parameters"})
+        respondBindError(c, err, "Invalid query parameters")
         return
     }
 
@@ -171,45 +293,178 @@ func (h *Handler) GetSongVerses(c *gin.Context) {
     if err != nil {
         h.logger.Error("Failed to get song verses",
             zap.Error(err),
-            zap.Int("id", id))
-        c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+            zap.Int("id", id),
+            zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    etag := songETag(&song.Song)
+    if notModified(c, etag) {
         return
     }
 
+    c.Header("ETag", etag)
     c.JSON(http.StatusOK, song)
 }
 
 // @Summary List songs
-// @Description Get a list of songs with optional filtering and pagination
+// @Description Get a list of songs with optional filtering and pagination. Pass either page or the cursor from a previous response.
 // @Tags songs
 // @Produce json
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce audio/x-scpls
 // @Param group query string false "Filter by group name"
+// @Param artist_id query int false "Filter by artist ID"
+// @Param album_id query int false "Only songs on this album"
 // @Param song query string false "Filter by song name"
-// @Param release_date query string false "Filter by release date"
+// @Param release_date query string false "Filter by release date (YYYY-MM-DD or DD.MM.YYYY)"
+// @Param released_after query string false "Only songs released after this date"
+// @Param released_before query string false "Only songs released before this date"
+// @Param year query int false "Only songs released in this year"
 // @Param page query int false "Page number (default: 1)"
 // @Param page_size query int false "Page size (default: 10)"
-// @Success 200 {array} models.Song
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Param fuzzy query bool false "Match group and song by trigram similarity instead of substring, best matches first"
+// @Param sort query string false "Comma-separated sort keys, prefix with - for descending: group, song, release_date, created_at, updated_at"
+// @Param cursor query string false "next_cursor or prev_cursor from a previous page"
+// @Param format query string false "Download every matching song as a playlist file instead of one page of JSON: m3u8, xspf or pls (also selectable with Accept)"
+// @Param include_deleted query bool false "List songs in the trash too (admins only)"
+// @Success 200 {object} models.SongList
+// @Header 200 {string} Link "RFC 8288 links to the first, previous, next and last pages"
+// @Failure 400 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /songs [get]
 func (h *Handler) ListSongs(c *gin.Context) {
+    format, err := exportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
     var filter models.SongFilter
     if err := c.ShouldBindQuery(&filter); err != nil {
         h.logger.Error("Failed to bind query parameters", zap.Error(err))
-        c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid query parameters"})
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+    if !allowIncludeDeleted(c, filter.IncludeDeleted) {
+        return
+    }
+
+    // A playlist file holds every matching song, not one page.
+    if format != nil {
+        var songs []models.Song
+        if err := h.songService.ExportSongs(&filter, collectTracks(&songs)); err != nil {
+            h.logger.Error("Failed to export songs", zap.Error(err), zap.String("request_id", requestID(c)))
+            respondError(c, err)
+            return
+        }
+        writePlaylistFile(c, format, "songs", "Songs", songs)
         return
     }
 
-    songs, err := h.songService.ListSongs(&filter)
+    list, err := h.songService.ListSongs(&filter)
     if err != nil {
-        h.logger.Error("Failed to list songs", zap.Error(err))
-        c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+        h.logger.Error("Failed to list songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
         return
     }
 
-    c.JSON(http.StatusOK, songs)
+    setPaginationLinks(c, list)
+    c.JSON(http.StatusOK, list)
 }
 
-type ErrorResponse struct {
-    Error string `json:"error"`
+// SongsAction dispatches the custom methods on the songs collection,
+// /songs:<action>. Gin cannot route a literal colon, so the action is
+// matched here.
+func (h *Handler) SongsAction(c *gin.Context) {
+    switch c.Request.Method + " " + c.Param("action") {
+    case "POST :import":
+        h.ImportSongs(c)
+    case "GET :export":
+        h.ExportSongs(c)
+    default:
+        writeProblem(c, newProblem(c, http.StatusNotFound, ProblemTypeNotFound, "The requested resource was not found"))
+    }
+}
+
+// @Summary Search lyrics
+// @Description Full-text search over group names, song names and lyrics, best matches first. Snippets are the verses containing a match, HTML-escaped, with matches wrapped in <mark> tags.
+// @Tags search
+// @Produce json
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce audio/x-scpls
+// @Param q query string true "Search query (web search syntax: quoted phrases, OR, -exclude)"
+// @Param lang query string false "Text search configuration, e.g. english or russian (default: the language of each song)"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Param format query string false "Download as a playlist file instead of JSON: m3u8, xspf or pls (also selectable with Accept)"
+// @Success 200 {object} models.SearchResults
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /search [get]
+func (h *Handler) SearchSongs(c *gin.Context) {
+    format, err := exportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
+    var query models.SearchQuery
+    if err := c.ShouldBindQuery(&query); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    results, err := h.songService.SearchSongs(&query)
+    if err != nil {
+        h.logger.Error("Failed to search songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    if format != nil {
+        songs := make([]models.Song, len(results.Items))
+        for i, hit := range results.Items {
+            songs[i] = hit.Song
+        }
+        writePlaylistFile(c, format, "search", "Search: "+query.Query, songs)
+        return
+    }
+    c.JSON(http.StatusOK, results)
+}
+
+// @Summary Suggest group and song names
+// @Description Autocomplete group and song names starting with a prefix, at the start of the name or of any word in it
+// @Tags songs
+// @Produce json
+// @Param prefix query string true "Text typed so far"
+// @Param limit query int false "Maximum number of suggestions (default: 10, max: 50)"
+// @Success 200 {array} models.Suggestion
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/suggest [get]
+func (h *Handler) SuggestSongs(c *gin.Context) {
+    var query models.SuggestQuery
+    if err := c.ShouldBindQuery(&query); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    suggestions, err := h.songService.SuggestSongs(&query)
+    if err != nil {
+        h.logger.Error("Failed to suggest songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, suggestions)
 }
@@ -0,0 +1,420 @@
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+const testAdminToken = "test-admin-token"
+
+// newTestRouter returns the API routes on services.
+func newTestRouter(services *testutil.Services) *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    handler := NewHandler(
+        services.Songs,
+        services.Enrichment,
+        services.Artists,
+        services.Albums,
+        services.Playlists,
+        services.Health,
+        services.Logger,
+    )
+    return SetupRouter(handler, testAdminToken)
+}
+
+// serve sends a request with a JSON body, if any, and optional headers as
+// name/value pairs.
+func serve(router *gin.Engine, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, path, strings.NewReader(body))
+    if body != "" {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    for i := 0; i+1 < len(headers); i += 2 {
+        req.Header.Set(headers[i], headers[i+1])
+    }
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+    t.Helper()
+    if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+        t.Fatalf("failed to decode %q: %v", w.Body.String(), err)
+    }
+}
+
+func createSong(t *testing.T, router *gin.Engine, body string) models.Song {
+    t.Helper()
+    w := serve(router, http.MethodPost, "/api/v1/songs", body)
+    if w.Code != http.StatusCreated {
+        t.Fatalf("POST /songs %s = %d %s, want 201", body, w.Code, w.Body.String())
+    }
+    var song models.Song
+    decode(t, w, &song)
+    return song
+}
+
+func TestCreateSongWithoutReleaseDate(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    song := createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+    if !song.ReleaseDate.IsZero() {
+        t.Errorf("release date = %s, want none", song.ReleaseDate)
+    }
+
+    w := serve(router, http.MethodGet, "/api/v1/songs/1", "")
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /songs/1 = %d, want 200", w.Code)
+    }
+    if !strings.Contains(w.Body.String(), `"releaseDate":""`) {
+        t.Errorf("GET /songs/1 body = %s, want an empty releaseDate", w.Body.String())
+    }
+}
+
+func TestListSongsSortsMissingReleaseDatesLast(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    undated := createSong(t, router, `{"group":"Muse","song":"Undated"}`)
+    older := createSong(t, router, `{"group":"Muse","song":"Older","releaseDate":"2001-03-01"}`)
+    newer := createSong(t, router, `{"group":"Muse","song":"Newer","releaseDate":"2009-06-01"}`)
+
+    tests := []struct {
+        sort string
+        want []int
+    }{
+        {"release_date", []int{older.ID, newer.ID, undated.ID}},
+        {"-release_date", []int{newer.ID, older.ID, undated.ID}},
+    }
+    for _, tt := range tests {
+        w := serve(router, http.MethodGet, "/api/v1/songs?sort="+tt.sort, "")
+        if w.Code != http.StatusOK {
+            t.Fatalf("GET /songs?sort=%s = %d, want 200", tt.sort, w.Code)
+        }
+        var list models.SongList
+        decode(t, w, &list)
+        var got []int
+        for _, song := range list.Items {
+            got = append(got, song.ID)
+        }
+        if len(got) != len(tt.want) {
+            t.Fatalf("GET /songs?sort=%s = %v, want %v", tt.sort, got, tt.want)
+        }
+        for i := range got {
+            if got[i] != tt.want[i] {
+                t.Errorf("GET /songs?sort=%s = %v, want %v", tt.sort, got, tt.want)
+                break
+            }
+        }
+    }
+}
+
+func TestCreateSongRequiresGroupAndSong(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    w := serve(router, http.MethodPost, "/api/v1/songs", `{"group":"Muse"}`)
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("POST /songs without song = %d, want 422", w.Code)
+    }
+    if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/problem+json") {
+        t.Errorf("Content-Type = %q, want application/problem+json", got)
+    }
+}
+
+func TestGetSongInvalidID(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    w := serve(router, http.MethodGet, "/api/v1/songs/abc", "")
+    if w.Code != http.StatusBadRequest {
+        t.Fatalf("GET /songs/abc = %d, want 400", w.Code)
+    }
+    var problem Problem
+    decode(t, w, &problem)
+    if problem.Status != http.StatusBadRequest {
+        t.Errorf("problem status = %d, want 400", problem.Status)
+    }
+}
+
+func TestGetMissingSongProblem(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+
+    w := serve(router, http.MethodGet, "/api/v1/songs/2", "")
+    if w.Code != http.StatusNotFound {
+        t.Fatalf("GET /songs/2 = %d, want 404", w.Code)
+    }
+    var problem Problem
+    decode(t, w, &problem)
+    if problem.Type != ProblemTypeNotFound || problem.Instance != "/api/v1/songs/2" {
+        t.Errorf("problem = %+v, want a not-found problem for /api/v1/songs/2", problem)
+    }
+}
+
+func TestPatchSong(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising","link":"https://example.com"}`)
+
+    w := serve(router, http.MethodPatch, "/api/v1/songs/1", `{"text":"Paranoia is in bloom","link":null}`,
+        "Content-Type", "application/merge-patch+json")
+    if w.Code != http.StatusOK {
+        t.Fatalf("merge patch = %d %s, want 200", w.Code, w.Body.String())
+    }
+    var song models.Song
+    decode(t, w, &song)
+    if song.Text != "Paranoia is in bloom" || song.Link != "" || song.SongName != "Uprising" {
+        t.Errorf("song after merge patch = %+v", song)
+    }
+
+    ops := `[{"op":"test","path":"/song","value":"Uprising"},{"op":"replace","path":"/song","value":"Resistance"}]`
+    w = serve(router, http.MethodPatch, "/api/v1/songs/1", ops, "Content-Type", "application/json-patch+json")
+    if w.Code != http.StatusOK {
+        t.Fatalf("JSON Patch = %d %s, want 200", w.Code, w.Body.String())
+    }
+    decode(t, w, &song)
+    if song.SongName != "Resistance" {
+        t.Errorf("song name after JSON Patch = %q, want Resistance", song.SongName)
+    }
+
+    if w := serve(router, http.MethodPatch, "/api/v1/songs/1", ops, "Content-Type", "application/json-patch+json"); w.Code != http.StatusConflict {
+        t.Errorf("JSON Patch with a failing test op = %d, want 409", w.Code)
+    }
+    if w := serve(router, http.MethodPatch, "/api/v1/songs/1", `{"song":null}`, "Content-Type", "application/merge-patch+json"); w.Code != http.StatusUnprocessableEntity {
+        t.Errorf("merge patch removing song = %d, want 422", w.Code)
+    }
+    if w := serve(router, http.MethodPatch, "/api/v1/songs/1", `{}`, "Content-Type", "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+        t.Errorf("patch as text/plain = %d, want 415", w.Code)
+    }
+}
+
+func TestTrashedSongHistoryNeedsAdmin(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising","text":"Paranoia is in bloom"}`)
+    if w := serve(router, http.MethodDelete, "/api/v1/songs/1", ""); w.Code != http.StatusNoContent {
+        t.Fatalf("DELETE /songs/1 = %d, want 204", w.Code)
+    }
+
+    for _, path := range []string{
+        "/api/v1/songs/1/revisions",
+        "/api/v1/songs/1/revisions/1",
+        "/api/v1/songs/1/provenance",
+        "/api/v1/songs/1/diff?from=1&to=2",
+    } {
+        if w := serve(router, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+            t.Errorf("GET %s of a trashed song = %d, want 404", path, w.Code)
+        }
+        if w := serve(router, http.MethodGet, path, "", "Authorization", "Bearer "+testAdminToken); w.Code != http.StatusOK {
+            t.Errorf("GET %s of a trashed song as admin = %d %s, want 200", path, w.Code, w.Body.String())
+        }
+    }
+}
+
+func TestIfMatchList(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+    update := `{"group":"Muse","song":"Uprising","text":"Paranoia is in bloom"}`
+
+    tests := []struct {
+        ifMatch string
+        want    int
+    }{
+        {`"3", "4"`, http.StatusPreconditionFailed},
+        {`W/"1", "2"`, http.StatusPreconditionFailed},
+        {`"3", "1"`, http.StatusOK},
+        {`"1", "2"`, http.StatusOK},
+        {`"3", *`, http.StatusOK},
+    }
+    for _, tt := range tests {
+        w := serve(router, http.MethodPut, "/api/v1/songs/1", update, "If-Match", tt.ifMatch)
+        if w.Code != tt.want {
+            t.Errorf("PUT with If-Match %s = %d, want %d", tt.ifMatch, w.Code, tt.want)
+        }
+    }
+}
+
+func TestSongETag(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+
+    w := serve(router, http.MethodGet, "/api/v1/songs/1", "")
+    etag := w.Header().Get("ETag")
+    if etag != `"1-pending"` {
+        t.Fatalf("ETag = %q, want \"1-pending\"", etag)
+    }
+    if w := serve(router, http.MethodGet, "/api/v1/songs/1", "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+        t.Errorf("GET with a matching If-None-Match = %d, want 304", w.Code)
+    }
+
+    update := `{"group":"Muse","song":"Uprising","releaseDate":"2009-09-07"}`
+    if w := serve(router, http.MethodPut, "/api/v1/songs/1", update, "If-Match", `"2"`); w.Code != http.StatusPreconditionFailed {
+        t.Errorf("PUT with a stale If-Match = %d, want 412", w.Code)
+    }
+    w = serve(router, http.MethodPut, "/api/v1/songs/1", update, "If-Match", etag)
+    if w.Code != http.StatusOK {
+        t.Fatalf("PUT with a matching If-Match = %d %s, want 200", w.Code, w.Body.String())
+    }
+    if got := w.Header().Get("ETag"); got != `"2-pending"` {
+        t.Errorf("ETag after PUT = %q, want \"2-pending\"", got)
+    }
+}
+
+func TestSongETagChangesWithEnrichmentStatus(t *testing.T) {
+    services := testutil.NewServices()
+    router := newTestRouter(services)
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+    etag := serve(router, http.MethodGet, "/api/v1/songs/1", "").Header().Get("ETag")
+
+    // The enrichment gives up without changing a field or the version.
+    jobs, err := services.EnrichmentStore.Claim(1, time.Minute)
+    if err != nil || len(jobs) != 1 {
+        t.Fatalf("Claim = %v, %v; want one job", jobs, err)
+    }
+    if err := services.EnrichmentStore.Fail(&jobs[0], "not found"); err != nil {
+        t.Fatalf("Fail: %v", err)
+    }
+
+    w := serve(router, http.MethodGet, "/api/v1/songs/1", "", "If-None-Match", etag)
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET with the pending ETag = %d, want 200", w.Code)
+    }
+    if got := w.Header().Get("ETag"); got != `"1-failed"` {
+        t.Errorf("ETag after the enrichment failed = %q, want \"1-failed\"", got)
+    }
+
+    update := `{"group":"Muse","song":"Uprising","text":"Paranoia is in bloom"}`
+    if w := serve(router, http.MethodPut, "/api/v1/songs/1", update, "If-Match", etag); w.Code != http.StatusOK {
+        t.Errorf("PUT with the pending ETag = %d, want 200", w.Code)
+    }
+}
+
+func TestDeleteSongNeedsAdminToSeeTrash(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+
+    if w := serve(router, http.MethodDelete, "/api/v1/songs/1", ""); w.Code != http.StatusNoContent {
+        t.Fatalf("DELETE /songs/1 = %d, want 204", w.Code)
+    }
+    if w := serve(router, http.MethodGet, "/api/v1/songs/1", ""); w.Code != http.StatusNotFound {
+        t.Errorf("GET deleted song = %d, want 404", w.Code)
+    }
+    if w := serve(router, http.MethodGet, "/api/v1/songs/1?include_deleted=true", ""); w.Code != http.StatusForbidden {
+        t.Errorf("GET deleted song as anyone = %d, want 403", w.Code)
+    }
+    w := serve(router, http.MethodGet, "/api/v1/songs/1?include_deleted=true", "", "Authorization", "Bearer "+testAdminToken)
+    if w.Code != http.StatusOK {
+        t.Errorf("GET deleted song as admin = %d, want 200", w.Code)
+    }
+
+    if w := serve(router, http.MethodPost, "/api/v1/songs/1:restore", ""); w.Code != http.StatusOK {
+        t.Fatalf("POST /songs/1:restore = %d, want 200", w.Code)
+    }
+    if w := serve(router, http.MethodGet, "/api/v1/songs/1", ""); w.Code != http.StatusOK {
+        t.Errorf("GET restored song = %d, want 200", w.Code)
+    }
+}
+
+func TestListSongsEnvelope(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    for _, name := range []string{"Uprising", "Resistance", "Undisclosed Desires"} {
+        createSong(t, router, `{"group":"Muse","song":"`+name+`"}`)
+    }
+
+    w := serve(router, http.MethodGet, "/api/v1/songs?page=2&page_size=2", "")
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /songs = %d, want 200", w.Code)
+    }
+    var list models.SongList
+    decode(t, w, &list)
+    if list.Total != 3 || len(list.Items) != 1 || list.PageInfo.TotalPages != 2 || list.PageInfo.HasNext || !list.PageInfo.HasPrev {
+        t.Errorf("GET /songs?page=2&page_size=2 = %+v", list)
+    }
+    if link := w.Header().Get("Link"); !strings.Contains(link, `rel="prev"`) || strings.Contains(link, `rel="next"`) {
+        t.Errorf("Link = %q, want a prev link and no next link", link)
+    }
+}
+
+func TestExportSongsAsPlaylistFile(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+    createSong(t, router, `{"group":"Muse","song":"Resistance"}`)
+    update := `{"group":"Muse","song":"Uprising","link":"https://example.com/uprising.mp3"}`
+    if w := serve(router, http.MethodPut, "/api/v1/songs/1", update); w.Code != http.StatusOK {
+        t.Fatalf("PUT /songs/1 = %d, want 200", w.Code)
+    }
+
+    w := serve(router, http.MethodGet, "/api/v1/songs?format=m3u8", "")
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /songs?format=m3u8 = %d %s, want 200", w.Code, w.Body.String())
+    }
+    want := "#EXTM3U\n#PLAYLIST:Songs\n#EXTINF:-1,Muse - Uprising\nhttps://example.com/uprising.mp3\n"
+    if w.Body.String() != want {
+        t.Errorf("m3u8 body = %q, want %q", w.Body.String(), want)
+    }
+
+    w = serve(router, http.MethodGet, "/api/v1/songs", "", "Accept", "application/xspf+xml")
+    if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/xspf+xml") {
+        t.Errorf("Content-Type for Accept: application/xspf+xml = %q", got)
+    }
+    if w := serve(router, http.MethodGet, "/api/v1/songs?format=wav", ""); w.Code != http.StatusUnprocessableEntity {
+        t.Errorf("GET /songs?format=wav = %d, want 422", w.Code)
+    }
+}
+
+func TestPlaylistFilesHoldEveryPage(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    if w := serve(router, http.MethodPost, "/api/v1/playlists", `{"name":"Everything"}`); w.Code != http.StatusCreated {
+        t.Fatalf("POST /playlists = %d %s, want 201", w.Code, w.Body.String())
+    }
+    // One more than the default page of a playlist, and of a song list.
+    const count = 51
+    for i := 1; i <= count; i++ {
+        song := createSong(t, router, fmt.Sprintf(`{"group":"Muse","song":"Song %d","link":"https://example.com/%d.mp3"}`, i, i))
+        body := fmt.Sprintf(`{"song_id":%d}`, song.ID)
+        if w := serve(router, http.MethodPost, "/api/v1/playlists/1/songs", body); w.Code != http.StatusCreated {
+            t.Fatalf("POST /playlists/1/songs = %d %s, want 201", w.Code, w.Body.String())
+        }
+    }
+
+    for _, path := range []string{
+        "/api/v1/playlists/1?format=m3u8",
+        "/api/v1/songs?format=m3u8",
+        "/api/v1/songs?page=2&format=m3u8",
+        "/api/v1/artists/1/songs?format=m3u8",
+    } {
+        w := serve(router, http.MethodGet, path, "")
+        if w.Code != http.StatusOK {
+            t.Fatalf("GET %s = %d %s, want 200", path, w.Code, w.Body.String())
+        }
+        if tracks := strings.Count(w.Body.String(), "#EXTINF:"); tracks != count {
+            t.Errorf("GET %s has %d tracks, want %d", path, tracks, count)
+        }
+        if !strings.HasSuffix(w.Body.String(), fmt.Sprintf("https://example.com/%d.mp3\n", count)) {
+            t.Errorf("GET %s does not end with the last song", path)
+        }
+    }
+}
+
+func TestExportCSVCanBeImported(t *testing.T) {
+    router := newTestRouter(testutil.NewServices())
+    createSong(t, router, `{"group":"Muse","song":"Uprising"}`)
+    createSong(t, router, `{"group":"Queen","song":"Bohemian Rhapsody"}`)
+
+    w := serve(router, http.MethodGet, "/api/v1/songs:export?format=csv", "")
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /songs:export = %d %s, want 200", w.Code, w.Body.String())
+    }
+
+    other := newTestRouter(testutil.NewServices())
+    w = serve(other, http.MethodPost, "/api/v1/songs:import", w.Body.String(), "Content-Type", "text/csv")
+    if w.Code != http.StatusOK {
+        t.Fatalf("POST /songs:import = %d %s, want 200", w.Code, w.Body.String())
+    }
+    var report models.ImportReport
+    decode(t, w, &report)
+    if report.Created != 2 {
+        t.Errorf("imported %d songs from the export, want 2: %s", report.Created, w.Body.String())
+    }
+}
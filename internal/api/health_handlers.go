@@ -0,0 +1,34 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "music-library/internal/models"
+    "net/http"
+)
+
+// @Summary Check service health
+// @Description Report the state of the service's dependencies: the database and the circuit breaker in front of the music info API. The status is "down" (503) when a critical dependency is down and "degraded" when another one is; songs can still be created while the music info API is down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Failure 503 {object} models.HealthReport
+// @Router /health [get]
+func (h *Handler) Health(c *gin.Context) {
+    report := h.healthService.Check(c.Request.Context())
+
+    status := http.StatusOK
+    if report.Status == models.HealthDown {
+        status = http.StatusServiceUnavailable
+    }
+    c.JSON(status, report)
+}
+
+// @Summary Check that the server is running
+// @Description Liveness probe; answers without checking any dependency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /health/live [get]
+func (h *Handler) Live(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"status": models.HealthUp})
+}
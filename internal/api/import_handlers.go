@@ -0,0 +1,132 @@
+package api
+
+import (
+    "bytes"
+    "errors"
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "io"
+    "music-library/internal/models"
+    "net/http"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// maxImportSize caps the size of an import upload.
+const maxImportSize = 64 << 20
+
+// importFormats maps request and file part media types onto import formats.
+var importFormats = map[string]string{
+    "text/csv":             models.ImportFormatCSV,
+    "application/csv":      models.ImportFormatCSV,
+    "application/json":     models.ImportFormatJSON,
+    "application/x-ndjson": models.ImportFormatNDJSON,
+    "application/ndjson":   models.ImportFormatNDJSON,
+    "application/jsonl":    models.ImportFormatNDJSON,
+}
+
+// importExtensions maps upload file extensions onto import formats.
+var importExtensions = map[string]string{
+    ".csv":    models.ImportFormatCSV,
+    ".json":   models.ImportFormatJSON,
+    ".ndjson": models.ImportFormatNDJSON,
+    ".jsonl":  models.ImportFormatNDJSON,
+}
+
+// @Summary Import songs in bulk
+// @Description Import songs from a CSV file (with a header row), a JSON array or NDJSON, sent as the request body or as the "file" field of a multipart form. Rows are validated one by one and are not enriched from the music API. Songs whose group and name are already in the library are skipped as duplicates. The report lists the outcome of every row.
+// @Tags songs
+// @Accept text/csv
+// @Accept json
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Produce json
+// @Param dry_run query bool false "Validate and report without storing anything"
+// @Param file formData file false "Import file; the format is taken from its extension (.csv, .json, .ndjson) or the format field"
+// @Param format formData string false "csv, json or ndjson"
+// @Param X-Actor header string false "Who makes the change, recorded in the songs' revisions"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs:import [post]
+func (h *Handler) ImportSongs(c *gin.Context) {
+    dryRun := false
+    if value := c.Query("dry_run"); value != "" {
+        var err error
+        if dryRun, err = strconv.ParseBool(value); err != nil {
+            respondBadRequest(c, "dry_run must be true or false")
+            return
+        }
+    }
+
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+    var body io.Reader
+    var format string
+    if c.ContentType() == "multipart/form-data" {
+        fileHeader, err := c.FormFile("file")
+        if err != nil {
+            h.logger.Error("Failed to read import file", zap.Error(err))
+            respondImportReadError(c, err, "The multipart form must contain a file field")
+            return
+        }
+        file, err := fileHeader.Open()
+        if err != nil {
+            h.logger.Error("Failed to open import file", zap.Error(err))
+            respondBadRequest(c, "Invalid import file")
+            return
+        }
+        defer file.Close()
+
+        body = file
+        format = strings.ToLower(c.PostForm("format"))
+        if format == "" {
+            format = importExtensions[strings.ToLower(filepath.Ext(fileHeader.Filename))]
+        }
+        if format == "" {
+            format = importFormats[strings.ToLower(strings.Split(fileHeader.Header.Get("Content-Type"), ";")[0])]
+        }
+        if format == "" {
+            respondUnsupportedMediaType(c, "Name the file .csv, .json or .ndjson, or set the format field")
+            return
+        }
+    } else {
+        var ok bool
+        if format, ok = importFormats[c.ContentType()]; !ok {
+            respondUnsupportedMediaType(c, "Use text/csv, application/json, application/x-ndjson or multipart/form-data")
+            return
+        }
+        data, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            h.logger.Error("Failed to read request body", zap.Error(err))
+            respondImportReadError(c, err, "Invalid request body")
+            return
+        }
+        body = bytes.NewReader(data)
+    }
+
+    report, err := h.songService.ImportSongs(body, format, dryRun, actor(c))
+    if err != nil {
+        h.logger.Error("Failed to import songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, report)
+}
+
+// respondImportReadError answers 413 when the upload exceeded
+// maxImportSize and 400 with detail otherwise.
+func respondImportReadError(c *gin.Context, err error, detail string) {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        writeProblem(c, newProblem(c, http.StatusRequestEntityTooLarge, ProblemTypeTooLarge,
+            "The import file is larger than "+strconv.Itoa(maxImportSize>>20)+" MB"))
+        return
+    }
+    respondBadRequest(c, detail)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "music-library/internal/models"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// setPaginationLinks writes an RFC 8288 Link header pointing at the pages
+// around list. Other query parameters of the request are preserved.
+func setPaginationLinks(c *gin.Context, list *models.SongList) {
+    var links []string
+    add := func(rel, key, value string) {
+        query := c.Request.URL.Query()
+        query.Del("page")
+        query.Del("cursor")
+        query.Set(key, value)
+        target := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+        links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel))
+    }
+
+    add("first", "page", "1")
+    if list.PrevCursor != "" {
+        add("prev", "cursor", list.PrevCursor)
+    }
+    if list.NextCursor != "" {
+        add("next", "cursor", list.NextCursor)
+    }
+    if list.PageInfo.Page > 0 && list.PageInfo.TotalPages > 0 {
+        add("last", "page", strconv.Itoa(list.PageInfo.TotalPages))
+    }
+
+    c.Header("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+)
+
+// @Summary Diff a song's lyrics
+// @Description Compare the text of a song between two revisions, or between a revision or the stored text and a candidate from the song info providers. The diff is line by line; each line carries its verse number, verses being separated by blank lines. Changes are grouped into hunks with unchanged lines around them, as in a unified diff.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param from query string false "Revision number, current for the stored text or candidate for what the song info providers have now (default: current)"
+// @Param to query string true "Revision number, current or candidate"
+// @Param context query int false "Unchanged lines around each change (default: 3)"
+// @Param unified query bool false "Also render the diff in unified format"
+// @Success 200 {object} models.LyricDiff
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Failure 502 {object} Problem
+// @Router /songs/{id}/diff [get]
+func (h *Handler) DiffSongLyrics(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    var query models.LyricDiffQuery
+    if err := c.ShouldBindQuery(&query); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    diff, err := h.songService.DiffLyrics(c.Request.Context(), id, &query, isAdmin(c))
+    if err != nil {
+        h.logger.Error("Failed to diff song lyrics", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, diff)
+}
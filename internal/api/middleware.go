@@ -0,0 +1,80 @@
+package api
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "github.com/gin-gonic/gin"
+    "music-library/internal/models"
+    "net/http"
+    "strings"
+)
+
+const (
+    requestIDHeader = "X-Request-ID"
+    requestIDKey    = "request_id"
+    actorHeader     = "X-Actor"
+    adminKey        = "admin"
+)
+
+// RequestID propagates the caller's X-Request-ID or assigns a new one, and
+// echoes it back on the response.
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        id := c.GetHeader(requestIDHeader)
+        if id == "" || len(id) > 128 {
+            id = newRequestID()
+        }
+        c.Set(requestIDKey, id)
+        c.Header(requestIDHeader, id)
+        c.Next()
+    }
+}
+
+func requestID(c *gin.Context) string {
+    return c.GetString(requestIDKey)
+}
+
+func newRequestID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return ""
+    }
+    return hex.EncodeToString(b)
+}
+
+// actor names who makes the request, as given in X-Actor by the client or
+// an authenticating proxy in front of the API.
+func actor(c *gin.Context) string {
+    name := strings.TrimSpace(c.GetHeader(actorHeader))
+    if name == "" || len(name) > 128 {
+        return models.ActorAnonymous
+    }
+    return name
+}
+
+// Admin marks requests that carry "Authorization: Bearer <token>" as made by
+// an admin. With an empty token no request is.
+func Admin(token string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+        if ok && token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+            c.Set(adminKey, true)
+        }
+        c.Next()
+    }
+}
+
+func isAdmin(c *gin.Context) bool {
+    return c.GetBool(adminKey)
+}
+
+// allowIncludeDeleted answers 403 and returns false when a request that is
+// not an admin's asks for songs in the trash.
+func allowIncludeDeleted(c *gin.Context, includeDeleted bool) bool {
+    if includeDeleted && !isAdmin(c) {
+        writeProblem(c, newProblem(c, http.StatusForbidden, ProblemTypeForbidden, "include_deleted is only available to admins"))
+        return false
+    }
+    return true
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+)
+
+// @Summary Create a playlist
+// @Description Create an empty playlist
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param playlist body models.Playlist true "Playlist object"
+// @Success 201 {object} models.Playlist
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists [post]
+func (h *Handler) CreatePlaylist(c *gin.Context) {
+    var playlist models.Playlist
+    if err := c.ShouldBindJSON(&playlist); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    if err := h.playlistService.CreatePlaylist(&playlist); err != nil {
+        h.logger.Error("Failed to create playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, playlist)
+}
+
+// @Summary List playlists
+// @Description Get a page of playlists ordered by name
+// @Tags playlists
+// @Produce json
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Success 200 {object} models.PlaylistList
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists [get]
+func (h *Handler) ListPlaylists(c *gin.Context) {
+    var filter models.PlaylistFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    list, err := h.playlistService.ListPlaylists(&filter)
+    if err != nil {
+        h.logger.Error("Failed to list playlists", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get a playlist
+// @Description Get a playlist with one page of its songs, in playlist order
+// @Tags playlists
+// @Produce json
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce audio/x-scpls
+// @Param id path int true "Playlist ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Entries per page (default: 50)"
+// @Param format query string false "Download the whole playlist as a playlist file instead of one page of JSON: m3u8, xspf or pls (also selectable with Accept)"
+// @Success 200 {object} models.PlaylistWithEntries
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id} [get]
+func (h *Handler) GetPlaylist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+    format, err := exportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
+    // A playlist file holds the whole playlist, not one page.
+    if format != nil {
+        playlist, songs, err := h.playlistService.GetPlaylistSongs(id)
+        if err != nil {
+            h.logger.Error("Failed to get playlist songs", zap.Error(err), zap.String("request_id", requestID(c)))
+            respondError(c, err)
+            return
+        }
+        writePlaylistFile(c, format, fmt.Sprintf("playlist-%d", id), playlist.Name, songs)
+        return
+    }
+
+    var pagination models.PlaylistPagination
+    if err := c.ShouldBindQuery(&pagination); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    playlist, err := h.playlistService.GetPlaylist(id, &pagination)
+    if err != nil {
+        h.logger.Error("Failed to get playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, playlist)
+}
+
+// @Summary Rename a playlist
+// @Description Change the name and description of a playlist
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param id path int true "Playlist ID"
+// @Param playlist body models.Playlist true "Playlist object"
+// @Success 200 {object} models.Playlist
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id} [put]
+func (h *Handler) UpdatePlaylist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+
+    var playlist models.Playlist
+    if err := c.ShouldBindJSON(&playlist); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    playlist.ID = id
+    if err := h.playlistService.UpdatePlaylist(&playlist); err != nil {
+        h.logger.Error("Failed to update playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, playlist)
+}
+
+// @Summary Delete a playlist
+// @Description Delete a playlist. The songs are kept.
+// @Tags playlists
+// @Produce json
+// @Param id path int true "Playlist ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id} [delete]
+func (h *Handler) DeletePlaylist(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+
+    if err := h.playlistService.DeletePlaylist(id); err != nil {
+        h.logger.Error("Failed to delete playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Status(http.StatusNoContent)
+}
+
+// @Summary Add a song to a playlist
+// @Description Insert a song at a position, shifting later entries down, or append it when position is omitted. A song can be added more than once.
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param id path int true "Playlist ID"
+// @Param entry body models.PlaylistAdd true "Song and position"
+// @Success 201 {object} models.PlaylistEntry
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id}/songs [post]
+func (h *Handler) AddPlaylistSong(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+
+    var add models.PlaylistAdd
+    if err := c.ShouldBindJSON(&add); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    entry, err := h.playlistService.AddSong(id, &add)
+    if err != nil {
+        h.logger.Error("Failed to add song to playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, entry)
+}
+
+// @Summary Remove a song from a playlist
+// @Description Remove the entry at a position; later entries move up
+// @Tags playlists
+// @Produce json
+// @Param id path int true "Playlist ID"
+// @Param position path int true "Entry position, starting at 1"
+// @Success 204 "No Content"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id}/songs/{position} [delete]
+func (h *Handler) RemovePlaylistSong(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+    position, err := strconv.Atoi(c.Param("position"))
+    if err != nil {
+        h.logger.Error("Invalid playlist position", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist position")
+        return
+    }
+
+    if err := h.playlistService.RemoveSong(id, position); err != nil {
+        h.logger.Error("Failed to remove song from playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Status(http.StatusNoContent)
+}
+
+// @Summary Move a song within a playlist
+// @Description Move the entry at one position to another, shifting the entries in between
+// @Tags playlists
+// @Accept json
+// @Produce json
+// @Param id path int true "Playlist ID"
+// @Param move body models.PlaylistMove true "From and to positions"
+// @Success 200 {object} models.PlaylistEntry
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /playlists/{id}/moves [post]
+func (h *Handler) MovePlaylistSong(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid playlist ID", zap.Error(err))
+        respondBadRequest(c, "Invalid playlist ID")
+        return
+    }
+
+    var move models.PlaylistMove
+    if err := c.ShouldBindJSON(&move); err != nil {
+        h.logger.Error("Failed to bind JSON", zap.Error(err))
+        respondBindError(c, err, "Invalid request body")
+        return
+    }
+
+    entry, err := h.playlistService.MoveSong(id, &move)
+    if err != nil {
+        h.logger.Error("Failed to move song in playlist", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, entry)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "github.com/gin-gonic/gin"
+    "github.com/go-playground/validator/v10"
+    "music-library/internal/models"
+    "net/http"
+    "reflect"
+    "strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem type URIs returned in the "type" member of a Problem.
+const (
+    ProblemTypeBadRequest = "urn:music-library:problem:bad-request"
+    ProblemTypeNotFound   = "urn:music-library:problem:not-found"
+    ProblemTypeForbidden  = "urn:music-library:problem:forbidden"
+    ProblemTypeMediaType  = "urn:music-library:problem:unsupported-media-type"
+    ProblemTypeTooLarge   = "urn:music-library:problem:payload-too-large"
+    ProblemTypeConflict   = "urn:music-library:problem:conflict"
+    ProblemTypeStale      = "urn:music-library:problem:precondition-failed"
+    ProblemTypeValidation = "urn:music-library:problem:validation"
+    ProblemTypeUpstream   = "urn:music-library:problem:upstream"
+    ProblemTypeInternal   = "urn:music-library:problem:internal"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+    Type      string       `json:"type"`
+    Title     string       `json:"title"`
+    Status    int          `json:"status"`
+    Detail    string       `json:"detail,omitempty"`
+    Instance  string       `json:"instance,omitempty"`
+    RequestID string       `json:"request_id,omitempty"`
+    Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+func newProblem(c *gin.Context, status int, problemType, detail string) *Problem {
+    return &Problem{
+        Type:      problemType,
+        Title:     http.StatusText(status),
+        Status:    status,
+        Detail:    detail,
+        Instance:  c.Request.URL.Path,
+        RequestID: requestID(c),
+    }
+}
+
+func writeProblem(c *gin.Context, problem *Problem) {
+    body, err := json.Marshal(problem)
+    if err != nil {
+        c.AbortWithStatus(http.StatusInternalServerError)
+        return
+    }
+    c.Data(problem.Status, problemContentType, body)
+}
+
+// respondBadRequest reports a malformed request, such as an unparsable path
+// parameter or body.
+func respondBadRequest(c *gin.Context, detail string) {
+    writeProblem(c, newProblem(c, http.StatusBadRequest, ProblemTypeBadRequest, detail))
+}
+
+func respondUnsupportedMediaType(c *gin.Context, detail string) {
+    writeProblem(c, newProblem(c, http.StatusUnsupportedMediaType, ProblemTypeMediaType, detail))
+}
+
+// respondBindError reports a failed ShouldBind call. Validator failures are
+// listed per field; anything else is treated as a malformed request.
+func respondBindError(c *gin.Context, err error, detail string) {
+    var validationErrs validator.ValidationErrors
+    if !errors.As(err, &validationErrs) {
+        respondBadRequest(c, detail)
+        return
+    }
+
+    problem := newProblem(c, http.StatusUnprocessableEntity, ProblemTypeValidation, "One or more fields are invalid")
+    for _, fe := range validationErrs {
+        problem.Errors = append(problem.Errors, FieldError{
+            Field:   fieldPath(fe),
+            Message: validationMessage(fe),
+        })
+    }
+    writeProblem(c, problem)
+}
+
+// respondError maps a service error onto a problem response. Internal
+// details are never sent to the client, only the request ID to correlate
+// with the server logs.
+func respondError(c *gin.Context, err error) {
+    var problem *Problem
+    switch {
+    case errors.Is(err, models.ErrNotFound):
+        problem = newProblem(c, http.StatusNotFound, ProblemTypeNotFound, "The requested resource was not found")
+    case errors.Is(err, models.ErrConflict):
+        problem = newProblem(c, http.StatusConflict, ProblemTypeConflict, "The request conflicts with the current state of the resource")
+    case errors.Is(err, models.ErrPreconditionFailed):
+        problem = newProblem(c, http.StatusPreconditionFailed, ProblemTypeStale, "The resource has been modified; fetch it again to get the current ETag")
+    case errors.Is(err, models.ErrValidation):
+        problem = newProblem(c, http.StatusUnprocessableEntity, ProblemTypeValidation, "One or more fields are invalid")
+        var fieldErr *models.ValidationError
+        if errors.As(err, &fieldErr) {
+            problem.Errors = []FieldError{{Field: fieldErr.Field, Message: fieldErr.Message}}
+        }
+    case errors.Is(err, models.ErrUpstream):
+        problem = newProblem(c, http.StatusBadGateway, ProblemTypeUpstream, "The music info service failed to respond")
+    default:
+        problem = newProblem(c, http.StatusInternalServerError, ProblemTypeInternal, "An unexpected error occurred")
+    }
+    writeProblem(c, problem)
+}
+
+func fieldPath(fe validator.FieldError) string {
+    // Namespace is "Song.group"; drop the top-level struct name.
+    ns := fe.Namespace()
+    if i := strings.IndexByte(ns, '.'); i >= 0 {
+        return ns[i+1:]
+    }
+    return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError) string {
+    switch fe.Tag() {
+    case "required":
+        return "is required"
+    case "min", "gte":
+        return fmt.Sprintf("must be at least %s", fe.Param())
+    case "max", "lte":
+        return fmt.Sprintf("must be at most %s", fe.Param())
+    case "oneof":
+        return fmt.Sprintf("must be one of: %s", fe.Param())
+    default:
+        return fmt.Sprintf("failed the %q check", fe.Tag())
+    }
+}
+
+// jsonFieldName makes validator report fields by their JSON or form name
+// instead of the Go struct field name.
+func jsonFieldName(field reflect.StructField) string {
+    for _, tag := range []string{"json", "form"} {
+        name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+        if name == "-" {
+            return ""
+        }
+        if name != "" {
+            return name
+        }
+    }
+    return field.Name
+}
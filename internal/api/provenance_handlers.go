@@ -0,0 +1,86 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "net/http"
+    "strconv"
+)
+
+// @Summary Get a song's field provenance
+// @Description List, for each field of the song, the source of its current value (user, import or the name of a song info provider) and whether it is locked against enrichment.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} models.SongProvenance
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/provenance [get]
+func (h *Handler) GetSongProvenance(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    provenance, err := h.songService.GetProvenance(id, isAdmin(c))
+    if err != nil {
+        h.logger.Error("Failed to get song provenance", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, provenance)
+}
+
+// @Summary Lock a song field
+// @Description Keep enrichment from overwriting a field, even one that is empty or came from an upstream. Only releaseDate, text and link can be locked. The field can still be edited.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param field path string true "Field name" Enums(releaseDate, text, link)
+// @Success 200 {object} models.SongProvenance
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/locks/{field} [put]
+func (h *Handler) LockSongField(c *gin.Context) {
+    h.setSongFieldLock(c, true)
+}
+
+// @Summary Unlock a song field
+// @Description Let enrichment overwrite the field again, unless it holds a value a user or an import set.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param field path string true "Field name" Enums(releaseDate, text, link)
+// @Success 200 {object} models.SongProvenance
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/locks/{field} [delete]
+func (h *Handler) UnlockSongField(c *gin.Context) {
+    h.setSongFieldLock(c, false)
+}
+
+func (h *Handler) setSongFieldLock(c *gin.Context, locked bool) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    provenance, err := h.songService.SetFieldLock(id, c.Param("field"), locked)
+    if err != nil {
+        h.logger.Error("Failed to set song field lock", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, provenance)
+}
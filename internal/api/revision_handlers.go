@@ -0,0 +1,137 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// @Summary List a song's revisions
+// @Description List the changes made to a song, newest first: who made each one, which fields it changed and the version it produced. The history of a song in the trash, or purged, is only available to admins.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param page_size query int false "Page size (default: 10)"
+// @Success 200 {object} models.SongRevisionList
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/revisions [get]
+func (h *Handler) ListSongRevisions(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    var filter models.RevisionFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+
+    list, err := h.songService.ListRevisions(id, &filter, isAdmin(c))
+    if err != nil {
+        h.logger.Error("Failed to list song revisions", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get a song revision
+// @Description Get one revision of a song with the old and new value of every field it changed, and the song as the revision left it.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.SongRevision
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/revisions/{rev} [get]
+func (h *Handler) GetSongRevision(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+    revision, err := strconv.Atoi(c.Param("rev"))
+    if err != nil {
+        h.logger.Error("Invalid revision", zap.Error(err))
+        respondBadRequest(c, "Invalid revision")
+        return
+    }
+
+    rev, err := h.songService.GetRevision(id, revision, isAdmin(c))
+    if err != nil {
+        h.logger.Error("Failed to get song revision", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, rev)
+}
+
+// SongRevisionAction dispatches the custom methods on a revision,
+// /songs/:id/revisions/<rev>:<action>, like SongsAction does for the songs
+// collection.
+func (h *Handler) SongRevisionAction(c *gin.Context) {
+    rev, action, _ := strings.Cut(c.Param("rev"), ":")
+    switch action {
+    case "restore":
+        h.RestoreSongRevision(c, rev)
+    default:
+        writeProblem(c, newProblem(c, http.StatusNotFound, ProblemTypeNotFound, "The requested resource was not found"))
+    }
+}
+
+// @Summary Restore a song revision
+// @Description Set the song's fields back to how the revision left them, as a new revision. Restoring a revision of a deleted song recreates it under its old ID. Provenance of the restored fields is set to user.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Param If-Match header string false "ETag the song must still match, or a list of them"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
+// @Success 200 {object} models.Song
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}/revisions/{rev}:restore [post]
+func (h *Handler) RestoreSongRevision(c *gin.Context, rev string) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+    revision, err := strconv.Atoi(rev)
+    if err != nil {
+        h.logger.Error("Invalid revision", zap.Error(err))
+        respondBadRequest(c, "Invalid revision")
+        return
+    }
+
+    song, err := h.songService.RestoreRevision(id, revision, h.ifMatchVersion(c, id), actor(c))
+    if err != nil {
+        h.logger.Error("Failed to restore song revision", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Header("ETag", songETag(song))
+    c.JSON(http.StatusOK, song)
+}
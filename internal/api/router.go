@@ -2,12 +2,21 @@ package api
 
 import (
     "github.com/gin-gonic/gin"
+    "github.com/gin-gonic/gin/binding"
+    "github.com/go-playground/validator/v10"
     swaggerFiles "github.com/swaggo/files"
     ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRouter(handler *Handler) *gin.Engine {
+// SetupRouter builds the routes. Requests bearing adminToken are treated as
+// an admin's.
+func SetupRouter(handler *Handler, adminToken string) *gin.Engine {
+    if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+        v.RegisterTagNameFunc(jsonFieldName)
+    }
+
     router := gin.Default()
+    router.Use(RequestID(), Admin(adminToken))
 
     // Swagger documentation
     router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -19,11 +28,62 @@ func SetupRouter(handler *Handler) *gin.Engine {
         {
             songs.POST("", handler.CreateSong)
             songs.GET("", handler.ListSongs)
+            songs.GET("/suggest", handler.SuggestSongs)
             songs.GET("/:id", handler.GetSong)
             songs.GET("/:id/verses", handler.GetSongVerses)
+            songs.GET("/:id/enrichment", handler.GetSongEnrichment)
+            songs.POST("/:id/enrich", handler.EnrichSong)
+            songs.GET("/:id/provenance", handler.GetSongProvenance)
+            songs.PUT("/:id/locks/:field", handler.LockSongField)
+            songs.DELETE("/:id/locks/:field", handler.UnlockSongField)
+            songs.GET("/:id/revisions", handler.ListSongRevisions)
+            songs.GET("/:id/revisions/:rev", handler.GetSongRevision)
+            songs.POST("/:id/revisions/:rev", handler.SongRevisionAction)
+            songs.GET("/:id/diff", handler.DiffSongLyrics)
             songs.PUT("/:id", handler.UpdateSong)
+            songs.PATCH("/:id", handler.PatchSong)
+            songs.POST("/:id", handler.SongAction)
             songs.DELETE("/:id", handler.DeleteSong)
         }
+
+        v1.GET("/songs:action", handler.SongsAction)
+        v1.POST("/songs:action", handler.SongsAction)
+
+        artists := v1.Group("/artists")
+        {
+            artists.POST("", handler.CreateArtist)
+            artists.GET("", handler.ListArtists)
+            artists.GET("/:id", handler.GetArtist)
+            artists.GET("/:id/songs", handler.ListArtistSongs)
+            artists.PUT("/:id", handler.UpdateArtist)
+            artists.DELETE("/:id", handler.DeleteArtist)
+        }
+
+        albums := v1.Group("/albums")
+        {
+            albums.POST("", handler.CreateAlbum)
+            albums.GET("", handler.ListAlbums)
+            albums.GET("/:id", handler.GetAlbum)
+            albums.PUT("/:id/tracks", handler.SetAlbumTracks)
+            albums.DELETE("/:id", handler.DeleteAlbum)
+        }
+
+        playlists := v1.Group("/playlists")
+        {
+            playlists.POST("", handler.CreatePlaylist)
+            playlists.GET("", handler.ListPlaylists)
+            playlists.GET("/:id", handler.GetPlaylist)
+            playlists.PUT("/:id", handler.UpdatePlaylist)
+            playlists.DELETE("/:id", handler.DeletePlaylist)
+            playlists.POST("/:id/songs", handler.AddPlaylistSong)
+            playlists.DELETE("/:id/songs/:position", handler.RemovePlaylistSong)
+            playlists.POST("/:id/moves", handler.MovePlaylistSong)
+        }
+
+        v1.GET("/search", handler.SearchSongs)
+
+        v1.GET("/health", handler.Health)
+        v1.GET("/health/live", handler.Live)
     }
 
     return router
@@ -0,0 +1,249 @@
+package api
+
+import (
+    "compress/gzip"
+    "encoding/csv"
+    "encoding/json"
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "io"
+    "music-library/internal/models"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// songEncoder writes songs one at a time in an export format.
+type songEncoder interface {
+    Begin() error
+    Encode(song *models.Song) error
+    End() error
+}
+
+type exportFormatInfo struct {
+    contentType string
+    newEncoder  func(w io.Writer) songEncoder
+}
+
+// songExportFormats are the formats of GET /songs:export, keyed by the
+// format parameter.
+var songExportFormats = map[string]exportFormatInfo{
+    "ndjson": {"application/x-ndjson", func(w io.Writer) songEncoder { return &ndjsonSongEncoder{enc: json.NewEncoder(w)} }},
+    "json":   {"application/json; charset=utf-8", func(w io.Writer) songEncoder { return &jsonSongEncoder{w: w} }},
+    "csv":    {"text/csv; charset=utf-8", func(w io.Writer) songEncoder { return &csvSongEncoder{w: csv.NewWriter(w)} }},
+}
+
+// songExportFormat picks the export format from ?format= or the Accept
+// header, defaulting to NDJSON.
+func songExportFormat(c *gin.Context) (string, error) {
+    if name := strings.ToLower(c.Query("format")); name != "" {
+        if _, ok := songExportFormats[name]; !ok {
+            return "", &models.ValidationError{Field: "format", Message: "must be one of ndjson, json, csv"}
+        }
+        return name, nil
+    }
+
+    switch c.NegotiateFormat("application/x-ndjson", "application/json", "text/csv") {
+    case "application/json":
+        return "json", nil
+    case "text/csv":
+        return "csv", nil
+    }
+    return "ndjson", nil
+}
+
+// @Summary Export songs
+// @Description Stream every song matching the filters, including lyrics, as NDJSON (default), JSON or CSV. Paging parameters are ignored. The response is gzip-compressed when the client accepts it. If the export fails midway the stream is cut off, so a JSON export lacks its closing bracket.
+// @Tags songs
+// @Produce application/x-ndjson
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "ndjson, json or csv (also selectable with Accept)"
+// @Param group query string false "Filter by group name"
+// @Param artist_id query int false "Filter by artist ID"
+// @Param album_id query int false "Only songs on this album"
+// @Param song query string false "Filter by song name"
+// @Param release_date query string false "Filter by release date (YYYY-MM-DD or DD.MM.YYYY)"
+// @Param released_after query string false "Only songs released after this date"
+// @Param released_before query string false "Only songs released before this date"
+// @Param year query int false "Only songs released in this year"
+// @Param fuzzy query bool false "Match group and song by trigram similarity instead of substring"
+// @Param sort query string false "Comma-separated sort keys, prefix with - for descending (default: id)"
+// @Param include_deleted query bool false "Export songs in the trash too (admins only)"
+// @Success 200 {array} models.Song
+// @Failure 400 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs:export [get]
+func (h *Handler) ExportSongs(c *gin.Context) {
+    format, err := songExportFormat(c)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+
+    var filter models.SongFilter
+    if err := c.ShouldBindQuery(&filter); err != nil {
+        h.logger.Error("Failed to bind query parameters", zap.Error(err))
+        respondBindError(c, err, "Invalid query parameters")
+        return
+    }
+    if !allowIncludeDeleted(c, filter.IncludeDeleted) {
+        return
+    }
+
+    // The response starts with the first song, so errors found before
+    // that can still be answered with a problem document.
+    var out io.Writer = c.Writer
+    var gz *gzip.Writer
+    var enc songEncoder
+    start := func() error {
+        info := songExportFormats[format]
+        c.Header("Content-Type", info.contentType)
+        c.Header("Content-Disposition", `attachment; filename="songs-`+time.Now().UTC().Format("20060102")+`.`+format+`"`)
+        c.Header("Vary", "Accept, Accept-Encoding")
+        if acceptsGzip(c) {
+            c.Header("Content-Encoding", "gzip")
+            gz = gzip.NewWriter(c.Writer)
+            out = gz
+        }
+        c.Status(http.StatusOK)
+        enc = info.newEncoder(out)
+        return enc.Begin()
+    }
+
+    err = h.songService.ExportSongs(&filter, func(song *models.Song) error {
+        if enc == nil {
+            if err := start(); err != nil {
+                return err
+            }
+        }
+        return enc.Encode(song)
+    })
+    if err != nil {
+        h.logger.Error("Failed to export songs", zap.Error(err), zap.String("request_id", requestID(c)))
+        if enc == nil {
+            respondError(c, err)
+            return
+        }
+        // Too late for a status code: cut the stream short.
+        c.Abort()
+        return
+    }
+
+    if enc == nil {
+        if err := start(); err != nil {
+            return
+        }
+    }
+    if err := enc.End(); err != nil {
+        h.logger.Error("Failed to finish export", zap.Error(err))
+        return
+    }
+    if gz != nil {
+        if err := gz.Close(); err != nil {
+            h.logger.Error("Failed to finish export", zap.Error(err))
+        }
+    }
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(c *gin.Context) bool {
+    for _, part := range strings.Split(c.GetHeader("Accept-Encoding"), ",") {
+        fields := strings.Split(part, ";")
+        if strings.TrimSpace(strings.ToLower(fields[0])) != "gzip" {
+            continue
+        }
+        for _, param := range fields[1:] {
+            if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+                if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+                    return false
+                }
+            }
+        }
+        return true
+    }
+    return false
+}
+
+type ndjsonSongEncoder struct {
+    enc *json.Encoder
+}
+
+func (e *ndjsonSongEncoder) Begin() error { return nil }
+
+func (e *ndjsonSongEncoder) Encode(song *models.Song) error { return e.enc.Encode(song) }
+
+func (e *ndjsonSongEncoder) End() error { return nil }
+
+// jsonSongEncoder writes a JSON array one element at a time.
+type jsonSongEncoder struct {
+    w     io.Writer
+    count int
+}
+
+func (e *jsonSongEncoder) Begin() error {
+    _, err := io.WriteString(e.w, "[")
+    return err
+}
+
+func (e *jsonSongEncoder) Encode(song *models.Song) error {
+    data, err := json.Marshal(song)
+    if err != nil {
+        return err
+    }
+    if e.count > 0 {
+        if _, err := io.WriteString(e.w, ",\n"); err != nil {
+            return err
+        }
+    }
+    e.count++
+    _, err = e.w.Write(data)
+    return err
+}
+
+func (e *jsonSongEncoder) End() error {
+    _, err := io.WriteString(e.w, "]\n")
+    return err
+}
+
+// csvSongColumns is the CSV header. It matches the columns the bulk import
+// reads, so an export can be imported again.
+var csvSongColumns = []string{
+    "id", "artist_id", "group", "song", "release_date", "text", "link", "language", "version", "created_at", "updated_at",
+}
+
+type csvSongEncoder struct {
+    w *csv.Writer
+}
+
+func (e *csvSongEncoder) Begin() error {
+    return e.w.Write(csvSongColumns)
+}
+
+func (e *csvSongEncoder) Encode(song *models.Song) error {
+    releaseDate := ""
+    if !song.ReleaseDate.IsZero() {
+        releaseDate = song.ReleaseDate.String()
+    }
+    return e.w.Write([]string{
+        strconv.Itoa(song.ID),
+        strconv.Itoa(song.ArtistID),
+        song.GroupName,
+        song.SongName,
+        releaseDate,
+        song.Text,
+        song.Link,
+        song.Language,
+        strconv.Itoa(song.Version),
+        song.CreatedAt.Format(time.RFC3339Nano),
+        song.UpdatedAt.Format(time.RFC3339Nano),
+    })
+}
+
+func (e *csvSongEncoder) End() error {
+    e.w.Flush()
+    return e.w.Error()
+}
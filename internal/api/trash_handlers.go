@@ -0,0 +1,54 @@
+package api
+
+import (
+    "github.com/gin-gonic/gin"
+    "go.uber.org/zap"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// SongAction dispatches the custom methods on a song, /songs/<id>:<action>,
+// like SongsAction does for the songs collection.
+func (h *Handler) SongAction(c *gin.Context) {
+    id, action, _ := strings.Cut(c.Param("id"), ":")
+    switch action {
+    case "restore":
+        h.RestoreSong(c, id)
+    default:
+        writeProblem(c, newProblem(c, http.StatusNotFound, ProblemTypeNotFound, "The requested resource was not found"))
+    }
+}
+
+// @Summary Restore a song from the trash
+// @Description Take a deleted song out of the trash before it is purged. Restoring a song that is not in the trash returns it unchanged.
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param If-Match header string false "ETag the song must still match, or a list of them"
+// @Param X-Actor header string false "Who makes the change, recorded in the song's revisions"
+// @Success 200 {object} models.Song
+// @Header 200 {string} ETag "Song version and enrichment status"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /songs/{id}:restore [post]
+func (h *Handler) RestoreSong(c *gin.Context, rawID string) {
+    id, err := strconv.Atoi(rawID)
+    if err != nil {
+        h.logger.Error("Invalid song ID", zap.Error(err))
+        respondBadRequest(c, "Invalid song ID")
+        return
+    }
+
+    song, err := h.songService.RestoreSong(id, h.ifMatchVersion(c, id), actor(c))
+    if err != nil {
+        h.logger.Error("Failed to restore song", zap.Error(err), zap.String("request_id", requestID(c)))
+        respondError(c, err)
+        return
+    }
+
+    c.Header("ETag", songETag(song))
+    c.JSON(http.StatusOK, song)
+}
@@ -4,6 +4,8 @@ import (
     "fmt"
     "github.com/joho/godotenv"
     "os"
+    "strconv"
+    "time"
 )
 
 type Config struct {
@@ -13,8 +15,30 @@ type Config struct {
     DBPassword string
     DBName     string
     DBSSLMode  string
+    AutoMigrate bool
     MusicAPIURL string
     ServerPort string
+    EnrichWorkers     int
+    EnrichMaxAttempts int
+
+    MusicAPITimeout          time.Duration
+    MusicAPIMaxRetries       int
+    MusicAPIBreakerThreshold int
+    MusicAPIBreakerCooldown  time.Duration
+
+    SongInfoCacheSize        int
+    SongInfoCacheTTL         time.Duration
+    SongInfoCacheNegativeTTL time.Duration
+    SongInfoCacheShared      bool
+
+    SongInfoProviders   string
+    SongInfoPrecedence  string
+    SongInfoCatalogue   string
+    SongInfoMusicBrainz string
+
+    AdminToken         string
+    TrashRetentionDays int
+    TrashPurgeInterval time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -22,19 +46,79 @@ func LoadConfig() (*Config, error) {
         return nil, fmt.Errorf("error loading .env file: %w", err)
     }
 
-    return &Config{
+    cfg := &Config{
         DBHost:     os.Getenv("DB_HOST"),
         DBPort:     os.Getenv("DB_PORT"),
         DBUser:     os.Getenv("DB_USER"),
         DBPassword: os.Getenv("DB_PASSWORD"),
         DBName:     os.Getenv("DB_NAME"),
         DBSSLMode:  os.Getenv("DB_SSL_MODE"),
+        AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
         MusicAPIURL: os.Getenv("MUSIC_API_URL"),
         ServerPort: os.Getenv("SERVER_PORT"),
-    }, nil
+        EnrichWorkers:     getEnvInt("ENRICH_WORKERS", 4),
+        EnrichMaxAttempts: getEnvInt("ENRICH_MAX_ATTEMPTS", 5),
+
+        MusicAPITimeout:          getEnvDuration("MUSIC_API_TIMEOUT", 10*time.Second),
+        MusicAPIMaxRetries:       getEnvInt("MUSIC_API_MAX_RETRIES", 3),
+        MusicAPIBreakerThreshold: getEnvInt("MUSIC_API_BREAKER_THRESHOLD", 5),
+        MusicAPIBreakerCooldown:  getEnvDuration("MUSIC_API_BREAKER_COOLDOWN", 30*time.Second),
+
+        SongInfoCacheSize:        getEnvInt("SONG_INFO_CACHE_SIZE", 1000),
+        SongInfoCacheTTL:         getEnvDuration("SONG_INFO_CACHE_TTL", 24*time.Hour),
+        SongInfoCacheNegativeTTL: getEnvDuration("SONG_INFO_CACHE_NEGATIVE_TTL", 10*time.Minute),
+        SongInfoCacheShared:      getEnvBool("SONG_INFO_CACHE_SHARED", false),
+
+        SongInfoProviders:   getEnv("SONG_INFO_PROVIDERS", "music_api"),
+        SongInfoPrecedence:  os.Getenv("SONG_INFO_PRECEDENCE"),
+        SongInfoCatalogue:   os.Getenv("SONG_INFO_CATALOGUE"),
+        SongInfoMusicBrainz: os.Getenv("SONG_INFO_MUSICBRAINZ_DUMP"),
+
+        AdminToken:         os.Getenv("ADMIN_TOKEN"),
+        TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+        TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+    }
+    if cfg.EnrichWorkers < 1 {
+        return nil, fmt.Errorf("ENRICH_WORKERS must be at least 1, got %d", cfg.EnrichWorkers)
+    }
+    if cfg.MusicAPIBreakerThreshold < 1 {
+        return nil, fmt.Errorf("MUSIC_API_BREAKER_THRESHOLD must be at least 1, got %d", cfg.MusicAPIBreakerThreshold)
+    }
+    return cfg, nil
 }
 
 func (c *Config) GetDBConnString() string {
     return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
         c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)
 }
+
+func getEnv(key, fallback string) string {
+    if value := os.Getenv(key); value != "" {
+        return value
+    }
+    return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+    value, err := strconv.ParseBool(os.Getenv(key))
+    if err != nil {
+        return fallback
+    }
+    return value
+}
+
+func getEnvInt(key string, fallback int) int {
+    value, err := strconv.Atoi(os.Getenv(key))
+    if err != nil || value < 0 {
+        return fallback
+    }
+    return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+    value, err := time.ParseDuration(os.Getenv(key))
+    if err != nil || value <= 0 {
+        return fallback
+    }
+    return value
+}
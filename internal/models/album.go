@@ -0,0 +1,49 @@
+package models
+
+import (
+    "time"
+)
+
+type Album struct {
+    ID          int       `json:"id"`
+    ArtistID    int       `json:"artist_id"`
+    Artist      string    `json:"artist" binding:"required"`
+    Title       string    `json:"title" binding:"required"`
+    ReleaseDate Date      `json:"releaseDate" swaggertype:"string" format:"date" example:"2006-07-03"`
+    CoverLink   string    `json:"cover_link"`
+    TrackCount  int       `json:"track_count"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+
+    // SongIDs is the initial track listing when creating an album.
+    SongIDs []int `json:"song_ids,omitempty"`
+}
+
+type AlbumTrack struct {
+    Position int  `json:"position"`
+    Song     Song `json:"song"`
+}
+
+type AlbumWithTracks struct {
+    Album
+    Tracks []AlbumTrack `json:"tracks"`
+}
+
+// AlbumTracks is the body of a track listing update: the album's songs in
+// their new order.
+type AlbumTracks struct {
+    SongIDs []int `json:"song_ids" binding:"required"`
+}
+
+type AlbumFilter struct {
+    ArtistID int    `form:"artist_id"`
+    Title    string `form:"title"`
+    Page     int    `form:"page,default=1"`
+    PageSize int    `form:"page_size,default=10"`
+}
+
+type AlbumList struct {
+    Items    []Album  `json:"items"`
+    Total    int      `json:"total"`
+    PageInfo PageInfo `json:"page_info"`
+}
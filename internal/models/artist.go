@@ -0,0 +1,38 @@
+package models
+
+import (
+    "strings"
+    "time"
+)
+
+type Artist struct {
+    ID        int       `json:"id"`
+    Name      string    `json:"name" binding:"required"`
+    SongCount int       `json:"song_count"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ArtistFilter struct {
+    Name     string `form:"name"`
+    Page     int    `form:"page,default=1"`
+    PageSize int    `form:"page_size,default=10"`
+}
+
+type ArtistList struct {
+    Items    []Artist `json:"items"`
+    Total    int      `json:"total"`
+    PageInfo PageInfo `json:"page_info"`
+}
+
+// NormalizeArtistName trims an artist name and collapses runs of
+// whitespace, so "Muse" and " Muse " name the same artist.
+func NormalizeArtistName(name string) string {
+    return strings.Join(strings.Fields(name), " ")
+}
+
+// ArtistNameKey is the case-insensitive identity of an artist name. It
+// matches the artists.name_key column.
+func ArtistNameKey(name string) string {
+    return strings.ToLower(NormalizeArtistName(name))
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// DateLayout is the ISO 8601 calendar date format used for output.
+const DateLayout = "2006-01-02"
+
+// dateInputLayouts are accepted when parsing: the DD.MM.YYYY format used by
+// the music info API, ISO 8601 dates and full RFC 3339 timestamps.
+var dateInputLayouts = []string{"02.01.2006", DateLayout, time.RFC3339Nano}
+
+// Date is a calendar date without a time of day. The zero Date is stored as
+// NULL and marshalled as an empty string.
+type Date struct {
+    time.Time
+}
+
+func NewDate(year int, month time.Month, day int) Date {
+    return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses DD.MM.YYYY, YYYY-MM-DD or an RFC 3339 timestamp.
+func ParseDate(value string) (Date, error) {
+    value = strings.TrimSpace(value)
+    if value == "" {
+        return Date{}, nil
+    }
+
+    for _, layout := range dateInputLayouts {
+        if t, err := time.Parse(layout, value); err == nil {
+            return NewDate(t.Date()), nil
+        }
+    }
+    return Date{}, fmt.Errorf("invalid date %q: use DD.MM.YYYY or YYYY-MM-DD", value)
+}
+
+func (d Date) String() string {
+    if d.IsZero() {
+        return ""
+    }
+    return d.Format(DateLayout)
+}
+
+// AddDays returns the date n days later.
+func (d Date) AddDays(n int) Date {
+    return Date{d.AddDate(0, 0, n)}
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+    return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+    var value *string
+    if err := json.Unmarshal(data, &value); err != nil {
+        return fmt.Errorf("date must be a string: %w", err)
+    }
+    if value == nil {
+        *d = Date{}
+        return nil
+    }
+
+    parsed, err := ParseDate(*value)
+    if err != nil {
+        return err
+    }
+    *d = parsed
+    return nil
+}
+
+func (d *Date) Scan(src interface{}) error {
+    switch v := src.(type) {
+    case nil:
+        *d = Date{}
+    case time.Time:
+        *d = NewDate(v.Date())
+    case string:
+        return d.parseScanned(v)
+    case []byte:
+        return d.parseScanned(string(v))
+    default:
+        return fmt.Errorf("cannot scan %T into Date", src)
+    }
+    return nil
+}
+
+func (d *Date) parseScanned(value string) error {
+    parsed, err := ParseDate(value)
+    if err != nil {
+        return err
+    }
+    *d = parsed
+    return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+    if d.IsZero() {
+        return nil, nil
+    }
+    return d.Format(DateLayout), nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// EnrichmentStatus tracks whether a song's details have been fetched from
+// the music info API.
+type EnrichmentStatus string
+
+const (
+    EnrichmentPending  EnrichmentStatus = "pending"
+    EnrichmentEnriched EnrichmentStatus = "enriched"
+    EnrichmentFailed   EnrichmentStatus = "failed"
+)
+
+// EnrichmentJob is a claimed request to enrich a song. Attempts includes the
+// current one. Refresh asks for fresh details instead of cached ones.
+type EnrichmentJob struct {
+    ID        int64
+    SongID    int
+    GroupName string
+    SongName  string
+    Attempts  int
+    Refresh   bool
+}
+
+// Enrichment is the enrichment state of a song as reported to clients.
+type Enrichment struct {
+    SongID        int              `json:"song_id"`
+    Status        EnrichmentStatus `json:"status"`
+    Attempts      int              `json:"attempts"`
+    Error         string           `json:"error,omitempty"`
+    NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty"`
+    // Sources names the provider of each field whose current value came
+    // from enrichment.
+    Sources map[string]string `json:"sources,omitempty"`
+}
+
+// CachedSongInfo is a cached lookup in the music info API. NotFound records
+// that the API does not know the song, in which case Detail is nil.
+type CachedSongInfo struct {
+    Detail    *SongDetail
+    NotFound  bool
+    ExpiresAt time.Time
+}
+
+// SongInfoKey identifies a lookup: group and song name, ignoring case and
+// extra whitespace.
+func SongInfoKey(group, song string) string {
+    return ArtistNameKey(group) + "\x00" + ArtistNameKey(song)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+)
+
+// Domain errors shared by the repository, service and API layers. Wrap them
+// with fmt.Errorf("...: %w", Err...) and test with errors.Is.
+var (
+    ErrNotFound   = errors.New("not found")
+    ErrConflict   = errors.New("conflict")
+    ErrValidation = errors.New("validation failed")
+    ErrUpstream   = errors.New("upstream service error")
+
+    // ErrPreconditionFailed means the stored version no longer matches the
+    // version the client expected.
+    ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// ValidationError rejects a single input field. It matches ErrValidation.
+type ValidationError struct {
+    Field   string
+    Message string
+}
+
+func (e *ValidationError) Error() string {
+    return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Is(target error) bool {
+    return target == ErrValidation
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type HealthStatus string
+
+const (
+    HealthUp       HealthStatus = "up"
+    HealthDegraded HealthStatus = "degraded"
+    HealthDown     HealthStatus = "down"
+)
+
+// HealthCheck is the state of one dependency of the service.
+type HealthCheck struct {
+    Status HealthStatus `json:"status"`
+    // Critical checks make the whole service unavailable when down.
+    Critical bool   `json:"critical"`
+    Error    string `json:"error,omitempty"`
+    // Circuit is set for dependencies guarded by a circuit breaker.
+    Circuit *CircuitState `json:"circuit,omitempty"`
+    // Cache is set for caches.
+    Cache *CacheStats `json:"cache,omitempty"`
+}
+
+type HealthReport struct {
+    Status HealthStatus           `json:"status"`
+    Checks map[string]HealthCheck `json:"checks"`
+}
+
+// CircuitState describes a circuit breaker. RetryAt is when an open breaker
+// lets the next request through.
+type CircuitState struct {
+    State               string     `json:"state" enums:"closed,open,half-open"`
+    ConsecutiveFailures int        `json:"consecutive_failures"`
+    OpenedAt            *time.Time `json:"opened_at,omitempty"`
+    RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// CacheStats counts lookups in a cache since the server started.
+type CacheStats struct {
+    Hits         int64 `json:"hits"`
+    NegativeHits int64 `json:"negative_hits"`
+    Misses       int64 `json:"misses"`
+    Entries      int   `json:"entries"`
+    Capacity     int   `json:"capacity"`
+}
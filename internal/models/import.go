@@ -0,0 +1,47 @@
+package models
+
+// Formats accepted by the bulk song import.
+const (
+    ImportFormatCSV    = "csv"
+    ImportFormatJSON   = "json"
+    ImportFormatNDJSON = "ndjson"
+)
+
+type ImportStatus string
+
+const (
+    ImportCreated   ImportStatus = "created"
+    ImportDuplicate ImportStatus = "duplicate"
+    ImportFailed    ImportStatus = "failed"
+)
+
+// ImportRow is one song in an import file. Unlike Song, the release date is
+// kept as text so that a bad date fails only its own row.
+type ImportRow struct {
+    GroupName   string `json:"group"`
+    SongName    string `json:"song"`
+    ReleaseDate string `json:"releaseDate"`
+    Text        string `json:"text"`
+    Link        string `json:"link"`
+    Language    string `json:"language"`
+}
+
+// ImportResult reports what happened to one row. Row numbers start at 1 and
+// count data rows, not the CSV header.
+type ImportResult struct {
+    Row    int          `json:"row"`
+    Status ImportStatus `json:"status"`
+    SongID int          `json:"song_id,omitempty"`
+    Group  string       `json:"group,omitempty"`
+    Song   string       `json:"song,omitempty"`
+    Errors []string     `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+    DryRun     bool           `json:"dry_run"`
+    Total      int            `json:"total"`
+    Created    int            `json:"created"`
+    Duplicates int            `json:"duplicates"`
+    Failed     int            `json:"failed"`
+    Results    []ImportResult `json:"results"`
+}
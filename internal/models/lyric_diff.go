@@ -0,0 +1,67 @@
+package models
+
+// Sources of the texts a lyric diff compares.
+const (
+    DiffSideRevision  = "revision"
+    DiffSideCurrent   = "current"
+    DiffSideCandidate = "candidate"
+)
+
+// Kinds of line in a lyric diff hunk.
+const (
+    DiffLineContext = "context"
+    DiffLineAdded   = "added"
+    DiffLineRemoved = "removed"
+)
+
+type LyricDiffQuery struct {
+    From    string `form:"from,default=current"`
+    To      string `form:"to" binding:"required"`
+    Context int    `form:"context,default=3"`
+    Unified bool   `form:"unified"`
+}
+
+// LyricDiffSide names one of the two texts compared: a revision, the
+// stored text or a candidate from the song info providers.
+type LyricDiffSide struct {
+    Source   string `json:"source" enums:"revision,current,candidate" example:"revision"`
+    Revision int    `json:"revision,omitempty"`
+    // Provider names the song info provider a candidate's text came from,
+    // when several are merged.
+    Provider string `json:"provider,omitempty" example:"music_api"`
+}
+
+// DiffLine is one line of a hunk. Line numbers count from 1 over the whole
+// text and verse numbers count the blank-line separated verses; both are
+// left out for the side the line is not on. Blank lines between verses
+// belong to no verse.
+type DiffLine struct {
+    Kind     string `json:"kind" enums:"context,added,removed"`
+    Text     string `json:"text"`
+    OldLine  int    `json:"old_line,omitempty"`
+    NewLine  int    `json:"new_line,omitempty"`
+    OldVerse int    `json:"old_verse,omitempty"`
+    NewVerse int    `json:"new_verse,omitempty"`
+}
+
+// DiffHunk is a run of changed lines with the context around them, as in a
+// unified diff. Verse is the verse of the first changed line, counted on the
+// new side unless the line was removed.
+type DiffHunk struct {
+    OldStart int        `json:"old_start"`
+    OldLines int        `json:"old_lines"`
+    NewStart int        `json:"new_start"`
+    NewLines int        `json:"new_lines"`
+    Verse    int        `json:"verse,omitempty"`
+    Lines    []DiffLine `json:"lines"`
+}
+
+type LyricDiff struct {
+    SongID  int           `json:"song_id"`
+    From    LyricDiffSide `json:"from"`
+    To      LyricDiffSide `json:"to"`
+    Added   int           `json:"added"`
+    Removed int           `json:"removed"`
+    Hunks   []DiffHunk    `json:"hunks"`
+    Unified string        `json:"unified,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+    "time"
+)
+
+type Playlist struct {
+    ID          int       `json:"id"`
+    Name        string    `json:"name" binding:"required"`
+    Description string    `json:"description"`
+    TrackCount  int       `json:"track_count"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PlaylistEntry is one song on a playlist. Position is 1-based.
+type PlaylistEntry struct {
+    ID       int       `json:"entry_id"`
+    Position int       `json:"position"`
+    Song     Song      `json:"song"`
+    AddedAt  time.Time `json:"added_at"`
+}
+
+type PlaylistWithEntries struct {
+    Playlist
+    Entries  []PlaylistEntry `json:"entries"`
+    PageInfo PageInfo        `json:"page_info"`
+}
+
+// PlaylistAdd adds a song at Position, or at the end when Position is 0.
+type PlaylistAdd struct {
+    SongID   int `json:"song_id" binding:"required"`
+    Position int `json:"position"`
+}
+
+// PlaylistMove moves the entry at From to To, shifting the entries between.
+type PlaylistMove struct {
+    From int `json:"from" binding:"required"`
+    To   int `json:"to" binding:"required"`
+}
+
+type PlaylistFilter struct {
+    Page     int `form:"page,default=1"`
+    PageSize int `form:"page_size,default=10"`
+}
+
+type PlaylistList struct {
+    Items    []Playlist `json:"items"`
+    Total    int        `json:"total"`
+    PageInfo PageInfo   `json:"page_info"`
+}
+
+type PlaylistPagination struct {
+    Page     int `form:"page,default=1"`
+    PageSize int `form:"page_size,default=50"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Song fields as named in the song JSON.
+const (
+    FieldGroup       = "group"
+    FieldSong        = "song"
+    FieldReleaseDate = "releaseDate"
+    FieldText        = "text"
+    FieldLink        = "link"
+    FieldLanguage    = "language"
+)
+
+// Provenance sources other than the names of song info providers.
+const (
+    SourceUser   = "user"
+    SourceImport = "import"
+)
+
+// EnrichableFields are the fields song info providers supply, and the only
+// ones that can be locked.
+var EnrichableFields = []string{FieldReleaseDate, FieldText, FieldLink}
+
+// FieldProvenance records where the current value of a song field came from
+// and whether enrichment may overwrite it.
+type FieldProvenance struct {
+    Source    string    `json:"source,omitempty" example:"music_api"`
+    Locked    bool      `json:"locked"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SongProvenance lists the provenance of a song's fields, keyed by field
+// name. Fields without a recorded source or lock are left out.
+type SongProvenance struct {
+    SongID int                        `json:"song_id"`
+    Fields map[string]FieldProvenance `json:"fields"`
+}
+
+// IsEnrichableField reports whether field is one of EnrichableFields.
+func IsEnrichableField(field string) bool {
+    for _, f := range EnrichableFields {
+        if f == field {
+            return true
+        }
+    }
+    return false
+}
+
+// CreatedFields lists the fields a new song was given a value for.
+func (s *Song) CreatedFields() []string {
+    return changedFields(DiffSongs(nil, s))
+}
+
+// ChangedFields lists the fields whose values differ between old and s.
+// Both songs must have gone through artist lookup, so that equal groups
+// have equal names.
+func (s *Song) ChangedFields(old *Song) []string {
+    return changedFields(DiffSongs(old, s))
+}
+
+// Fields lists the fields the patch sets.
+func (p *SongPatch) Fields() []string {
+    var fields []string
+    if p.GroupName != nil {
+        fields = append(fields, FieldGroup)
+    }
+    if p.SongName != nil {
+        fields = append(fields, FieldSong)
+    }
+    if p.ReleaseDate != nil {
+        fields = append(fields, FieldReleaseDate)
+    }
+    if p.Text != nil {
+        fields = append(fields, FieldText)
+    }
+    if p.Link != nil {
+        fields = append(fields, FieldLink)
+    }
+    if p.Language != nil {
+        fields = append(fields, FieldLanguage)
+    }
+    return fields
+}
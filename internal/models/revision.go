@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+type RevisionAction string
+
+const (
+    RevisionCreate  RevisionAction = "create"
+    RevisionUpdate  RevisionAction = "update"
+    RevisionDelete  RevisionAction = "delete"
+    RevisionRestore RevisionAction = "restore"
+    RevisionPurge   RevisionAction = "purge"
+)
+
+const (
+    // ActorAnonymous is recorded for requests that do not name their actor.
+    ActorAnonymous = "anonymous"
+    // ActorEnrichment is recorded for changes made by the enrichment workers.
+    ActorEnrichment = "enrichment"
+    // ActorPurge is recorded when songs are removed from the trash for good.
+    ActorPurge = "purge"
+)
+
+// FieldChange is one field of a song changed by a revision. Values are
+// formatted as in the song JSON.
+type FieldChange struct {
+    Field string `json:"field" example:"text"`
+    Old   string `json:"old"`
+    New   string `json:"new"`
+}
+
+// SongRevision records one change to a song. Song holds the song as the
+// revision left it; for a delete it holds the deleted song.
+type SongRevision struct {
+    SongID       int            `json:"song_id"`
+    Revision     int            `json:"revision"`
+    Action       RevisionAction `json:"action" swaggertype:"string" enums:"create,update,delete,restore,purge"`
+    Actor        string         `json:"actor" example:"alice"`
+    Version      int            `json:"version"`
+    RestoredFrom int            `json:"restored_from,omitempty"`
+    Fields       []string       `json:"fields"`
+    Changes      []FieldChange  `json:"changes,omitempty"`
+    Song         *Song          `json:"song,omitempty"`
+    CreatedAt    time.Time      `json:"created_at"`
+}
+
+type RevisionFilter struct {
+    Page     int `form:"page,default=1"`
+    PageSize int `form:"page_size,default=10"`
+}
+
+// SongRevisionList is a page of revisions, newest first. Items leave out
+// Changes and Song.
+type SongRevisionList struct {
+    Items    []SongRevision `json:"items"`
+    Total    int            `json:"total"`
+    PageInfo PageInfo       `json:"page_info"`
+}
+
+// NewSongRevision describes the change from old to song. old is nil when the
+// song is created and song is nil when it is deleted.
+func NewSongRevision(action RevisionAction, actor string, old, song *Song) *SongRevision {
+    snapshot := song
+    if snapshot == nil {
+        snapshot = old
+    }
+    copied := *snapshot
+
+    changes := DiffSongs(old, song)
+    return &SongRevision{
+        SongID:  snapshot.ID,
+        Action:  action,
+        Actor:   actor,
+        Version: snapshot.Version,
+        Fields:  changedFields(changes),
+        Changes: changes,
+        Song:    &copied,
+    }
+}
+
+// DiffSongs lists the fields whose values differ between old and song. A
+// nil song counts as one with every field empty.
+func DiffSongs(old, song *Song) []FieldChange {
+    if old == nil {
+        old = &Song{}
+    }
+    if song == nil {
+        song = &Song{}
+    }
+
+    var changes []FieldChange
+    add := func(field, oldValue, newValue string) {
+        if oldValue != newValue {
+            changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+        }
+    }
+    add(FieldGroup, old.GroupName, song.GroupName)
+    add(FieldSong, old.SongName, song.SongName)
+    add(FieldReleaseDate, old.ReleaseDate.String(), song.ReleaseDate.String())
+    add(FieldText, old.Text, song.Text)
+    add(FieldLink, old.Link, song.Link)
+    add(FieldLanguage, old.Language, song.Language)
+    return changes
+}
+
+func changedFields(changes []FieldChange) []string {
+    fields := make([]string, len(changes))
+    for i, change := range changes {
+        fields[i] = change.Field
+    }
+    return fields
+}
+
+// CopyContent copies the fields a user can edit from other onto s.
+func (s *Song) CopyContent(other *Song) {
+    s.GroupName = other.GroupName
+    s.SongName = other.SongName
+    s.ReleaseDate = other.ReleaseDate
+    s.Text = other.Text
+    s.Link = other.Link
+    s.Language = other.Language
+}
@@ -0,0 +1,71 @@
+package models
+
+// DefaultSearchLanguage is the text search configuration used when a song
+// or query does not name one. It does no stemming.
+const DefaultSearchLanguage = "simple"
+
+// SearchLanguages lists the Postgres text search configurations songs and
+// queries may use.
+var SearchLanguages = map[string]bool{
+    "simple":     true,
+    "danish":     true,
+    "dutch":      true,
+    "english":    true,
+    "finnish":    true,
+    "french":     true,
+    "german":     true,
+    "hungarian":  true,
+    "italian":    true,
+    "norwegian":  true,
+    "portuguese": true,
+    "romanian":   true,
+    "russian":    true,
+    "spanish":    true,
+    "swedish":    true,
+    "turkish":    true,
+}
+
+// SearchQuery is a lyric search. An empty Language parses the query with
+// the language of each song.
+type SearchQuery struct {
+    Query    string `form:"q" binding:"required"`
+    Language string `form:"lang"`
+    Page     int    `form:"page,default=1"`
+    PageSize int    `form:"page_size,default=10"`
+}
+
+// SearchHit is a song matching a lyric search. Headline is the song text,
+// HTML-escaped, with matches wrapped in <mark> tags; Snippets holds just the
+// verses that contain a match.
+type SearchHit struct {
+    Song
+    Rank     float64  `json:"rank"`
+    Headline string   `json:"-"`
+    Snippets []string `json:"snippets"`
+}
+
+type SearchResults struct {
+    Items    []SearchHit `json:"items"`
+    Total    int         `json:"total"`
+    PageInfo PageInfo    `json:"page_info"`
+}
+
+// FuzzyThreshold is the minimum pg_trgm similarity for a fuzzy group or song
+// name match. It is lower than the pg_trgm default of 0.3 so that a single
+// typo in a short name ("Muze") still matches ("Muse").
+const FuzzyThreshold = 0.2
+
+type SuggestQuery struct {
+    Prefix string `form:"prefix" binding:"required"`
+    Limit  int    `form:"limit,default=10"`
+}
+
+// Suggestion is an autocomplete entry: a group name, or a song name with
+// its group and id.
+type Suggestion struct {
+    Kind   string  `json:"kind" enums:"group,song"`
+    Text   string  `json:"text"`
+    Group  string  `json:"group,omitempty"`
+    SongID int     `json:"song_id,omitempty"`
+    Score  float64 `json:"score"`
+}
@@ -6,27 +6,138 @@ import (
 
 type Song struct {
     ID          int       `json:"id"`
+    ArtistID    int       `json:"artist_id"`
     GroupName   string    `json:"group" binding:"required"`
     SongName    string    `json:"song" binding:"required"`
-    ReleaseDate string    `json:"releaseDate"`
+    ReleaseDate Date      `json:"releaseDate" swaggertype:"string" format:"date" example:"2006-07-16"`
     Text        string    `json:"text"`
     Link        string    `json:"link"`
+    Language    string    `json:"language" example:"english"`
+    Version     int       `json:"version"`
     CreatedAt   time.Time `json:"created_at"`
     UpdatedAt   time.Time `json:"updated_at"`
+
+    // EnrichmentStatus is maintained by the server and ignored on input.
+    EnrichmentStatus EnrichmentStatus `json:"enrichment_status" swaggertype:"string" enums:"pending,enriched,failed"`
+    // DeletedAt is set while the song is in the trash.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+    // RefreshInfo makes the enrichment of a new song bypass the song info
+    // cache.
+    RefreshInfo bool `json:"-"`
+}
+
+// SongPatch holds the song fields sent in a PATCH request. Nil fields are
+// left unchanged.
+type SongPatch struct {
+    GroupName   *string
+    SongName    *string
+    ReleaseDate *Date
+    Text        *string
+    Link        *string
+    Language    *string
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p *SongPatch) IsEmpty() bool {
+    return p.GroupName == nil && p.SongName == nil && p.ReleaseDate == nil && p.Text == nil && p.Link == nil &&
+        p.Language == nil
+}
+
+// ApplyTo copies the set fields of the patch onto song.
+func (p *SongPatch) ApplyTo(song *Song) {
+    if p.GroupName != nil {
+        song.GroupName = *p.GroupName
+    }
+    if p.SongName != nil {
+        song.SongName = *p.SongName
+    }
+    if p.ReleaseDate != nil {
+        song.ReleaseDate = *p.ReleaseDate
+    }
+    if p.Text != nil {
+        song.Text = *p.Text
+    }
+    if p.Link != nil {
+        song.Link = *p.Link
+    }
+    if p.Language != nil {
+        song.Language = *p.Language
+    }
 }
 
 type SongFilter struct {
-    GroupName   string `form:"group"`
-    SongName    string `form:"song"`
-    ReleaseDate string `form:"release_date"`
-    Page        int    `form:"page,default=1"`
-    PageSize    int    `form:"page_size,default=10"`
+    GroupName      string `form:"group"`
+    SongName       string `form:"song"`
+    ReleaseDate    string `form:"release_date"`
+    ReleasedAfter  string `form:"released_after"`
+    ReleasedBefore string `form:"released_before"`
+    Year           int    `form:"year"`
+    Page           int    `form:"page,default=1"`
+    PageSize       int    `form:"page_size,default=10"`
+    Cursor         string `form:"cursor"`
+    Sort           string `form:"sort"`
+    Fuzzy          bool   `form:"fuzzy"`
+    ArtistID       int    `form:"artist_id"`
+    AlbumID        int    `form:"album_id"`
+    IncludeDeleted bool   `form:"include_deleted"`
+
+    // ReleasedFrom and ReleasedUntil are the release date filters resolved
+    // into one half-open range [ReleasedFrom, ReleasedUntil). Zero means
+    // unbounded.
+    ReleasedFrom  Date `form:"-" json:"-"`
+    ReleasedUntil Date `form:"-" json:"-"`
+
+    // SortKeys is the parsed Sort; songs are always ordered by id last.
+    SortKeys []SortKey `form:"-" json:"-"`
+    // Keyset is the decoded Cursor. When set, Page is ignored.
+    Keyset *SongCursor `form:"-" json:"-"`
+    // Limit overrides PageSize as the number of rows to fetch, without
+    // moving the page offset.
+    Limit int `form:"-" json:"-"`
+}
+
+// RowLimit is the number of rows a List call should return.
+func (f *SongFilter) RowLimit() int {
+    if f.Limit > 0 {
+        return f.Limit
+    }
+    return f.PageSize
+}
+
+// SongCursor marks a position in the song list for keyset pagination: the
+// sort key values and id of the boundary row. A nil key is NULL.
+type SongCursor struct {
+    ID     int       `json:"id"`
+    Sort   string    `json:"sort,omitempty"`
+    Keys   []*string `json:"keys,omitempty"`
+    Before bool      `json:"before,omitempty"`
+}
+
+// SongList is one page of songs together with the information needed to
+// fetch its neighbours.
+type SongList struct {
+    Items      []Song   `json:"items"`
+    Total      int      `json:"total"`
+    PageInfo   PageInfo `json:"page_info"`
+    NextCursor string   `json:"next_cursor,omitempty"`
+    PrevCursor string   `json:"prev_cursor,omitempty"`
+}
+
+type PageInfo struct {
+    Page       int  `json:"page,omitempty"`
+    PageSize   int  `json:"page_size"`
+    TotalPages int  `json:"total_pages"`
+    HasNext    bool `json:"has_next"`
+    HasPrev    bool `json:"has_prev"`
 }
 
 type SongDetail struct {
-    ReleaseDate string `json:"releaseDate"`
+    ReleaseDate Date   `json:"releaseDate" swaggertype:"string" format:"date"`
     Text        string `json:"text"`
     Link        string `json:"link"`
+    // Sources names the provider that supplied each field, keyed by the
+    // field's JSON name. It is filled in when several providers are merged.
+    Sources map[string]string `json:"-"`
 }
 
 type VersePagination struct {
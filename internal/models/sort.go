@@ -0,0 +1,92 @@
+package models
+
+import (
+    "strings"
+    "time"
+)
+
+// songSortColumns whitelists the columns songs can be ordered by, keyed by
+// the names accepted in the sort query parameter.
+var songSortColumns = map[string]string{
+    "group":        "group_name",
+    "group_name":   "group_name",
+    "song":         "song_name",
+    "song_name":    "song_name",
+    "release_date": "release_date",
+    "created_at":   "created_at",
+    "updated_at":   "updated_at",
+}
+
+// SortKey orders songs by a whitelisted column.
+type SortKey struct {
+    Column string
+    Desc   bool
+}
+
+// ParseSongSort parses a sort parameter such as "-release_date,group".
+// A leading "-" sorts descending. Unknown columns are rejected.
+func ParseSongSort(spec string) ([]SortKey, error) {
+    if strings.TrimSpace(spec) == "" {
+        return nil, nil
+    }
+
+    var keys []SortKey
+    seen := make(map[string]bool)
+    for _, part := range strings.Split(spec, ",") {
+        part = strings.TrimSpace(part)
+        desc := strings.HasPrefix(part, "-")
+        name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+        column, ok := songSortColumns[name]
+        if !ok {
+            return nil, &ValidationError{Field: "sort", Message: "cannot sort by " + strings.TrimSpace(name)}
+        }
+        if seen[column] {
+            continue
+        }
+        seen[column] = true
+        keys = append(keys, SortKey{Column: column, Desc: desc})
+    }
+
+    return keys, nil
+}
+
+// FormatSongSort renders keys in the canonical form of the sort parameter.
+func FormatSongSort(keys []SortKey) string {
+    parts := make([]string, len(keys))
+    for i, key := range keys {
+        parts[i] = key.Column
+        if key.Desc {
+            parts[i] = "-" + key.Column
+        }
+    }
+    return strings.Join(parts, ",")
+}
+
+// Nullable reports whether the key's column can be NULL. NULLs sort after
+// every other value in both directions.
+func (k SortKey) Nullable() bool {
+    return k.Column == "release_date"
+}
+
+// Value returns the song's value for the key's column, as used in cursors,
+// or nil for NULL.
+func (k SortKey) Value(song *Song) *string {
+    var value string
+    switch k.Column {
+    case "group_name":
+        value = song.GroupName
+    case "song_name":
+        value = song.SongName
+    case "release_date":
+        if song.ReleaseDate.IsZero() {
+            return nil
+        }
+        value = song.ReleaseDate.String()
+    case "created_at":
+        value = song.CreatedAt.Format(time.RFC3339Nano)
+    case "updated_at":
+        value = song.UpdatedAt.Format(time.RFC3339Nano)
+    }
+    return &value
+}
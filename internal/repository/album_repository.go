@@ -0,0 +1,283 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+    "strings"
+)
+
+const albumColumns = `al.id, al.artist_id, ar.name, al.title, al.release_date, COALESCE(al.cover_link, ''),
+    (SELECT COUNT(*) FROM album_tracks t JOIN songs ts ON ts.id = t.song_id
+        WHERE t.album_id = al.id AND ts.deleted_at IS NULL) AS track_count,
+    al.created_at, al.updated_at`
+
+const albumFrom = "albums al JOIN artists ar ON ar.id = al.artist_id"
+
+func scanAlbum(row rowScanner, album *models.Album) error {
+    return row.Scan(
+        &album.ID,
+        &album.ArtistID,
+        &album.Artist,
+        &album.Title,
+        &album.ReleaseDate,
+        &album.CoverLink,
+        &album.TrackCount,
+        &album.CreatedAt,
+        &album.UpdatedAt,
+    )
+}
+
+type AlbumRepository struct {
+    db *sql.DB
+}
+
+func NewAlbumRepository(db *sql.DB) *AlbumRepository {
+    return &AlbumRepository{db: db}
+}
+
+// Create stores album under the artist named album.Artist, creating the
+// artist if needed, together with its initial track listing album.SongIDs.
+func (r *AlbumRepository) Create(album *models.Album) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        var err error
+        album.ArtistID, album.Artist, err = upsertArtist(tx, models.NormalizeArtistName(album.Artist))
+        if err != nil {
+            return err
+        }
+
+        err = tx.QueryRow(`
+            INSERT INTO albums (artist_id, title, release_date, cover_link)
+            VALUES ($1, $2, $3, NULLIF($4, ''))
+            RETURNING id, created_at, updated_at`,
+            album.ArtistID, album.Title, album.ReleaseDate, album.CoverLink,
+        ).Scan(&album.ID, &album.CreatedAt, &album.UpdatedAt)
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := checkTrackSongs(tx, album.SongIDs); err != nil {
+            return err
+        }
+        if err := insertTracks(tx, album.ID, album.SongIDs); err != nil {
+            return err
+        }
+        album.TrackCount = len(album.SongIDs)
+        return nil
+    })
+}
+
+func (r *AlbumRepository) Delete(id int) error {
+    result, err := r.db.Exec("DELETE FROM albums WHERE id = $1", id)
+    if err != nil {
+        return translateError(err)
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return albumNotFound(id)
+    }
+    return nil
+}
+
+func (r *AlbumRepository) GetByID(id int) (*models.Album, error) {
+    album := &models.Album{}
+    err := scanAlbum(r.db.QueryRow(`
+        SELECT `+albumColumns+`
+        FROM `+albumFrom+`
+        WHERE al.id = $1`, id), album)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, albumNotFound(id)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return album, nil
+}
+
+// Tracks returns the album's songs in track order. Songs in the trash are
+// left out and the positions of the others close up.
+func (r *AlbumRepository) Tracks(id int) ([]models.AlbumTrack, error) {
+    rows, err := r.db.Query(`
+        SELECT `+songColumns+`, ROW_NUMBER() OVER (ORDER BY t.position)
+        FROM album_tracks t
+        JOIN `+songFrom+` ON s.id = t.song_id
+        WHERE t.album_id = $1 AND s.deleted_at IS NULL
+        ORDER BY t.position`, id)
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var tracks []models.AlbumTrack
+    for rows.Next() {
+        var track models.AlbumTrack
+        if err := scanSong(rows, &track.Song, &track.Position); err != nil {
+            return nil, err
+        }
+        tracks = append(tracks, track)
+    }
+    return tracks, rows.Err()
+}
+
+// SetTracks replaces the album's track listing with songIDs, in order.
+// Tracks whose song is in the trash keep their place.
+func (r *AlbumRepository) SetTracks(id int, songIDs []int) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        // Lock the album so concurrent reorders apply one after the other.
+        err := tx.QueryRow("SELECT id FROM albums WHERE id = $1 FOR UPDATE", id).Scan(&id)
+        if errors.Is(err, sql.ErrNoRows) {
+            return albumNotFound(id)
+        }
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := checkTrackSongs(tx, songIDs); err != nil {
+            return err
+        }
+        current, hidden, err := albumTrackSongs(tx, id)
+        if err != nil {
+            return err
+        }
+
+        if _, err := tx.Exec("DELETE FROM album_tracks WHERE album_id = $1", id); err != nil {
+            return translateError(err)
+        }
+        if err := insertTracks(tx, id, mergeHidden(current, hidden, songIDs)); err != nil {
+            return err
+        }
+
+        _, err = tx.Exec("UPDATE albums SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+        return translateError(err)
+    })
+}
+
+// albumTrackSongs returns the song ids of the album's tracks in order,
+// together with the set of those in the trash.
+func albumTrackSongs(q querier, albumID int) ([]int, map[int]bool, error) {
+    rows, err := q.Query(`
+        SELECT t.song_id, s.deleted_at IS NOT NULL
+        FROM album_tracks t
+        JOIN songs s ON s.id = t.song_id
+        WHERE t.album_id = $1
+        ORDER BY t.position`, albumID)
+    if err != nil {
+        return nil, nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var songIDs []int
+    hidden := make(map[int]bool)
+    for rows.Next() {
+        var songID int
+        var trashed bool
+        if err := rows.Scan(&songID, &trashed); err != nil {
+            return nil, nil, err
+        }
+        songIDs = append(songIDs, songID)
+        hidden[songID] = trashed
+    }
+    return songIDs, hidden, rows.Err()
+}
+
+// checkTrackSongs reports unknown songs, and songs in the trash, as a
+// validation error rather than letting the foreign key fail.
+func checkTrackSongs(q querier, songIDs []int) error {
+    if len(songIDs) == 0 {
+        return nil
+    }
+
+    var missing []string
+    err := q.QueryRow(`
+        SELECT COALESCE(array_agg(wanted.id ORDER BY wanted.id), '{}')
+        FROM unnest($1::int[]) AS wanted (id)
+        WHERE NOT EXISTS (SELECT 1 FROM songs WHERE songs.id = wanted.id AND songs.deleted_at IS NULL)`,
+        pq.Array(songIDs),
+    ).Scan(pq.Array(&missing))
+    if err != nil {
+        return translateError(err)
+    }
+    if len(missing) > 0 {
+        return &models.ValidationError{Field: "song_ids", Message: "contains unknown songs " + strings.Join(missing, ", ")}
+    }
+    return nil
+}
+
+// insertTracks adds songIDs to the album, numbering them from 1.
+func insertTracks(q querier, albumID int, songIDs []int) error {
+    if len(songIDs) == 0 {
+        return nil
+    }
+
+    _, err := q.Exec(`
+        INSERT INTO album_tracks (album_id, song_id, position)
+        SELECT $1, track.song_id, track.position
+        FROM unnest($2::int[]) WITH ORDINALITY AS track (song_id, position)`,
+        albumID, pq.Array(songIDs))
+    return translateError(err)
+}
+
+func (r *AlbumRepository) List(filter *models.AlbumFilter) ([]models.Album, error) {
+    where, args := albumFilterWhere(filter)
+    args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+
+    rows, err := r.db.Query(`
+        SELECT `+albumColumns+`
+        FROM `+albumFrom+`
+        `+where+`
+        ORDER BY al.title, al.id
+        `+fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+        args...)
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var albums []models.Album
+    for rows.Next() {
+        var album models.Album
+        if err := scanAlbum(rows, &album); err != nil {
+            return nil, err
+        }
+        albums = append(albums, album)
+    }
+    return albums, rows.Err()
+}
+
+func (r *AlbumRepository) Count(filter *models.AlbumFilter) (int, error) {
+    where, args := albumFilterWhere(filter)
+
+    var total int
+    err := r.db.QueryRow("SELECT COUNT(*) FROM "+albumFrom+" "+where, args...).Scan(&total)
+    if err != nil {
+        return 0, translateError(err)
+    }
+    return total, nil
+}
+
+func albumFilterWhere(filter *models.AlbumFilter) (string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+    arg := func(value interface{}) string {
+        args = append(args, value)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    if filter.ArtistID != 0 {
+        conditions = append(conditions, "al.artist_id = "+arg(filter.ArtistID))
+    }
+    if filter.Title != "" {
+        conditions = append(conditions, "al.title ILIKE '%' || "+arg(escapeLike(filter.Title))+" || '%'")
+    }
+
+    if len(conditions) == 0 {
+        return "WHERE TRUE", args
+    }
+    return "WHERE " + strings.Join(conditions, "\n        AND "), args
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "music-library/internal/models"
+
+// AlbumStore is the persistence contract for albums and their track
+// listings. Track listings name songs by id in track order; unknown song ids
+// return models.ErrValidation.
+type AlbumStore interface {
+    Create(album *models.Album) error
+    Delete(id int) error
+    GetByID(id int) (*models.Album, error)
+    Tracks(id int) ([]models.AlbumTrack, error)
+    SetTracks(id int, songIDs []int) error
+    List(filter *models.AlbumFilter) ([]models.Album, error)
+    Count(filter *models.AlbumFilter) (int, error)
+}
+
+var (
+    _ AlbumStore = (*AlbumRepository)(nil)
+    _ AlbumStore = (*MemoryAlbumRepository)(nil)
+)
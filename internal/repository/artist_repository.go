@@ -0,0 +1,122 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "music-library/internal/models"
+)
+
+const artistColumns = `a.id, a.name, a.created_at, a.updated_at,
+    (SELECT COUNT(*) FROM songs WHERE songs.artist_id = a.id AND songs.deleted_at IS NULL) AS song_count`
+
+func scanArtist(row rowScanner, artist *models.Artist) error {
+    return row.Scan(&artist.ID, &artist.Name, &artist.CreatedAt, &artist.UpdatedAt, &artist.SongCount)
+}
+
+type ArtistRepository struct {
+    db *sql.DB
+}
+
+func NewArtistRepository(db *sql.DB) *ArtistRepository {
+    return &ArtistRepository{db: db}
+}
+
+func (r *ArtistRepository) Create(artist *models.Artist) error {
+    artist.Name = models.NormalizeArtistName(artist.Name)
+    err := r.db.QueryRow(`
+        INSERT INTO artists (name)
+        VALUES ($1)
+        RETURNING id, created_at, updated_at`,
+        artist.Name,
+    ).Scan(&artist.ID, &artist.CreatedAt, &artist.UpdatedAt)
+    return translateError(err)
+}
+
+// Update renames an artist. Every song of the artist shows the new name as
+// its group.
+func (r *ArtistRepository) Update(artist *models.Artist) error {
+    artist.Name = models.NormalizeArtistName(artist.Name)
+    err := r.db.QueryRow(`
+        UPDATE artists a
+        SET name = $1, updated_at = CURRENT_TIMESTAMP
+        WHERE a.id = $2
+        RETURNING `+artistColumns,
+        artist.Name, artist.ID,
+    ).Scan(&artist.ID, &artist.Name, &artist.CreatedAt, &artist.UpdatedAt, &artist.SongCount)
+    if errors.Is(err, sql.ErrNoRows) {
+        return artistNotFound(artist.ID)
+    }
+    return translateError(err)
+}
+
+func (r *ArtistRepository) Delete(id int) error {
+    result, err := r.db.Exec("DELETE FROM artists WHERE id = $1", id)
+    if err != nil {
+        return translateError(err)
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return artistNotFound(id)
+    }
+    return nil
+}
+
+func (r *ArtistRepository) GetByID(id int) (*models.Artist, error) {
+    artist := &models.Artist{}
+    err := scanArtist(r.db.QueryRow(`
+        SELECT `+artistColumns+`
+        FROM artists a
+        WHERE a.id = $1`, id), artist)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, artistNotFound(id)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return artist, nil
+}
+
+// List returns one page of artists whose name contains filter.Name, ordered
+// by name.
+func (r *ArtistRepository) List(filter *models.ArtistFilter) ([]models.Artist, error) {
+    rows, err := r.db.Query(`
+        SELECT `+artistColumns+`
+        FROM artists a
+        WHERE ($1 = '' OR a.name ILIKE '%' || $1 || '%')
+        ORDER BY a.name, a.id
+        LIMIT $2 OFFSET $3`,
+        escapeLike(filter.Name), filter.PageSize, (filter.Page-1)*filter.PageSize,
+    )
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var artists []models.Artist
+    for rows.Next() {
+        var artist models.Artist
+        if err := scanArtist(rows, &artist); err != nil {
+            return nil, err
+        }
+        artists = append(artists, artist)
+    }
+    return artists, rows.Err()
+}
+
+func (r *ArtistRepository) Count(filter *models.ArtistFilter) (int, error) {
+    var total int
+    err := r.db.QueryRow(`
+        SELECT COUNT(*)
+        FROM artists a
+        WHERE ($1 = '' OR a.name ILIKE '%' || $1 || '%')`,
+        escapeLike(filter.Name),
+    ).Scan(&total)
+    if err != nil {
+        return 0, translateError(err)
+    }
+    return total, nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import "music-library/internal/models"
+
+// ArtistStore is the persistence contract for artists. Artist names are
+// unique ignoring case and extra whitespace; a clash returns
+// models.ErrConflict, as does deleting an artist that still has songs.
+type ArtistStore interface {
+    Create(artist *models.Artist) error
+    Update(artist *models.Artist) error
+    Delete(id int) error
+    GetByID(id int) (*models.Artist, error)
+    List(filter *models.ArtistFilter) ([]models.Artist, error)
+    Count(filter *models.ArtistFilter) (int, error)
+}
+
+var (
+    _ ArtistStore = (*ArtistRepository)(nil)
+    _ ArtistStore = (*MemoryArtistRepository)(nil)
+)
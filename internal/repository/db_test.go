@@ -0,0 +1,78 @@
+package repository_test
+
+import (
+    "database/sql"
+    "errors"
+    "github.com/golang-migrate/migrate/v4"
+    "github.com/golang-migrate/migrate/v4/database/postgres"
+    "github.com/golang-migrate/migrate/v4/source/iofs"
+    "music-library/internal/models"
+    "music-library/migrations"
+    "os"
+    "testing"
+
+    _ "github.com/lib/pq"
+)
+
+// testDatabaseEnv names the variable holding the connection string of a
+// scratch Postgres database. The tests that need one are skipped without it.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// openTestDB migrates the test database and empties it.
+func openTestDB(t *testing.T) *sql.DB {
+    t.Helper()
+    dsn := os.Getenv(testDatabaseEnv)
+    if dsn == "" {
+        t.Skipf("%s is not set", testDatabaseEnv)
+    }
+
+    db, err := sql.Open("postgres", dsn)
+    if err != nil {
+        t.Fatalf("open database: %v", err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    source, err := iofs.New(migrations.FS, ".")
+    if err != nil {
+        t.Fatalf("open migrations: %v", err)
+    }
+    driver, err := postgres.WithInstance(db, &postgres.Config{})
+    if err != nil {
+        t.Fatalf("create migration driver: %v", err)
+    }
+    m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+    if err != nil {
+        t.Fatalf("create migration instance: %v", err)
+    }
+    if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+        t.Fatalf("migrate: %v", err)
+    }
+
+    _, err = db.Exec(`
+        TRUNCATE songs, artists, albums, album_tracks, playlists, playlist_entries,
+            enrichment_jobs, song_info_cache, song_field_provenance, song_revisions
+        RESTART IDENTITY CASCADE`)
+    if err != nil {
+        t.Fatalf("empty database: %v", err)
+    }
+    return db
+}
+
+// countRows returns the number of rows in table.
+func countRows(t *testing.T, db *sql.DB, table string) int {
+    t.Helper()
+    var n int
+    if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+        t.Fatalf("count %s: %v", table, err)
+    }
+    return n
+}
+
+func newTestSong(group, name string) models.Song {
+    return models.Song{
+        GroupName: group,
+        SongName:  name,
+        Text:      "They will not force us",
+        Language:  "english",
+    }
+}
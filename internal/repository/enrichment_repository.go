@@ -0,0 +1,260 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "music-library/internal/models"
+    "strings"
+    "time"
+)
+
+type EnrichmentRepository struct {
+    db *sql.DB
+}
+
+func NewEnrichmentRepository(db *sql.DB) *EnrichmentRepository {
+    return &EnrichmentRepository{db: db}
+}
+
+// Enqueue and Fail only change the enrichment status, so they leave the
+// song's version alone: versions, and the revisions recording them, follow
+// changes to its fields.
+func (r *EnrichmentRepository) Enqueue(songID int, refresh bool) (*models.Enrichment, error) {
+    var enrichment *models.Enrichment
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        result, err := tx.Exec(`
+            UPDATE songs
+            SET enrichment_status = 'pending', enrichment_error = ''
+            WHERE id = $1 AND deleted_at IS NULL`, songID)
+        if err != nil {
+            return translateError(err)
+        }
+        if n, err := result.RowsAffected(); err != nil {
+            return err
+        } else if n == 0 {
+            return songNotFound(songID)
+        }
+
+        _, err = tx.Exec(`
+            INSERT INTO enrichment_jobs (song_id, refresh) VALUES ($1, $2)
+            ON CONFLICT (song_id) DO UPDATE
+            SET attempts = 0, run_at = CURRENT_TIMESTAMP, locked_until = NULL, last_error = '',
+                refresh = enrichment_jobs.refresh OR EXCLUDED.refresh`, songID, refresh)
+        if err != nil {
+            return translateError(err)
+        }
+
+        enrichment, err = getEnrichment(tx, songID)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return enrichment, nil
+}
+
+// Claim takes due jobs that no other worker holds. SKIP LOCKED lets
+// concurrent workers claim different jobs without waiting on each other.
+// Jobs of songs in the trash wait until the song is restored.
+func (r *EnrichmentRepository) Claim(limit int, lease time.Duration) ([]models.EnrichmentJob, error) {
+    rows, err := r.db.Query(`
+        UPDATE enrichment_jobs j
+        SET attempts = j.attempts + 1, locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+        FROM songs s JOIN artists a ON a.id = s.artist_id
+        WHERE j.id IN (
+                SELECT id FROM enrichment_jobs
+                WHERE run_at <= CURRENT_TIMESTAMP
+                  AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+                  AND song_id IN (SELECT id FROM songs WHERE deleted_at IS NULL)
+                ORDER BY run_at
+                LIMIT $1
+                FOR UPDATE SKIP LOCKED)
+          AND s.id = j.song_id
+        RETURNING j.id, j.song_id, a.name, s.song_name, j.attempts, j.refresh`, limit, lease.Seconds())
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var jobs []models.EnrichmentJob
+    for rows.Next() {
+        var job models.EnrichmentJob
+        if err := rows.Scan(&job.ID, &job.SongID, &job.GroupName, &job.SongName, &job.Attempts, &job.Refresh); err != nil {
+            return nil, err
+        }
+        jobs = append(jobs, job)
+    }
+    return jobs, rows.Err()
+}
+
+// Complete skips the fields keptFields names. Each applied field records the
+// provider that supplied it. Only a change to the song's fields bumps its
+// version, recorded as a revision by models.ActorEnrichment.
+func (r *EnrichmentRepository) Complete(job *models.EnrichmentJob, detail *models.SongDetail) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        old, ok, err := finishJob(tx, job)
+        if err != nil || !ok {
+            return err
+        }
+        provenance, err := getProvenance(tx, job.SongID, false)
+        if err != nil {
+            return err
+        }
+        kept := keptFields(provenance.Fields, old)
+
+        sets := []string{"enrichment_status = 'enriched'", "enrichment_error = ''"}
+        args := []interface{}{job.SongID}
+        var applied []string
+        set := func(field, column string, value interface{}) {
+            args = append(args, value)
+            sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+            applied = append(applied, field)
+        }
+        if !detail.ReleaseDate.IsZero() && !kept[models.FieldReleaseDate] {
+            set(models.FieldReleaseDate, "release_date", detail.ReleaseDate)
+        }
+        if detail.Text != "" && !kept[models.FieldText] {
+            set(models.FieldText, "text", detail.Text)
+        }
+        if detail.Link != "" && !kept[models.FieldLink] {
+            set(models.FieldLink, "link", detail.Link)
+        }
+
+        _, err = tx.Exec("UPDATE songs SET "+strings.Join(sets, ", ")+" WHERE id = $1", args...)
+        if err != nil {
+            return translateError(err)
+        }
+
+        for _, field := range applied {
+            if err := recordProvenance(tx, job.SongID, []string{field}, enrichmentSource(detail, field)); err != nil {
+                return err
+            }
+        }
+
+        song, err := getSong(tx, job.SongID)
+        if err != nil {
+            return err
+        }
+        if len(song.ChangedFields(old)) == 0 {
+            return nil
+        }
+
+        _, err = tx.Exec("UPDATE songs SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1", job.SongID)
+        if err != nil {
+            return translateError(err)
+        }
+        song, err = getSong(tx, job.SongID)
+        if err != nil {
+            return err
+        }
+        return insertRevision(tx, models.NewSongRevision(models.RevisionUpdate, models.ActorEnrichment, old, song))
+    })
+}
+
+func (r *EnrichmentRepository) Retry(job *models.EnrichmentJob, runAt time.Time, cause string, refund bool) error {
+    attempts := job.Attempts
+    if refund {
+        attempts--
+    }
+    _, err := r.db.Exec(`
+        UPDATE enrichment_jobs
+        SET attempts = $3, run_at = $4, locked_until = NULL, last_error = $5
+        WHERE id = $1 AND attempts = $2`, job.ID, job.Attempts, attempts, runAt, cause)
+    return translateError(err)
+}
+
+func (r *EnrichmentRepository) Fail(job *models.EnrichmentJob, cause string) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        if _, ok, err := finishJob(tx, job); err != nil || !ok {
+            return err
+        }
+
+        _, err := tx.Exec(`
+            UPDATE songs
+            SET enrichment_status = 'failed', enrichment_error = $2
+            WHERE id = $1`, job.SongID, cause)
+        return translateError(err)
+    })
+}
+
+// finishJob locks the song of job and removes the job if it still belongs
+// to the caller. If the song went to the trash while the job ran, the job is
+// handed back instead, without counting the attempt, to run once the song is
+// restored. ok reports whether the caller should go on with the song.
+func finishJob(tx *sql.Tx, job *models.EnrichmentJob) (*models.Song, bool, error) {
+    song, err := lockSong(tx, job.SongID)
+    if errors.Is(err, models.ErrNotFound) {
+        // The song was purged and its job with it.
+        return nil, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+
+    if song.DeletedAt != nil {
+        _, err := tx.Exec(`
+            UPDATE enrichment_jobs
+            SET attempts = attempts - 1, locked_until = NULL
+            WHERE id = $1 AND attempts = $2`, job.ID, job.Attempts)
+        return nil, false, translateError(err)
+    }
+    owned, err := deleteJob(tx, job)
+    if err != nil || !owned {
+        return nil, false, err
+    }
+    return song, true, nil
+}
+
+// deleteJob removes job if it still belongs to the caller.
+func deleteJob(tx *sql.Tx, job *models.EnrichmentJob) (bool, error) {
+    result, err := tx.Exec("DELETE FROM enrichment_jobs WHERE id = $1 AND attempts = $2", job.ID, job.Attempts)
+    if err != nil {
+        return false, translateError(err)
+    }
+    n, err := result.RowsAffected()
+    return n > 0, err
+}
+
+func (r *EnrichmentRepository) Get(songID int) (*models.Enrichment, error) {
+    return getEnrichment(r.db, songID)
+}
+
+func getEnrichment(q querier, songID int) (*models.Enrichment, error) {
+    enrichment := &models.Enrichment{SongID: songID}
+    var songError string
+    var attempts sql.NullInt64
+    var jobError sql.NullString
+    var runAt sql.NullTime
+    err := q.QueryRow(`
+        SELECT s.enrichment_status, s.enrichment_error, j.attempts, j.last_error, j.run_at
+        FROM songs s LEFT JOIN enrichment_jobs j ON j.song_id = s.id
+        WHERE s.id = $1 AND s.deleted_at IS NULL`, songID).Scan(&enrichment.Status, &songError, &attempts, &jobError, &runAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, songNotFound(songID)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+
+    provenance, err := getProvenance(q, songID, false)
+    if err != nil {
+        return nil, err
+    }
+    for field, entry := range provenance.Fields {
+        if isProviderSource(entry.Source) {
+            if enrichment.Sources == nil {
+                enrichment.Sources = make(map[string]string)
+            }
+            enrichment.Sources[field] = entry.Source
+        }
+    }
+
+    enrichment.Error = songError
+    if attempts.Valid {
+        enrichment.Attempts = int(attempts.Int64)
+        enrichment.Error = jobError.String
+        enrichment.NextAttemptAt = &runAt.Time
+    }
+    return enrichment, nil
+}
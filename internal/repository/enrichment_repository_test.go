@@ -0,0 +1,143 @@
+package repository_test
+
+import (
+    "fmt"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "sync"
+    "testing"
+    "time"
+)
+
+// enqueueTestSongs imports n songs and queues each for enrichment.
+func enqueueTestSongs(t *testing.T, songs *repository.SongRepository, jobs *repository.EnrichmentRepository, n int) []models.Song {
+    t.Helper()
+    imported := make([]models.Song, n)
+    for i := range imported {
+        imported[i] = newTestSong("Muse", fmt.Sprintf("Song %d", i))
+    }
+    if _, err := songs.Import(imported, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+    for _, song := range imported {
+        if _, err := jobs.Enqueue(song.ID, false); err != nil {
+            t.Fatalf("Enqueue %d: %v", song.ID, err)
+        }
+    }
+    return imported
+}
+
+func TestClaimLeasesEachJobOnce(t *testing.T) {
+    db := openTestDB(t)
+    songs := repository.NewSongRepository(db)
+    jobs := repository.NewEnrichmentRepository(db)
+    imported := enqueueTestSongs(t, songs, jobs, 3)
+
+    claimed, err := jobs.Claim(2, time.Minute)
+    if err != nil {
+        t.Fatalf("Claim: %v", err)
+    }
+    if len(claimed) != 2 {
+        t.Fatalf("claimed %d jobs, want 2", len(claimed))
+    }
+    for _, job := range claimed {
+        if job.Attempts != 1 || job.GroupName != "Muse" || job.SongName == "" {
+            t.Errorf("claimed %+v, want the first attempt at a Muse song", job)
+        }
+    }
+
+    rest, err := jobs.Claim(5, time.Minute)
+    if err != nil {
+        t.Fatalf("Claim: %v", err)
+    }
+    if len(rest) != 1 || rest[0].SongID == claimed[0].SongID || rest[0].SongID == claimed[1].SongID {
+        t.Fatalf("second claim = %+v, want only the job left", rest)
+    }
+    if more, err := jobs.Claim(5, time.Minute); err != nil || len(more) != 0 {
+        t.Errorf("claim with every job leased = %+v, %v; want none", more, err)
+    }
+
+    // An expired lease lets the job be claimed again, as its next attempt.
+    if _, err := db.Exec("UPDATE enrichment_jobs SET locked_until = CURRENT_TIMESTAMP - INTERVAL '1 second' WHERE song_id = $1", imported[0].ID); err != nil {
+        t.Fatalf("expire lease: %v", err)
+    }
+    again, err := jobs.Claim(5, time.Minute)
+    if err != nil {
+        t.Fatalf("Claim: %v", err)
+    }
+    if len(again) != 1 || again[0].SongID != imported[0].ID || again[0].Attempts != 2 {
+        t.Errorf("claim after the lease expired = %+v, want song %d at attempt 2", again, imported[0].ID)
+    }
+}
+
+func TestClaimSkipsSongsInTheTrash(t *testing.T) {
+    db := openTestDB(t)
+    songs := repository.NewSongRepository(db)
+    jobs := repository.NewEnrichmentRepository(db)
+    imported := enqueueTestSongs(t, songs, jobs, 2)
+
+    if err := songs.Delete(imported[0].ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("Delete: %v", err)
+    }
+    claimed, err := jobs.Claim(5, time.Minute)
+    if err != nil {
+        t.Fatalf("Claim: %v", err)
+    }
+    if len(claimed) != 1 || claimed[0].SongID != imported[1].ID {
+        t.Fatalf("claimed %+v, want only song %d", claimed, imported[1].ID)
+    }
+
+    if _, err := songs.Restore(imported[0].ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("Restore: %v", err)
+    }
+    claimed, err = jobs.Claim(5, time.Minute)
+    if err != nil {
+        t.Fatalf("Claim: %v", err)
+    }
+    if len(claimed) != 1 || claimed[0].SongID != imported[0].ID {
+        t.Errorf("claimed %+v after restore, want song %d", claimed, imported[0].ID)
+    }
+}
+
+func TestConcurrentClaimsTakeDifferentJobs(t *testing.T) {
+    db := openTestDB(t)
+    songs := repository.NewSongRepository(db)
+    jobs := repository.NewEnrichmentRepository(db)
+    const n = 200
+    enqueueTestSongs(t, songs, jobs, n)
+
+    var mu sync.Mutex
+    claims := make(map[int]int)
+    var wg sync.WaitGroup
+    for w := 0; w < 8; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                claimed, err := jobs.Claim(3, time.Minute)
+                if err != nil {
+                    t.Errorf("Claim: %v", err)
+                    return
+                }
+                if len(claimed) == 0 {
+                    return
+                }
+                mu.Lock()
+                for _, job := range claimed {
+                    claims[job.SongID]++
+                }
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if len(claims) != n {
+        t.Errorf("claimed jobs of %d songs, want %d", len(claims), n)
+    }
+    for songID, count := range claims {
+        if count != 1 {
+            t.Errorf("job of song %d claimed %d times, want once", songID, count)
+        }
+    }
+}
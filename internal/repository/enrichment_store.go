@@ -0,0 +1,35 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "time"
+)
+
+// EnrichmentStore is the queue of songs waiting to be filled in from the
+// music info API.
+//
+// Claimed jobs carry their attempt count, which the other methods use as a
+// fencing token: a worker whose lease ran out, or whose job was re-queued in
+// the meantime, cannot complete, retry or fail it.
+type EnrichmentStore interface {
+    // Enqueue marks a song pending and schedules its job to run now,
+    // starting the attempt count over. With refresh the job bypasses the
+    // song info cache.
+    Enqueue(songID int, refresh bool) (*models.Enrichment, error)
+    // Claim leases up to limit due jobs to the caller.
+    Claim(limit int, lease time.Duration) ([]models.EnrichmentJob, error)
+    // Complete copies the non-empty details onto the song, marks it enriched
+    // and removes the job.
+    Complete(job *models.EnrichmentJob, detail *models.SongDetail) error
+    // Retry releases the job to run again at runAt. With refund the claimed
+    // attempt is given back, for jobs that were not really tried.
+    Retry(job *models.EnrichmentJob, runAt time.Time, cause string, refund bool) error
+    // Fail removes the job and marks the song failed.
+    Fail(job *models.EnrichmentJob, cause string) error
+    Get(songID int) (*models.Enrichment, error)
+}
+
+var (
+    _ EnrichmentStore = (*EnrichmentRepository)(nil)
+    _ EnrichmentStore = (*MemoryEnrichmentRepository)(nil)
+)
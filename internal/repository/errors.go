@@ -0,0 +1,62 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+)
+
+// translateError maps driver errors onto the domain errors in models.
+func translateError(err error) error {
+    if err == nil {
+        return nil
+    }
+
+    if errors.Is(err, sql.ErrNoRows) {
+        return models.ErrNotFound
+    }
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        switch pqErr.Code.Class() {
+        case "22":
+            // data exception: invalid date format, value too long, ...
+            return fmt.Errorf("%w: %s", models.ErrValidation, pqErr.Message)
+        case "23":
+            // unique_violation, or foreign_key_violation such as deleting
+            // an artist that still has songs
+            if pqErr.Code == "23505" || pqErr.Code == "23503" {
+                return fmt.Errorf("%w: %s", models.ErrConflict, pqErr.Message)
+            }
+            return fmt.Errorf("%w: %s", models.ErrValidation, pqErr.Message)
+        }
+    }
+
+    return err
+}
+
+func songNotFound(id int) error {
+    return fmt.Errorf("song with id %d: %w", id, models.ErrNotFound)
+}
+
+func artistNotFound(id int) error {
+    return fmt.Errorf("artist with id %d: %w", id, models.ErrNotFound)
+}
+
+func albumNotFound(id int) error {
+    return fmt.Errorf("album with id %d: %w", id, models.ErrNotFound)
+}
+
+func playlistNotFound(id int) error {
+    return fmt.Errorf("playlist with id %d: %w", id, models.ErrNotFound)
+}
+
+func versionMismatch(id int) error {
+    return fmt.Errorf("song with id %d has changed: %w", id, models.ErrPreconditionFailed)
+}
+
+func revisionNotFound(songID, revision int) error {
+    return fmt.Errorf("revision %d of song with id %d: %w", revision, songID, models.ErrNotFound)
+}
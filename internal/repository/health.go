@@ -0,0 +1,23 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "music-library/internal/models"
+)
+
+// DatabaseHealth checks that Postgres answers.
+type DatabaseHealth struct {
+    db *sql.DB
+}
+
+func NewDatabaseHealth(db *sql.DB) *DatabaseHealth {
+    return &DatabaseHealth{db: db}
+}
+
+func (h *DatabaseHealth) CheckHealth(ctx context.Context) models.HealthCheck {
+    if err := h.db.PingContext(ctx); err != nil {
+        return models.HealthCheck{Status: models.HealthDown, Critical: true, Error: err.Error()}
+    }
+    return models.HealthCheck{Status: models.HealthUp, Critical: true}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+    "fmt"
+    "music-library/internal/models"
+    "sort"
+    "strings"
+    "time"
+)
+
+// MemoryAlbumRepository is the in-memory counterpart of AlbumRepository,
+// sharing a MemoryDB with the other memory stores.
+type MemoryAlbumRepository struct {
+    db *MemoryDB
+}
+
+func NewMemoryAlbumRepository(db *MemoryDB) *MemoryAlbumRepository {
+    return &MemoryAlbumRepository{db: db}
+}
+
+func (r *MemoryAlbumRepository) Create(album *models.Album) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if err := r.checkSongs(album.SongIDs); err != nil {
+        return err
+    }
+
+    artist := r.db.upsertArtist(models.NormalizeArtistName(album.Artist))
+    now := time.Now()
+    album.ID = r.db.nextAlbumID
+    album.ArtistID = artist.ID
+    album.Artist = artist.Name
+    album.TrackCount = len(album.SongIDs)
+    album.CreatedAt = now
+    album.UpdatedAt = now
+    r.db.nextAlbumID++
+
+    stored := *album
+    stored.SongIDs = nil
+    r.db.albums[album.ID] = stored
+    r.db.tracks[album.ID] = append([]int(nil), album.SongIDs...)
+    return nil
+}
+
+func (r *MemoryAlbumRepository) Delete(id int) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if _, ok := r.db.albums[id]; !ok {
+        return albumNotFound(id)
+    }
+    delete(r.db.albums, id)
+    delete(r.db.tracks, id)
+    return nil
+}
+
+func (r *MemoryAlbumRepository) GetByID(id int) (*models.Album, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    album, ok := r.album(id)
+    if !ok {
+        return nil, albumNotFound(id)
+    }
+    return &album, nil
+}
+
+func (r *MemoryAlbumRepository) Tracks(id int) ([]models.AlbumTrack, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    var tracks []models.AlbumTrack
+    for i, songID := range visibleEntries(r.db.tracks[id], r.hidden(id)) {
+        tracks = append(tracks, models.AlbumTrack{Position: i + 1, Song: r.db.songs[songID]})
+    }
+    return tracks, nil
+}
+
+func (r *MemoryAlbumRepository) SetTracks(id int, songIDs []int) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    album, ok := r.db.albums[id]
+    if !ok {
+        return albumNotFound(id)
+    }
+    if err := r.checkSongs(songIDs); err != nil {
+        return err
+    }
+
+    album.UpdatedAt = time.Now()
+    r.db.albums[id] = album
+    r.db.tracks[id] = mergeHidden(r.db.tracks[id], r.hidden(id), songIDs)
+    return nil
+}
+
+func (r *MemoryAlbumRepository) List(filter *models.AlbumFilter) ([]models.Album, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    matched := r.match(filter)
+    offset := (filter.Page - 1) * filter.PageSize
+    if offset < 0 {
+        offset = 0
+    }
+    if offset >= len(matched) {
+        return nil, nil
+    }
+    matched = matched[offset:]
+    if len(matched) > filter.PageSize {
+        matched = matched[:filter.PageSize]
+    }
+    return matched, nil
+}
+
+func (r *MemoryAlbumRepository) Count(filter *models.AlbumFilter) (int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    return len(r.match(filter)), nil
+}
+
+// match returns the albums passing filter ordered by title. Callers must
+// hold the read lock.
+func (r *MemoryAlbumRepository) match(filter *models.AlbumFilter) []models.Album {
+    var matched []models.Album
+    for id := range r.db.albums {
+        album, _ := r.album(id)
+        if filter.ArtistID != 0 && album.ArtistID != filter.ArtistID {
+            continue
+        }
+        if !containsFold(album.Title, filter.Title) {
+            continue
+        }
+        matched = append(matched, album)
+    }
+
+    sort.Slice(matched, func(i, j int) bool {
+        if matched[i].Title != matched[j].Title {
+            return matched[i].Title < matched[j].Title
+        }
+        return matched[i].ID < matched[j].ID
+    })
+    return matched
+}
+
+// album returns the stored album with its current artist name and track
+// count. Callers must hold the read lock.
+func (r *MemoryAlbumRepository) album(id int) (models.Album, bool) {
+    album, ok := r.db.albums[id]
+    if !ok {
+        return album, false
+    }
+    album.Artist = r.db.artists[album.ArtistID].Name
+    album.TrackCount = len(visibleEntries(r.db.tracks[id], r.hidden(id)))
+    return album, true
+}
+
+// hidden returns the album's songs that are in the trash. Callers must hold
+// the read lock.
+func (r *MemoryAlbumRepository) hidden(id int) map[int]bool {
+    hidden := make(map[int]bool)
+    for _, songID := range r.db.tracks[id] {
+        if r.db.songs[songID].DeletedAt != nil {
+            hidden[songID] = true
+        }
+    }
+    return hidden
+}
+
+// checkSongs mirrors checkTrackSongs: every id must name an existing song
+// that is not in the trash.
+func (r *MemoryAlbumRepository) checkSongs(songIDs []int) error {
+    var missing []string
+    for _, id := range songIDs {
+        if song, ok := r.db.songs[id]; !ok || song.DeletedAt != nil {
+            missing = append(missing, fmt.Sprint(id))
+        }
+    }
+    if len(missing) > 0 {
+        return &models.ValidationError{Field: "song_ids", Message: "contains unknown songs " + strings.Join(missing, ", ")}
+    }
+    return nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+    "fmt"
+    "music-library/internal/models"
+    "sort"
+    "time"
+)
+
+// MemoryArtistRepository is the in-memory counterpart of ArtistRepository,
+// sharing a MemoryDB with MemorySongRepository.
+type MemoryArtistRepository struct {
+    db *MemoryDB
+}
+
+func NewMemoryArtistRepository(db *MemoryDB) *MemoryArtistRepository {
+    return &MemoryArtistRepository{db: db}
+}
+
+func (r *MemoryArtistRepository) Create(artist *models.Artist) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    artist.Name = models.NormalizeArtistName(artist.Name)
+    if err := r.db.checkArtistName(0, artist.Name); err != nil {
+        return err
+    }
+
+    now := time.Now()
+    artist.ID = r.db.nextArtistID
+    artist.SongCount = 0
+    artist.CreatedAt = now
+    artist.UpdatedAt = now
+    r.db.nextArtistID++
+
+    r.db.artists[artist.ID] = *artist
+    return nil
+}
+
+func (r *MemoryArtistRepository) Update(artist *models.Artist) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    existing, ok := r.db.artists[artist.ID]
+    if !ok {
+        return artistNotFound(artist.ID)
+    }
+    artist.Name = models.NormalizeArtistName(artist.Name)
+    if err := r.db.checkArtistName(artist.ID, artist.Name); err != nil {
+        return err
+    }
+
+    artist.CreatedAt = existing.CreatedAt
+    artist.UpdatedAt = time.Now()
+    r.db.artists[artist.ID] = *artist
+
+    // Songs keep a copy of the name as their group.
+    for id, song := range r.db.songs {
+        if song.ArtistID == artist.ID {
+            song.GroupName = artist.Name
+            r.db.songs[id] = song
+        }
+    }
+    artist.SongCount = r.songCount(artist.ID, false)
+    return nil
+}
+
+func (r *MemoryArtistRepository) Delete(id int) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if _, ok := r.db.artists[id]; !ok {
+        return artistNotFound(id)
+    }
+    if n := r.songCount(id, true); n > 0 {
+        return fmt.Errorf("%w: artist with id %d still has %d songs", models.ErrConflict, id, n)
+    }
+    for _, album := range r.db.albums {
+        if album.ArtistID == id {
+            return fmt.Errorf("%w: artist with id %d still has albums", models.ErrConflict, id)
+        }
+    }
+
+    delete(r.db.artists, id)
+    return nil
+}
+
+func (r *MemoryArtistRepository) GetByID(id int) (*models.Artist, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    artist, ok := r.db.artists[id]
+    if !ok {
+        return nil, artistNotFound(id)
+    }
+    artist.SongCount = r.songCount(id, false)
+    return &artist, nil
+}
+
+func (r *MemoryArtistRepository) List(filter *models.ArtistFilter) ([]models.Artist, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    matched := r.match(filter)
+    offset := (filter.Page - 1) * filter.PageSize
+    if offset < 0 {
+        offset = 0
+    }
+    if offset >= len(matched) {
+        return nil, nil
+    }
+    matched = matched[offset:]
+    if len(matched) > filter.PageSize {
+        matched = matched[:filter.PageSize]
+    }
+    return matched, nil
+}
+
+func (r *MemoryArtistRepository) Count(filter *models.ArtistFilter) (int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    return len(r.match(filter)), nil
+}
+
+// match returns the artists passing filter ordered by name. Callers must
+// hold the read lock.
+func (r *MemoryArtistRepository) match(filter *models.ArtistFilter) []models.Artist {
+    var matched []models.Artist
+    for _, artist := range r.db.artists {
+        if !containsFold(artist.Name, filter.Name) {
+            continue
+        }
+        artist.SongCount = r.songCount(artist.ID, false)
+        matched = append(matched, artist)
+    }
+
+    sort.Slice(matched, func(i, j int) bool {
+        if matched[i].Name != matched[j].Name {
+            return matched[i].Name < matched[j].Name
+        }
+        return matched[i].ID < matched[j].ID
+    })
+    return matched
+}
+
+// songCount counts the songs of an artist; with includeDeleted, songs in
+// the trash count too.
+func (r *MemoryArtistRepository) songCount(artistID int, includeDeleted bool) int {
+    n := 0
+    for _, song := range r.db.songs {
+        if song.ArtistID == artistID && (includeDeleted || song.DeletedAt == nil) {
+            n++
+        }
+    }
+    return n
+}
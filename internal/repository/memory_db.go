@@ -0,0 +1,134 @@
+package repository
+
+import (
+    "fmt"
+    "music-library/internal/models"
+    "sync"
+    "time"
+)
+
+// MemoryDB is the shared state behind the in-memory stores, so songs and
+// artists stay consistent with each other like the Postgres tables do.
+type MemoryDB struct {
+    mu           sync.RWMutex
+    songs        map[int]models.Song
+    artists      map[int]models.Artist
+    albums       map[int]models.Album
+    tracks       map[int][]int // album id -> song ids in track order
+    playlists    map[int]models.Playlist
+    entries      map[int]models.PlaylistEntry              // entry id -> entry, Song holds only the id
+    order        map[int][]int                             // playlist id -> entry ids in order
+    jobs         map[int]*memoryEnrichmentJob              // song id -> enrichment job
+    enrichErrors map[int]string                            // song id -> why enrichment failed
+    provenance   map[int]map[string]models.FieldProvenance // song id -> field -> provenance
+    revisions    map[int][]models.SongRevision             // song id -> revisions, oldest first
+    nextSongID   int
+    nextArtistID int
+    nextAlbumID  int
+    nextPlaylist int
+    nextEntryID  int
+    nextJobID    int64
+}
+
+func NewMemoryDB() *MemoryDB {
+    return &MemoryDB{
+        songs:        make(map[int]models.Song),
+        artists:      make(map[int]models.Artist),
+        albums:       make(map[int]models.Album),
+        tracks:       make(map[int][]int),
+        playlists:    make(map[int]models.Playlist),
+        entries:      make(map[int]models.PlaylistEntry),
+        order:        make(map[int][]int),
+        jobs:         make(map[int]*memoryEnrichmentJob),
+        enrichErrors: make(map[int]string),
+        provenance:   make(map[int]map[string]models.FieldProvenance),
+        revisions:    make(map[int][]models.SongRevision),
+        nextSongID:   1,
+        nextArtistID: 1,
+        nextAlbumID:  1,
+        nextPlaylist: 1,
+        nextEntryID:  1,
+    }
+}
+
+// ensureArtist mirrors the Postgres ensureArtist: it points song at the
+// artist named song.GroupName, creating it on first use. Callers must hold
+// the write lock.
+func (db *MemoryDB) ensureArtist(song *models.Song) error {
+    name := models.NormalizeArtistName(song.GroupName)
+    if name == "" {
+        return &models.ValidationError{Field: "group", Message: "must not be empty"}
+    }
+
+    artist := db.upsertArtist(name)
+    song.ArtistID = artist.ID
+    song.GroupName = artist.Name
+    return nil
+}
+
+// upsertArtist returns the artist called name, creating it if needed.
+// Callers must hold the write lock.
+func (db *MemoryDB) upsertArtist(name string) models.Artist {
+    if artist, ok := db.artistByName(name); ok {
+        return artist
+    }
+
+    now := time.Now()
+    artist := models.Artist{ID: db.nextArtistID, Name: name, CreatedAt: now, UpdatedAt: now}
+    db.artists[artist.ID] = artist
+    db.nextArtistID++
+    return artist
+}
+
+// artistByName finds an artist by its case-insensitive name key. Callers
+// must hold the lock.
+func (db *MemoryDB) artistByName(name string) (models.Artist, bool) {
+    key := models.ArtistNameKey(name)
+    for _, artist := range db.artists {
+        if models.ArtistNameKey(artist.Name) == key {
+            return artist, true
+        }
+    }
+    return models.Artist{}, false
+}
+
+// removeSong drops songID from every track listing and playlist and drops
+// its enrichment job and provenance, like the ON DELETE CASCADE on
+// album_tracks, playlist_entries, enrichment_jobs and song_field_provenance.
+// Callers must hold the write lock.
+func (db *MemoryDB) removeSong(songID int) {
+    delete(db.jobs, songID)
+    delete(db.enrichErrors, songID)
+    delete(db.provenance, songID)
+    for albumID, songIDs := range db.tracks {
+        db.tracks[albumID] = removeInts(songIDs, func(id int) bool { return id == songID })
+    }
+    for playlistID, entryIDs := range db.order {
+        db.order[playlistID] = removeInts(entryIDs, func(id int) bool {
+            if db.entries[id].Song.ID != songID {
+                return false
+            }
+            delete(db.entries, id)
+            return true
+        })
+    }
+}
+
+func removeInts(values []int, drop func(int) bool) []int {
+    kept := values[:0]
+    for _, v := range values {
+        if !drop(v) {
+            kept = append(kept, v)
+        }
+    }
+    return kept
+}
+
+// checkArtistName rejects name if another artist than id already uses it,
+// like the artists_name_key_unique constraint. Callers must hold the lock.
+func (db *MemoryDB) checkArtistName(id int, name string) error {
+    if existing, ok := db.artistByName(name); ok && existing.ID != id {
+        return fmt.Errorf("%w: artist %q already exists", models.ErrConflict, existing.Name)
+    }
+    return nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "sort"
+    "time"
+)
+
+// memoryEnrichmentJob is the in-memory counterpart of an enrichment_jobs row.
+type memoryEnrichmentJob struct {
+    id          int64
+    attempts    int
+    runAt       time.Time
+    lockedUntil time.Time
+    lastError   string
+    refresh     bool
+}
+
+type MemoryEnrichmentRepository struct {
+    db *MemoryDB
+}
+
+func NewMemoryEnrichmentRepository(db *MemoryDB) *MemoryEnrichmentRepository {
+    return &MemoryEnrichmentRepository{db: db}
+}
+
+// enqueueEnrichment schedules songID to be enriched at runAt, starting the
+// attempt count over. A refresh asked for earlier is kept. Callers must hold
+// the write lock.
+func (db *MemoryDB) enqueueEnrichment(songID int, runAt time.Time, refresh bool) {
+    if job, ok := db.jobs[songID]; ok {
+        refresh = refresh || job.refresh
+    }
+    db.nextJobID++
+    db.jobs[songID] = &memoryEnrichmentJob{id: db.nextJobID, runAt: runAt, refresh: refresh}
+    delete(db.enrichErrors, songID)
+}
+
+func (r *MemoryEnrichmentRepository) Enqueue(songID int, refresh bool) (*models.Enrichment, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    song, ok := r.db.songs[songID]
+    if !ok || song.DeletedAt != nil {
+        return nil, songNotFound(songID)
+    }
+
+    song.EnrichmentStatus = models.EnrichmentPending
+    r.db.songs[songID] = song
+    r.db.enqueueEnrichment(songID, time.Now(), refresh)
+    return r.db.enrichment(songID), nil
+}
+
+func (r *MemoryEnrichmentRepository) Claim(limit int, lease time.Duration) ([]models.EnrichmentJob, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    now := time.Now()
+    var due []int
+    for songID, job := range r.db.jobs {
+        if !job.runAt.After(now) && job.lockedUntil.Before(now) && r.db.songs[songID].DeletedAt == nil {
+            due = append(due, songID)
+        }
+    }
+    sort.Slice(due, func(i, j int) bool {
+        return r.db.jobs[due[i]].runAt.Before(r.db.jobs[due[j]].runAt)
+    })
+    if len(due) > limit {
+        due = due[:limit]
+    }
+
+    jobs := make([]models.EnrichmentJob, 0, len(due))
+    for _, songID := range due {
+        job := r.db.jobs[songID]
+        job.attempts++
+        job.lockedUntil = now.Add(lease)
+        song := r.db.songs[songID]
+        jobs = append(jobs, models.EnrichmentJob{
+            ID:        job.id,
+            SongID:    songID,
+            GroupName: song.GroupName,
+            SongName:  song.SongName,
+            Attempts:  job.attempts,
+            Refresh:   job.refresh,
+        })
+    }
+    return jobs, nil
+}
+
+func (r *MemoryEnrichmentRepository) Complete(job *models.EnrichmentJob, detail *models.SongDetail) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if !r.db.finishJob(job) {
+        return nil
+    }
+
+    old := r.db.songs[job.SongID]
+    song := old
+    kept := keptFields(r.db.provenance[job.SongID], &old)
+    var applied []string
+    if !detail.ReleaseDate.IsZero() && !kept[models.FieldReleaseDate] {
+        song.ReleaseDate = detail.ReleaseDate
+        applied = append(applied, models.FieldReleaseDate)
+    }
+    if detail.Text != "" && !kept[models.FieldText] {
+        song.Text = detail.Text
+        applied = append(applied, models.FieldText)
+    }
+    if detail.Link != "" && !kept[models.FieldLink] {
+        song.Link = detail.Link
+        applied = append(applied, models.FieldLink)
+    }
+    for _, field := range applied {
+        r.db.recordProvenance(job.SongID, []string{field}, enrichmentSource(detail, field))
+    }
+    song.EnrichmentStatus = models.EnrichmentEnriched
+    if len(song.ChangedFields(&old)) > 0 {
+        song.Version++
+        song.UpdatedAt = time.Now()
+        r.db.recordRevision(models.NewSongRevision(models.RevisionUpdate, models.ActorEnrichment, &old, &song))
+    }
+    r.db.songs[job.SongID] = song
+    return nil
+}
+
+func (r *MemoryEnrichmentRepository) Retry(job *models.EnrichmentJob, runAt time.Time, cause string, refund bool) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    stored, ok := r.db.jobs[job.SongID]
+    if !ok || stored.id != job.ID || stored.attempts != job.Attempts {
+        return nil
+    }
+    if refund {
+        stored.attempts--
+    }
+    stored.runAt = runAt
+    stored.lockedUntil = time.Time{}
+    stored.lastError = cause
+    return nil
+}
+
+func (r *MemoryEnrichmentRepository) Fail(job *models.EnrichmentJob, cause string) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if !r.db.finishJob(job) {
+        return nil
+    }
+
+    song := r.db.songs[job.SongID]
+    song.EnrichmentStatus = models.EnrichmentFailed
+    r.db.songs[job.SongID] = song
+    r.db.enrichErrors[job.SongID] = cause
+    return nil
+}
+
+// finishJob mirrors the Postgres finishJob: it removes job if it still
+// belongs to the caller, or hands it back if its song went to the trash.
+// Callers must hold the write lock.
+func (db *MemoryDB) finishJob(job *models.EnrichmentJob) bool {
+    stored, ok := db.jobs[job.SongID]
+    if !ok || stored.id != job.ID || stored.attempts != job.Attempts {
+        return false
+    }
+    if db.songs[job.SongID].DeletedAt != nil {
+        stored.attempts--
+        stored.lockedUntil = time.Time{}
+        return false
+    }
+    delete(db.jobs, job.SongID)
+    return true
+}
+
+func (r *MemoryEnrichmentRepository) Get(songID int) (*models.Enrichment, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    if song, ok := r.db.songs[songID]; !ok || song.DeletedAt != nil {
+        return nil, songNotFound(songID)
+    }
+    return r.db.enrichment(songID), nil
+}
+
+// enrichment reports the enrichment state of a stored song. Callers must
+// hold the lock.
+func (db *MemoryDB) enrichment(songID int) *models.Enrichment {
+    enrichment := &models.Enrichment{
+        SongID: songID,
+        Status: db.songs[songID].EnrichmentStatus,
+        Error:  db.enrichErrors[songID],
+    }
+    for field, entry := range db.provenance[songID] {
+        if isProviderSource(entry.Source) {
+            if enrichment.Sources == nil {
+                enrichment.Sources = make(map[string]string)
+            }
+            enrichment.Sources[field] = entry.Source
+        }
+    }
+    if job, ok := db.jobs[songID]; ok {
+        runAt := job.runAt
+        enrichment.Attempts = job.attempts
+        enrichment.Error = job.lastError
+        enrichment.NextAttemptAt = &runAt
+    }
+    return enrichment
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "sort"
+    "time"
+)
+
+// MemoryPlaylistRepository is the in-memory counterpart of
+// PlaylistRepository, sharing a MemoryDB with the other memory stores.
+type MemoryPlaylistRepository struct {
+    db *MemoryDB
+}
+
+func NewMemoryPlaylistRepository(db *MemoryDB) *MemoryPlaylistRepository {
+    return &MemoryPlaylistRepository{db: db}
+}
+
+func (r *MemoryPlaylistRepository) Create(playlist *models.Playlist) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    now := time.Now()
+    playlist.ID = r.db.nextPlaylist
+    playlist.TrackCount = 0
+    playlist.CreatedAt = now
+    playlist.UpdatedAt = now
+    r.db.nextPlaylist++
+
+    r.db.playlists[playlist.ID] = *playlist
+    return nil
+}
+
+func (r *MemoryPlaylistRepository) Update(playlist *models.Playlist) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    existing, ok := r.db.playlists[playlist.ID]
+    if !ok {
+        return playlistNotFound(playlist.ID)
+    }
+
+    existing.Name = playlist.Name
+    existing.Description = playlist.Description
+    existing.UpdatedAt = time.Now()
+    r.db.playlists[playlist.ID] = existing
+
+    *playlist = r.playlist(existing)
+    return nil
+}
+
+func (r *MemoryPlaylistRepository) Delete(id int) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if _, ok := r.db.playlists[id]; !ok {
+        return playlistNotFound(id)
+    }
+    for _, entryID := range r.db.order[id] {
+        delete(r.db.entries, entryID)
+    }
+    delete(r.db.order, id)
+    delete(r.db.playlists, id)
+    return nil
+}
+
+func (r *MemoryPlaylistRepository) GetByID(id int) (*models.Playlist, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    playlist, ok := r.db.playlists[id]
+    if !ok {
+        return nil, playlistNotFound(id)
+    }
+    playlist = r.playlist(playlist)
+    return &playlist, nil
+}
+
+func (r *MemoryPlaylistRepository) List(filter *models.PlaylistFilter) ([]models.Playlist, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    var playlists []models.Playlist
+    for _, playlist := range r.db.playlists {
+        playlists = append(playlists, r.playlist(playlist))
+    }
+    sort.Slice(playlists, func(i, j int) bool {
+        if playlists[i].Name != playlists[j].Name {
+            return playlists[i].Name < playlists[j].Name
+        }
+        return playlists[i].ID < playlists[j].ID
+    })
+
+    offset := (filter.Page - 1) * filter.PageSize
+    if offset < 0 {
+        offset = 0
+    }
+    if offset >= len(playlists) {
+        return nil, nil
+    }
+    playlists = playlists[offset:]
+    if len(playlists) > filter.PageSize {
+        playlists = playlists[:filter.PageSize]
+    }
+    return playlists, nil
+}
+
+func (r *MemoryPlaylistRepository) Count(filter *models.PlaylistFilter) (int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    return len(r.db.playlists), nil
+}
+
+func (r *MemoryPlaylistRepository) Entries(id int, offset, limit int) ([]models.PlaylistEntry, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    entryIDs := visibleEntries(r.db.order[id], r.hidden(id))
+    if offset >= len(entryIDs) {
+        return nil, nil
+    }
+    entryIDs = entryIDs[offset:]
+    if len(entryIDs) > limit {
+        entryIDs = entryIDs[:limit]
+    }
+
+    entries := make([]models.PlaylistEntry, len(entryIDs))
+    for i, entryID := range entryIDs {
+        entries[i] = r.entry(entryID, offset+i+1)
+    }
+    return entries, nil
+}
+
+func (r *MemoryPlaylistRepository) AddEntry(id int, songID int, position int) (*models.PlaylistEntry, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    playlist, ok := r.db.playlists[id]
+    if !ok {
+        return nil, playlistNotFound(id)
+    }
+    hidden := r.hidden(id)
+    visible := visibleEntries(r.db.order[id], hidden)
+    if position == 0 {
+        position = len(visible) + 1
+    }
+    if position < 1 || position > len(visible)+1 {
+        return nil, positionOutOfRange("position", len(visible)+1)
+    }
+    if song, ok := r.db.songs[songID]; !ok || song.DeletedAt != nil {
+        return nil, &models.ValidationError{Field: "song_id", Message: "does not name an existing song"}
+    }
+
+    entryID := r.db.nextEntryID
+    r.db.nextEntryID++
+    r.db.entries[entryID] = models.PlaylistEntry{ID: entryID, Song: models.Song{ID: songID}, AddedAt: time.Now()}
+    r.db.order[id] = mergeHidden(r.db.order[id], hidden, insertEntry(visible, position, entryID))
+    r.touch(playlist)
+
+    entry := r.entry(entryID, position)
+    return &entry, nil
+}
+
+func (r *MemoryPlaylistRepository) RemoveEntry(id int, position int) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    playlist, ok := r.db.playlists[id]
+    if !ok {
+        return playlistNotFound(id)
+    }
+    hidden := r.hidden(id)
+    visible := visibleEntries(r.db.order[id], hidden)
+    if position < 1 || position > len(visible) {
+        return positionOutOfRange("position", len(visible))
+    }
+
+    delete(r.db.entries, visible[position-1])
+    r.db.order[id] = mergeHidden(r.db.order[id], hidden, append(visible[:position-1], visible[position:]...))
+    r.touch(playlist)
+    return nil
+}
+
+func (r *MemoryPlaylistRepository) MoveEntry(id int, from, to int) (*models.PlaylistEntry, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    playlist, ok := r.db.playlists[id]
+    if !ok {
+        return nil, playlistNotFound(id)
+    }
+    hidden := r.hidden(id)
+    visible := visibleEntries(r.db.order[id], hidden)
+    if from < 1 || from > len(visible) {
+        return nil, positionOutOfRange("from", len(visible))
+    }
+    if to < 1 || to > len(visible) {
+        return nil, positionOutOfRange("to", len(visible))
+    }
+
+    entryID := visible[from-1]
+    r.db.order[id] = mergeHidden(r.db.order[id], hidden, moveEntry(visible, from, to))
+    r.touch(playlist)
+
+    entry := r.entry(entryID, to)
+    return &entry, nil
+}
+
+// playlist fills in the track count. Callers must hold the read lock.
+func (r *MemoryPlaylistRepository) playlist(playlist models.Playlist) models.Playlist {
+    playlist.TrackCount = len(visibleEntries(r.db.order[playlist.ID], r.hidden(playlist.ID)))
+    return playlist
+}
+
+// hidden returns the entries of the playlist whose song is in the trash.
+// Callers must hold the read lock.
+func (r *MemoryPlaylistRepository) hidden(id int) map[int]bool {
+    hidden := make(map[int]bool)
+    for _, entryID := range r.db.order[id] {
+        if r.db.songs[r.db.entries[entryID].Song.ID].DeletedAt != nil {
+            hidden[entryID] = true
+        }
+    }
+    return hidden
+}
+
+// entry returns the stored entry with its current song. Callers must hold
+// the read lock.
+func (r *MemoryPlaylistRepository) entry(entryID int, position int) models.PlaylistEntry {
+    entry := r.db.entries[entryID]
+    entry.Position = position
+    entry.Song = r.db.songs[entry.Song.ID]
+    return entry
+}
+
+// touch bumps the playlist's updated_at. Callers must hold the write lock.
+func (r *MemoryPlaylistRepository) touch(playlist models.Playlist) {
+    playlist.UpdatedAt = time.Now()
+    r.db.playlists[playlist.ID] = playlist
+}
@@ -0,0 +1,567 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// MemorySongRepository keeps songs in process memory. It mirrors the
+// filtering and pagination of SongRepository and is meant for tests and demos.
+type MemorySongRepository struct {
+    db *MemoryDB
+}
+
+func NewMemorySongRepository(db *MemoryDB) *MemorySongRepository {
+    return &MemorySongRepository{db: db}
+}
+
+func (r *MemorySongRepository) Create(song *models.Song, actor string) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if err := r.db.ensureArtist(song); err != nil {
+        return err
+    }
+
+    now := time.Now()
+    song.ID = r.db.nextSongID
+    song.Version = 1
+    song.CreatedAt = now
+    song.UpdatedAt = now
+    r.db.nextSongID++
+
+    r.db.songs[song.ID] = *song
+    r.db.recordProvenance(song.ID, song.CreatedFields(), models.SourceUser)
+    r.db.recordRevision(models.NewSongRevision(models.RevisionCreate, actor, nil, song))
+    if song.EnrichmentStatus == models.EnrichmentPending {
+        r.db.enqueueEnrichment(song.ID, now, song.RefreshInfo)
+    }
+    return nil
+}
+
+func (r *MemorySongRepository) Update(song *models.Song, expectedVersion int, actor string) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    existing, err := r.checkVersion(song.ID, expectedVersion)
+    if err != nil {
+        return err
+    }
+    if err := r.db.ensureArtist(song); err != nil {
+        return err
+    }
+
+    song.Version = existing.Version + 1
+    song.EnrichmentStatus = existing.EnrichmentStatus
+    song.CreatedAt = existing.CreatedAt
+    song.UpdatedAt = time.Now()
+    r.db.songs[song.ID] = *song
+    r.db.recordProvenance(song.ID, song.ChangedFields(&existing), models.SourceUser)
+    r.db.recordRevision(models.NewSongRevision(models.RevisionUpdate, actor, &existing, song))
+    return nil
+}
+
+func (r *MemorySongRepository) Patch(id int, patch *models.SongPatch, expectedVersion int, actor string) (*models.Song, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    old, err := r.checkVersion(id, expectedVersion)
+    if err != nil {
+        return nil, err
+    }
+
+    song := old
+    patch.ApplyTo(&song)
+    if patch.GroupName != nil {
+        if err := r.db.ensureArtist(&song); err != nil {
+            return nil, err
+        }
+    }
+    song.Version++
+    song.UpdatedAt = time.Now()
+    r.db.songs[id] = song
+    r.db.recordProvenance(id, patch.Fields(), models.SourceUser)
+    r.db.recordRevision(models.NewSongRevision(models.RevisionUpdate, actor, &old, &song))
+    return &song, nil
+}
+
+func (r *MemorySongRepository) Delete(id int, expectedVersion int, actor string) error {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    old, err := r.checkVersion(id, expectedVersion)
+    if err != nil {
+        return err
+    }
+
+    song := old
+    now := time.Now()
+    song.DeletedAt = &now
+    song.Version++
+    song.UpdatedAt = now
+    r.db.songs[id] = song
+    r.db.recordRevision(models.NewSongRevision(models.RevisionDelete, actor, &song, nil))
+    return nil
+}
+
+func (r *MemorySongRepository) Restore(id int, expectedVersion int, actor string) (*models.Song, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    old, ok := r.db.songs[id]
+    if !ok {
+        return nil, songNotFound(id)
+    }
+    if expectedVersion != 0 && old.Version != expectedVersion {
+        return nil, versionMismatch(id)
+    }
+    if old.DeletedAt == nil {
+        return &old, nil
+    }
+
+    restored := old
+    restored.DeletedAt = nil
+    restored.Version++
+    restored.UpdatedAt = time.Now()
+    r.db.songs[id] = restored
+    r.db.recordRevision(models.NewSongRevision(models.RevisionRestore, actor, &old, &restored))
+    return &restored, nil
+}
+
+func (r *MemorySongRepository) PurgeDeleted(cutoff time.Time, limit int) (int, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    var expired []models.Song
+    for _, song := range r.db.songs {
+        if song.DeletedAt != nil && song.DeletedAt.Before(cutoff) {
+            expired = append(expired, song)
+        }
+    }
+    sort.Slice(expired, func(i, j int) bool {
+        return expired[i].DeletedAt.Before(*expired[j].DeletedAt)
+    })
+    if len(expired) > limit {
+        expired = expired[:limit]
+    }
+
+    for i := range expired {
+        delete(r.db.songs, expired[i].ID)
+        r.db.removeSong(expired[i].ID)
+        r.db.recordRevision(models.NewSongRevision(models.RevisionPurge, models.ActorPurge, &expired[i], nil))
+    }
+    return len(expired), nil
+}
+
+// checkVersion returns the stored song if it exists, is not in the trash and
+// is at expectedVersion (0 accepts any version). Callers must hold the write
+// lock.
+func (r *MemorySongRepository) checkVersion(id int, expectedVersion int) (models.Song, error) {
+    song, ok := r.db.songs[id]
+    if !ok || song.DeletedAt != nil {
+        return song, songNotFound(id)
+    }
+    if expectedVersion != 0 && song.Version != expectedVersion {
+        return song, versionMismatch(id)
+    }
+    return song, nil
+}
+
+func (r *MemorySongRepository) GetByID(id int, includeDeleted bool) (*models.Song, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    song, ok := r.db.songs[id]
+    if !ok || (song.DeletedAt != nil && !includeDeleted) {
+        return nil, songNotFound(id)
+    }
+    return &song, nil
+}
+
+func (r *MemorySongRepository) List(filter *models.SongFilter) ([]models.Song, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    matched, err := r.match(filter)
+    if err != nil {
+        return nil, err
+    }
+
+    if cursor := filter.Keyset; cursor != nil {
+        if cursor.Before {
+            pos := sort.Search(len(matched), func(i int) bool {
+                return compareToCursor(&matched[i], filter.SortKeys, cursor) >= 0
+            })
+            start := pos - filter.RowLimit()
+            if start < 0 {
+                start = 0
+            }
+            return matched[start:pos], nil
+        }
+        pos := sort.Search(len(matched), func(i int) bool {
+            return compareToCursor(&matched[i], filter.SortKeys, cursor) > 0
+        })
+        return limitSongs(matched[pos:], filter.RowLimit()), nil
+    }
+
+    offset := (filter.Page - 1) * filter.PageSize
+    if offset < 0 {
+        offset = 0
+    }
+    if offset >= len(matched) {
+        return nil, nil
+    }
+
+    return limitSongs(matched[offset:], filter.RowLimit()), nil
+}
+
+func (r *MemorySongRepository) Count(filter *models.SongFilter) (int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    matched, err := r.match(filter)
+    if err != nil {
+        return 0, err
+    }
+    return len(matched), nil
+}
+
+// Search approximates Postgres full-text search with case-insensitive
+// substring matching: every query term must appear in the group, song name
+// or text, and terms prefixed with "-" must not. Terms are stemmed with the
+// query language, or without one the song's, so that "runs" finds "running"
+// in english lyrics.
+func (r *MemorySongRepository) Search(query *models.SearchQuery) ([]models.SearchHit, int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    var include, exclude []string
+    for _, term := range strings.Fields(strings.ToLower(query.Query)) {
+        term = strings.Trim(term, `"`)
+        switch {
+        case term == "" || term == "or":
+        case strings.HasPrefix(term, "-"):
+            exclude = append(exclude, strings.TrimPrefix(term, "-"))
+        default:
+            include = append(include, term)
+        }
+    }
+    if len(include) == 0 {
+        return nil, 0, nil
+    }
+
+    var hits []models.SearchHit
+    for _, song := range r.db.songs {
+        if song.DeletedAt != nil {
+            continue
+        }
+        language := query.Language
+        if language == "" {
+            language = song.Language
+        }
+        terms := stemTerms(include, language)
+        haystack := strings.ToLower(song.GroupName + "\n" + song.SongName + "\n" + song.Text)
+        rank := 0
+        for _, term := range terms {
+            n := strings.Count(haystack, term)
+            if n == 0 {
+                rank = 0
+                break
+            }
+            rank += n
+        }
+        for _, term := range stemTerms(exclude, language) {
+            if term != "" && strings.Contains(haystack, term) {
+                rank = 0
+            }
+        }
+        if rank == 0 {
+            continue
+        }
+
+        hits = append(hits, models.SearchHit{
+            Song:     song,
+            Rank:     float64(rank),
+            Headline: highlightTerms(song.Text, terms),
+        })
+    }
+
+    sort.Slice(hits, func(i, j int) bool {
+        if hits[i].Rank != hits[j].Rank {
+            return hits[i].Rank > hits[j].Rank
+        }
+        return hits[i].ID < hits[j].ID
+    })
+
+    total := len(hits)
+    offset := (query.Page - 1) * query.PageSize
+    if offset >= total {
+        return nil, total, nil
+    }
+    hits = hits[offset:]
+    if len(hits) > query.PageSize {
+        hits = hits[:query.PageSize]
+    }
+    return hits, total, nil
+}
+
+// stemTerms strips common suffixes from english terms, a rough stand-in for
+// the stemming of the english text search configuration. Terms in other
+// languages are left alone.
+func stemTerms(terms []string, language string) []string {
+    if language != "english" {
+        return terms
+    }
+    stemmed := make([]string, len(terms))
+    for i, term := range terms {
+        stemmed[i] = term
+        for _, suffix := range []string{"ing", "ed", "es", "s"} {
+            stem := strings.TrimSuffix(term, suffix)
+            if stem == term || len(stem) < 3 {
+                continue
+            }
+            // "running" and "stopped" double their last consonant.
+            if n := len(stem); suffix != "es" && suffix != "s" && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouls", rune(stem[n-1])) {
+                stem = stem[:n-1]
+            }
+            stemmed[i] = stem
+            break
+        }
+    }
+    return stemmed
+}
+
+// highlightTerms wraps case-insensitive occurrences of terms in <mark> tags,
+// like ts_headline with HighlightAll, and HTML-escapes the rest of the text
+// as markHeadline does.
+func highlightTerms(text string, terms []string) string {
+    quoted := make([]string, len(terms))
+    for i, term := range terms {
+        quoted[i] = regexp.QuoteMeta(term)
+    }
+    re := regexp.MustCompile("(?i)(" + strings.Join(quoted, "|") + ")")
+    text = strings.NewReplacer(headlineStart, "", headlineStop, "").Replace(text)
+    return markHeadline(re.ReplaceAllString(text, headlineStart+"$1"+headlineStop))
+}
+
+// match returns the songs passing filter in list order. Callers must hold
+// the read lock.
+func (r *MemorySongRepository) match(filter *models.SongFilter) ([]models.Song, error) {
+    var matched []models.Song
+    for _, song := range r.db.songs {
+        if song.DeletedAt != nil && !filter.IncludeDeleted {
+            continue
+        }
+        if filter.ArtistID != 0 && song.ArtistID != filter.ArtistID {
+            continue
+        }
+        if filter.AlbumID != 0 && !containsInt(r.db.tracks[filter.AlbumID], song.ID) {
+            continue
+        }
+        if !matchName(song.GroupName, filter.GroupName, filter.Fuzzy) {
+            continue
+        }
+        if !matchName(song.SongName, filter.SongName, filter.Fuzzy) {
+            continue
+        }
+        if song.ReleaseDate.IsZero() && !(filter.ReleasedFrom.IsZero() && filter.ReleasedUntil.IsZero()) {
+            continue
+        }
+        if !filter.ReleasedFrom.IsZero() && song.ReleaseDate.Before(filter.ReleasedFrom.Time) {
+            continue
+        }
+        if !filter.ReleasedUntil.IsZero() && !song.ReleaseDate.Before(filter.ReleasedUntil.Time) {
+            continue
+        }
+        matched = append(matched, song)
+    }
+
+    if filter.Fuzzy && len(filter.SortKeys) == 0 {
+        rank := func(song *models.Song) float64 {
+            var score float64
+            if filter.GroupName != "" {
+                score += trigramSimilarity(song.GroupName, filter.GroupName)
+            }
+            if filter.SongName != "" {
+                score += trigramSimilarity(song.SongName, filter.SongName)
+            }
+            return score
+        }
+        sort.Slice(matched, func(i, j int) bool {
+            ri, rj := rank(&matched[i]), rank(&matched[j])
+            if ri != rj {
+                return ri > rj
+            }
+            return matched[i].ID < matched[j].ID
+        })
+        return matched, nil
+    }
+
+    sort.Slice(matched, func(i, j int) bool {
+        return compareSongs(&matched[i], &matched[j], filter.SortKeys) < 0
+    })
+    return matched, nil
+}
+
+func (r *MemorySongRepository) Suggest(query *models.SuggestQuery) ([]models.Suggestion, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    prefix := strings.ToLower(query.Prefix)
+    matches := func(name string) bool {
+        name = strings.ToLower(name)
+        return strings.HasPrefix(name, prefix) || strings.Contains(name, " "+prefix)
+    }
+
+    var suggestions []models.Suggestion
+    hasSongs := make(map[int]bool)
+    for _, song := range r.db.songs {
+        if song.DeletedAt != nil {
+            continue
+        }
+        hasSongs[song.ArtistID] = true
+        if matches(song.SongName) {
+            suggestions = append(suggestions, models.Suggestion{
+                Kind:   "song",
+                Text:   song.SongName,
+                Group:  song.GroupName,
+                SongID: song.ID,
+                Score:  trigramSimilarity(song.SongName, query.Prefix),
+            })
+        }
+    }
+    // Only artists with a song outside the trash are suggested.
+    for _, artist := range r.db.artists {
+        if hasSongs[artist.ID] && matches(artist.Name) {
+            suggestions = append(suggestions, models.Suggestion{
+                Kind:  "group",
+                Text:  artist.Name,
+                Score: trigramSimilarity(artist.Name, query.Prefix),
+            })
+        }
+    }
+
+    sort.Slice(suggestions, func(i, j int) bool {
+        if suggestions[i].Score != suggestions[j].Score {
+            return suggestions[i].Score > suggestions[j].Score
+        }
+        return suggestions[i].Text < suggestions[j].Text
+    })
+    if len(suggestions) > query.Limit {
+        suggestions = suggestions[:query.Limit]
+    }
+    return suggestions, nil
+}
+
+// compareSongs orders songs by keys and then id, like orderByClause.
+func compareSongs(a, b *models.Song, keys []models.SortKey) int {
+    for _, key := range keys {
+        if c := compareSortValues(key, key.Value(a), key.Value(b)); c != 0 {
+            return c
+        }
+    }
+    return a.ID - b.ID
+}
+
+// compareToCursor compares song with the row the cursor points at.
+func compareToCursor(song *models.Song, keys []models.SortKey, cursor *models.SongCursor) int {
+    for i, key := range keys {
+        if c := compareSortValues(key, key.Value(song), cursor.Keys[i]); c != 0 {
+            return c
+        }
+    }
+    return song.ID - cursor.ID
+}
+
+// compareSortValues orders NULLs (nil) last in either direction, like
+// orderByClause.
+func compareSortValues(key models.SortKey, a, b *string) int {
+    switch {
+    case a == nil && b == nil:
+        return 0
+    case a == nil:
+        return 1
+    case b == nil:
+        return -1
+    }
+
+    c := strings.Compare(*a, *b)
+    if key.Column == "created_at" || key.Column == "updated_at" {
+        ta, _ := time.Parse(time.RFC3339Nano, *a)
+        tb, _ := time.Parse(time.RFC3339Nano, *b)
+        c = ta.Compare(tb)
+    }
+    if key.Desc {
+        return -c
+    }
+    return c
+}
+
+func containsInt(values []int, value int) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+func limitSongs(songs []models.Song, limit int) []models.Song {
+    if limit >= 0 && limit < len(songs) {
+        return songs[:limit]
+    }
+    return songs
+}
+
+// matchName mirrors the name filters of songFilterWhere: ILIKE '%filter%'
+// or, in fuzzy mode, the pg_trgm % operator.
+func matchName(name, filter string, fuzzy bool) bool {
+    if filter == "" {
+        return true
+    }
+    if fuzzy {
+        return trigramSimilarity(name, filter) >= models.FuzzyThreshold
+    }
+    return containsFold(name, filter)
+}
+
+// containsFold reports whether substr is within s, ignoring case, like ILIKE '%substr%'.
+func containsFold(s, substr string) bool {
+    return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// trigramSimilarity computes pg_trgm's similarity(): the share of distinct
+// trigrams two strings have in common, with every word padded by two spaces
+// in front and one behind.
+func trigramSimilarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    if len(ta) == 0 || len(tb) == 0 {
+        return 0
+    }
+
+    common := 0
+    for t := range ta {
+        if tb[t] {
+            common++
+        }
+    }
+    return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+func trigrams(s string) map[string]bool {
+    set := make(map[string]bool)
+    words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    for _, word := range words {
+        padded := []rune("  " + word + " ")
+        for i := 0; i+3 <= len(padded); i++ {
+            set[string(padded[i:i+3])] = true
+        }
+    }
+    return set
+}
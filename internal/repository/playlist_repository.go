@@ -0,0 +1,315 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+)
+
+const playlistColumns = `p.id, p.name, p.description,
+    (SELECT COUNT(*) FROM playlist_entries e JOIN songs es ON es.id = e.song_id
+        WHERE e.playlist_id = p.id AND es.deleted_at IS NULL) AS track_count,
+    p.created_at, p.updated_at`
+
+func scanPlaylist(row rowScanner, playlist *models.Playlist) error {
+    return row.Scan(
+        &playlist.ID,
+        &playlist.Name,
+        &playlist.Description,
+        &playlist.TrackCount,
+        &playlist.CreatedAt,
+        &playlist.UpdatedAt,
+    )
+}
+
+type PlaylistRepository struct {
+    db *sql.DB
+}
+
+func NewPlaylistRepository(db *sql.DB) *PlaylistRepository {
+    return &PlaylistRepository{db: db}
+}
+
+func (r *PlaylistRepository) Create(playlist *models.Playlist) error {
+    err := r.db.QueryRow(`
+        INSERT INTO playlists (name, description)
+        VALUES ($1, $2)
+        RETURNING id, created_at, updated_at`,
+        playlist.Name, playlist.Description,
+    ).Scan(&playlist.ID, &playlist.CreatedAt, &playlist.UpdatedAt)
+    return translateError(err)
+}
+
+// Update renames a playlist and replaces its description.
+func (r *PlaylistRepository) Update(playlist *models.Playlist) error {
+    err := scanPlaylist(r.db.QueryRow(`
+        UPDATE playlists p
+        SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+        WHERE p.id = $3
+        RETURNING `+playlistColumns,
+        playlist.Name, playlist.Description, playlist.ID,
+    ), playlist)
+    if errors.Is(err, sql.ErrNoRows) {
+        return playlistNotFound(playlist.ID)
+    }
+    return translateError(err)
+}
+
+func (r *PlaylistRepository) Delete(id int) error {
+    result, err := r.db.Exec("DELETE FROM playlists WHERE id = $1", id)
+    if err != nil {
+        return translateError(err)
+    }
+
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return playlistNotFound(id)
+    }
+    return nil
+}
+
+func (r *PlaylistRepository) GetByID(id int) (*models.Playlist, error) {
+    playlist := &models.Playlist{}
+    err := scanPlaylist(r.db.QueryRow(`
+        SELECT `+playlistColumns+`
+        FROM playlists p
+        WHERE p.id = $1`, id), playlist)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, playlistNotFound(id)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return playlist, nil
+}
+
+func (r *PlaylistRepository) List(filter *models.PlaylistFilter) ([]models.Playlist, error) {
+    rows, err := r.db.Query(`
+        SELECT `+playlistColumns+`
+        FROM playlists p
+        ORDER BY p.name, p.id
+        LIMIT $1 OFFSET $2`,
+        filter.PageSize, (filter.Page-1)*filter.PageSize,
+    )
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var playlists []models.Playlist
+    for rows.Next() {
+        var playlist models.Playlist
+        if err := scanPlaylist(rows, &playlist); err != nil {
+            return nil, err
+        }
+        playlists = append(playlists, playlist)
+    }
+    return playlists, rows.Err()
+}
+
+func (r *PlaylistRepository) Count(filter *models.PlaylistFilter) (int, error) {
+    var total int
+    if err := r.db.QueryRow("SELECT COUNT(*) FROM playlists").Scan(&total); err != nil {
+        return 0, translateError(err)
+    }
+    return total, nil
+}
+
+// Entries returns limit entries of the playlist starting after offset, in
+// playlist order. Positions are ordinals, so gaps left by deleted songs
+// and songs in the trash do not show.
+func (r *PlaylistRepository) Entries(id int, offset, limit int) ([]models.PlaylistEntry, error) {
+    rows, err := r.db.Query(`
+        SELECT `+songColumns+`, e.id, e.ordinal, e.added_at
+        FROM (
+            SELECT pe.id, pe.song_id, pe.added_at, ROW_NUMBER() OVER (ORDER BY pe.position, pe.id) AS ordinal
+            FROM playlist_entries pe
+            JOIN songs ps ON ps.id = pe.song_id
+            WHERE pe.playlist_id = $1 AND ps.deleted_at IS NULL
+        ) AS e
+        JOIN `+songFrom+` ON s.id = e.song_id
+        ORDER BY e.ordinal
+        LIMIT $2 OFFSET $3`,
+        id, limit, offset,
+    )
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var entries []models.PlaylistEntry
+    for rows.Next() {
+        var entry models.PlaylistEntry
+        if err := scanSong(rows, &entry.Song, &entry.ID, &entry.Position, &entry.AddedAt); err != nil {
+            return nil, err
+        }
+        entries = append(entries, entry)
+    }
+    return entries, rows.Err()
+}
+
+// AddEntry inserts songID at position, or appends it when position is 0.
+func (r *PlaylistRepository) AddEntry(id int, songID int, position int) (*models.PlaylistEntry, error) {
+    var entry *models.PlaylistEntry
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        entryIDs, hidden, err := lockPlaylistEntries(tx, id)
+        if err != nil {
+            return err
+        }
+        visible := visibleEntries(entryIDs, hidden)
+        if position == 0 {
+            position = len(visible) + 1
+        }
+        if position < 1 || position > len(visible)+1 {
+            return positionOutOfRange("position", len(visible)+1)
+        }
+
+        var exists bool
+        if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM songs WHERE id = $1 AND deleted_at IS NULL)", songID).Scan(&exists); err != nil {
+            return translateError(err)
+        }
+        if !exists {
+            return &models.ValidationError{Field: "song_id", Message: "does not name an existing song"}
+        }
+
+        var entryID int
+        err = tx.QueryRow(`
+            INSERT INTO playlist_entries (playlist_id, song_id, position)
+            SELECT $1, $2, COALESCE(MAX(position), 0) + 1
+            FROM playlist_entries
+            WHERE playlist_id = $1
+            RETURNING id`,
+            id, songID,
+        ).Scan(&entryID)
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := renumberEntries(tx, id, mergeHidden(entryIDs, hidden, insertEntry(visible, position, entryID))); err != nil {
+            return err
+        }
+        entry, err = playlistEntry(tx, id, entryID, position)
+        return err
+    })
+    return entry, err
+}
+
+func (r *PlaylistRepository) RemoveEntry(id int, position int) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        entryIDs, hidden, err := lockPlaylistEntries(tx, id)
+        if err != nil {
+            return err
+        }
+        visible := visibleEntries(entryIDs, hidden)
+        if position < 1 || position > len(visible) {
+            return positionOutOfRange("position", len(visible))
+        }
+
+        if _, err := tx.Exec("DELETE FROM playlist_entries WHERE id = $1", visible[position-1]); err != nil {
+            return translateError(err)
+        }
+        visible = append(visible[:position-1], visible[position:]...)
+        return renumberEntries(tx, id, mergeHidden(entryIDs, hidden, visible))
+    })
+}
+
+func (r *PlaylistRepository) MoveEntry(id int, from, to int) (*models.PlaylistEntry, error) {
+    var entry *models.PlaylistEntry
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        entryIDs, hidden, err := lockPlaylistEntries(tx, id)
+        if err != nil {
+            return err
+        }
+        visible := visibleEntries(entryIDs, hidden)
+        if from < 1 || from > len(visible) {
+            return positionOutOfRange("from", len(visible))
+        }
+        if to < 1 || to > len(visible) {
+            return positionOutOfRange("to", len(visible))
+        }
+
+        entryID := visible[from-1]
+        if err := renumberEntries(tx, id, mergeHidden(entryIDs, hidden, moveEntry(visible, from, to))); err != nil {
+            return err
+        }
+        entry, err = playlistEntry(tx, id, entryID, to)
+        return err
+    })
+    return entry, err
+}
+
+// lockPlaylistEntries locks the playlist against concurrent changes and
+// returns its entry ids in order, together with the set of those whose song
+// is in the trash.
+func lockPlaylistEntries(tx *sql.Tx, id int) ([]int, map[int]bool, error) {
+    err := tx.QueryRow("SELECT id FROM playlists WHERE id = $1 FOR UPDATE", id).Scan(&id)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, nil, playlistNotFound(id)
+    }
+    if err != nil {
+        return nil, nil, translateError(err)
+    }
+
+    rows, err := tx.Query(`
+        SELECT e.id, s.deleted_at IS NOT NULL
+        FROM playlist_entries e
+        JOIN songs s ON s.id = e.song_id
+        WHERE e.playlist_id = $1
+        ORDER BY e.position, e.id`, id)
+    if err != nil {
+        return nil, nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var ids []int
+    hidden := make(map[int]bool)
+    for rows.Next() {
+        var entryID int
+        var trashed bool
+        if err := rows.Scan(&entryID, &trashed); err != nil {
+            return nil, nil, err
+        }
+        ids = append(ids, entryID)
+        hidden[entryID] = trashed
+    }
+    return ids, hidden, rows.Err()
+}
+
+// renumberEntries stores entryIDs as the playlist order, numbering from 1,
+// and touches the playlist's updated_at.
+func renumberEntries(tx *sql.Tx, id int, entryIDs []int) error {
+    _, err := tx.Exec(`
+        UPDATE playlist_entries e
+        SET position = o.position
+        FROM unnest($2::int[]) WITH ORDINALITY AS o (entry_id, position)
+        WHERE e.id = o.entry_id AND e.playlist_id = $1`,
+        id, pq.Array(entryIDs),
+    )
+    if err != nil {
+        return translateError(err)
+    }
+
+    _, err = tx.Exec("UPDATE playlists SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+    return translateError(err)
+}
+
+// playlistEntry reads one entry; position is its place among the visible
+// entries.
+func playlistEntry(q querier, id int, entryID int, position int) (*models.PlaylistEntry, error) {
+    entry := &models.PlaylistEntry{Position: position}
+    err := scanSong(q.QueryRow(`
+        SELECT `+songColumns+`, e.id, e.added_at
+        FROM playlist_entries e
+        JOIN `+songFrom+` ON s.id = e.song_id
+        WHERE e.playlist_id = $1 AND e.id = $2`,
+        id, entryID,
+    ), &entry.Song, &entry.ID, &entry.AddedAt)
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return entry, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "strconv"
+)
+
+// PlaylistStore is the persistence contract for playlists. Entry positions
+// are 1-based; an out-of-range position returns models.ErrValidation.
+// Entries of songs in the trash are hidden: they are not listed, counted or
+// addressed by position, and keep their place for when the song is
+// restored.
+type PlaylistStore interface {
+    Create(playlist *models.Playlist) error
+    Update(playlist *models.Playlist) error
+    Delete(id int) error
+    GetByID(id int) (*models.Playlist, error)
+    List(filter *models.PlaylistFilter) ([]models.Playlist, error)
+    Count(filter *models.PlaylistFilter) (int, error)
+    Entries(id int, offset, limit int) ([]models.PlaylistEntry, error)
+    AddEntry(id int, songID int, position int) (*models.PlaylistEntry, error)
+    RemoveEntry(id int, position int) error
+    MoveEntry(id int, from, to int) (*models.PlaylistEntry, error)
+}
+
+var (
+    _ PlaylistStore = (*PlaylistRepository)(nil)
+    _ PlaylistStore = (*MemoryPlaylistRepository)(nil)
+)
+
+// positionOutOfRange reports a position outside 1..max.
+func positionOutOfRange(field string, max int) error {
+    if max == 0 {
+        return &models.ValidationError{Field: field, Message: "is out of range, the playlist is empty"}
+    }
+    return &models.ValidationError{Field: field, Message: "must be between 1 and " + strconv.Itoa(max)}
+}
+
+// moveEntry moves the entry id at position from to position to, both
+// 1-based and already range checked.
+func moveEntry(entryIDs []int, from, to int) []int {
+    id := entryIDs[from-1]
+    entryIDs = append(entryIDs[:from-1], entryIDs[from:]...)
+    return insertEntry(entryIDs, to, id)
+}
+
+// visibleEntries drops the hidden ids, those of songs in the trash, leaving
+// the order that clients see and address by position.
+func visibleEntries(ids []int, hidden map[int]bool) []int {
+    visible := make([]int, 0, len(ids))
+    for _, id := range ids {
+        if !hidden[id] {
+            visible = append(visible, id)
+        }
+    }
+    return visible
+}
+
+// mergeHidden rebuilds the full order of ids after the visible part was
+// changed to visible. Hidden ids keep their slots and the other slots are
+// filled from visible in turn; ids left over go at the end, and slots left
+// over are dropped. A song restored from the trash thus comes back where it
+// was.
+func mergeHidden(ids []int, hidden map[int]bool, visible []int) []int {
+    merged := make([]int, 0, len(ids)+1)
+    next := 0
+    for _, id := range ids {
+        switch {
+        case hidden[id]:
+            merged = append(merged, id)
+        case next < len(visible):
+            merged = append(merged, visible[next])
+            next++
+        }
+    }
+    return append(merged, visible[next:]...)
+}
+
+// insertEntry inserts id so that it ends up at the 1-based position.
+func insertEntry(entryIDs []int, position int, id int) []int {
+    entryIDs = append(entryIDs, 0)
+    copy(entryIDs[position:], entryIDs[position-1:])
+    entryIDs[position-1] = id
+    return entryIDs
+}
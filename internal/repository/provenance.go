@@ -0,0 +1,179 @@
+package repository
+
+import (
+    "database/sql"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+    "time"
+)
+
+// enrichmentSource names the provider that supplied field of detail.
+func enrichmentSource(detail *models.SongDetail, field string) string {
+    if source := detail.Sources[field]; source != "" {
+        return source
+    }
+    return "enrichment"
+}
+
+// isProviderSource reports whether source names a song info provider.
+func isProviderSource(source string) bool {
+    return source != "" && source != models.SourceUser && source != models.SourceImport
+}
+
+// recordProvenance marks fields of a song as last set by source.
+func recordProvenance(q querier, songID int, fields []string, source string) error {
+    if len(fields) == 0 {
+        return nil
+    }
+    _, err := q.Exec(`
+        INSERT INTO song_field_provenance (song_id, field, source)
+        SELECT $1, field, $3 FROM unnest($2::text[]) AS f (field)
+        ON CONFLICT (song_id, field) DO UPDATE
+        SET source = EXCLUDED.source, updated_at = CURRENT_TIMESTAMP`,
+        songID, pq.Array(fields), source)
+    return translateError(err)
+}
+
+// keptFields returns the enrichable fields of song that enrichment must
+// leave alone: locked ones, and ones holding a value a user or an import
+// supplied. Empty fields are filled in unless locked.
+func keptFields(provenance map[string]models.FieldProvenance, song *models.Song) map[string]bool {
+    empty := map[string]bool{
+        models.FieldReleaseDate: song.ReleaseDate.IsZero(),
+        models.FieldText:        song.Text == "",
+        models.FieldLink:        song.Link == "",
+    }
+
+    kept := make(map[string]bool)
+    for _, field := range models.EnrichableFields {
+        entry := provenance[field]
+        if entry.Locked || (!empty[field] && entry.Source != "" && !isProviderSource(entry.Source)) {
+            kept[field] = true
+        }
+    }
+    return kept
+}
+
+// getProvenance reads the provenance of a song. Without includeDeleted a
+// song in the trash is not found.
+func getProvenance(q querier, songID int, includeDeleted bool) (*models.SongProvenance, error) {
+    var exists bool
+    err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM songs WHERE id = $1 AND ($2 OR deleted_at IS NULL))", songID, includeDeleted).Scan(&exists)
+    if err != nil {
+        return nil, translateError(err)
+    }
+    if !exists {
+        return nil, songNotFound(songID)
+    }
+
+    rows, err := q.Query(`
+        SELECT field, source, locked, updated_at
+        FROM song_field_provenance
+        WHERE song_id = $1`, songID)
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    provenance := &models.SongProvenance{SongID: songID, Fields: make(map[string]models.FieldProvenance)}
+    for rows.Next() {
+        var field string
+        var entry models.FieldProvenance
+        if err := rows.Scan(&field, &entry.Source, &entry.Locked, &entry.UpdatedAt); err != nil {
+            return nil, err
+        }
+        provenance.Fields[field] = entry
+    }
+    return provenance, rows.Err()
+}
+
+func (r *SongRepository) Provenance(id int, includeDeleted bool) (*models.SongProvenance, error) {
+    return getProvenance(r.db, id, includeDeleted)
+}
+
+// SetFieldLock locks or unlocks one field of a song against enrichment.
+// Songs in the trash are not found.
+func (r *SongRepository) SetFieldLock(id int, field string, locked bool) (*models.SongProvenance, error) {
+    var provenance *models.SongProvenance
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        song, err := lockSong(tx, id)
+        if err != nil {
+            return err
+        }
+        if song.DeletedAt != nil {
+            return songNotFound(id)
+        }
+
+        _, err = tx.Exec(`
+            INSERT INTO song_field_provenance (song_id, field, locked)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (song_id, field) DO UPDATE
+            SET locked = EXCLUDED.locked`, id, field, locked)
+        if err != nil {
+            return translateError(err)
+        }
+
+        provenance, err = getProvenance(tx, id, false)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return provenance, nil
+}
+
+// recordProvenance is the in-memory counterpart of recordProvenance. Callers
+// must hold the write lock.
+func (db *MemoryDB) recordProvenance(songID int, fields []string, source string) {
+    if len(fields) == 0 {
+        return
+    }
+    if db.provenance[songID] == nil {
+        db.provenance[songID] = make(map[string]models.FieldProvenance)
+    }
+    now := time.Now()
+    for _, field := range fields {
+        entry := db.provenance[songID][field]
+        entry.Source = source
+        entry.UpdatedAt = now
+        db.provenance[songID][field] = entry
+    }
+}
+
+// songProvenance copies the provenance of a song. Callers must hold the lock.
+func (db *MemoryDB) songProvenance(songID int) *models.SongProvenance {
+    provenance := &models.SongProvenance{SongID: songID, Fields: make(map[string]models.FieldProvenance)}
+    for field, entry := range db.provenance[songID] {
+        provenance.Fields[field] = entry
+    }
+    return provenance
+}
+
+func (r *MemorySongRepository) Provenance(id int, includeDeleted bool) (*models.SongProvenance, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    if song, ok := r.db.songs[id]; !ok || (song.DeletedAt != nil && !includeDeleted) {
+        return nil, songNotFound(id)
+    }
+    return r.db.songProvenance(id), nil
+}
+
+func (r *MemorySongRepository) SetFieldLock(id int, field string, locked bool) (*models.SongProvenance, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    if song, ok := r.db.songs[id]; !ok || song.DeletedAt != nil {
+        return nil, songNotFound(id)
+    }
+    if r.db.provenance[id] == nil {
+        r.db.provenance[id] = make(map[string]models.FieldProvenance)
+    }
+    entry, ok := r.db.provenance[id][field]
+    if !ok {
+        entry.UpdatedAt = time.Now()
+    }
+    entry.Locked = locked
+    r.db.provenance[id][field] = entry
+    return r.db.songProvenance(id), nil
+}
@@ -0,0 +1,375 @@
+package repository
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+    "time"
+)
+
+// insertRevision appends rev to the history of its song and sets its
+// number and creation time. It must run in the transaction that made the
+// change.
+func insertRevision(q querier, rev *models.SongRevision) error {
+    changes := rev.Changes
+    if changes == nil {
+        changes = []models.FieldChange{}
+    }
+    changesJSON, err := json.Marshal(changes)
+    if err != nil {
+        return err
+    }
+    songJSON, err := json.Marshal(rev.Song)
+    if err != nil {
+        return err
+    }
+
+    err = q.QueryRow(`
+        INSERT INTO song_revisions (song_id, revision, action, actor, version, restored_from, changes, song)
+        SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, NULLIF($5, 0), $6, $7
+        FROM song_revisions
+        WHERE song_id = $1
+        RETURNING revision, created_at`,
+        rev.SongID, rev.Action, rev.Actor, rev.Version, rev.RestoredFrom, string(changesJSON), string(songJSON),
+    ).Scan(&rev.Revision, &rev.CreatedAt)
+    return translateError(err)
+}
+
+// revisionSummaryColumns are the columns scanned by scanRevisionSummary.
+const revisionSummaryColumns = `r.song_id, r.revision, r.action, r.actor, r.version, COALESCE(r.restored_from, 0), r.created_at,
+    ARRAY(SELECT c->>'field' FROM jsonb_array_elements(r.changes) c)`
+
+func scanRevisionSummary(row rowScanner, rev *models.SongRevision, extra ...interface{}) error {
+    dest := []interface{}{
+        &rev.SongID,
+        &rev.Revision,
+        &rev.Action,
+        &rev.Actor,
+        &rev.Version,
+        &rev.RestoredFrom,
+        &rev.CreatedAt,
+        pq.Array(&rev.Fields),
+    }
+    return row.Scan(append(dest, extra...)...)
+}
+
+// Revisions returns one page of a song's revisions, newest first, and the
+// total number of revisions. The history of a song in the trash, or purged,
+// is only available with includeDeleted.
+func (r *SongRepository) Revisions(id int, filter *models.RevisionFilter, includeDeleted bool) ([]models.SongRevision, int, error) {
+    if !includeDeleted {
+        if _, err := r.GetByID(id, false); err != nil {
+            return nil, 0, err
+        }
+    }
+
+    var total int
+    err := r.db.QueryRow("SELECT COUNT(*) FROM song_revisions WHERE song_id = $1", id).Scan(&total)
+    if err != nil {
+        return nil, 0, translateError(err)
+    }
+    if total == 0 {
+        if _, err := getSong(r.db, id); err != nil {
+            return nil, 0, err
+        }
+        return nil, 0, nil
+    }
+
+    rows, err := r.db.Query(`
+        SELECT `+revisionSummaryColumns+`
+        FROM song_revisions r
+        WHERE r.song_id = $1
+        ORDER BY r.revision DESC
+        LIMIT $2 OFFSET $3`,
+        id, filter.PageSize, (filter.Page-1)*filter.PageSize)
+    if err != nil {
+        return nil, 0, translateError(err)
+    }
+    defer rows.Close()
+
+    var revisions []models.SongRevision
+    for rows.Next() {
+        var rev models.SongRevision
+        if err := scanRevisionSummary(rows, &rev); err != nil {
+            return nil, 0, err
+        }
+        revisions = append(revisions, rev)
+    }
+    return revisions, total, rows.Err()
+}
+
+func (r *SongRepository) Revision(id int, revision int, includeDeleted bool) (*models.SongRevision, error) {
+    if !includeDeleted {
+        if _, err := r.GetByID(id, false); err != nil {
+            return nil, err
+        }
+    }
+    return getRevision(r.db, id, revision)
+}
+
+func getRevision(q querier, id int, revision int) (*models.SongRevision, error) {
+    rev := &models.SongRevision{}
+    var changes, song []byte
+    err := scanRevisionSummary(q.QueryRow(`
+        SELECT `+revisionSummaryColumns+`, r.changes, r.song
+        FROM song_revisions r
+        WHERE r.song_id = $1 AND r.revision = $2`, id, revision), rev, &changes, &song)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, revisionNotFound(id, revision)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+
+    if err := json.Unmarshal(changes, &rev.Changes); err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(song, &rev.Song); err != nil {
+        return nil, err
+    }
+    return rev, nil
+}
+
+// RestoreRevision sets the fields of a song back to how revision left
+// them, recording a restore revision. A song in the trash is taken out of
+// it, and a purged one is recreated under its old id. Restoring a song to
+// the state it is already in changes nothing.
+func (r *SongRepository) RestoreRevision(id int, revision int, expectedVersion int, actor string) (*models.Song, error) {
+    var song *models.Song
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        rev, err := getRevision(tx, id, revision)
+        if err != nil {
+            return err
+        }
+
+        old, err := lockSong(tx, id)
+        if errors.Is(err, models.ErrNotFound) {
+            if expectedVersion != 0 {
+                return versionMismatch(id)
+            }
+            song, err = recreateSong(tx, rev, actor)
+            return err
+        }
+        if err != nil {
+            return err
+        }
+        if expectedVersion != 0 && old.Version != expectedVersion {
+            return versionMismatch(id)
+        }
+
+        restored := *old
+        restored.CopyContent(rev.Song)
+        restored.DeletedAt = nil
+        if err := ensureArtist(tx, &restored); err != nil {
+            return err
+        }
+        fields := restored.ChangedFields(old)
+        if len(fields) == 0 && old.DeletedAt == nil {
+            song = old
+            return nil
+        }
+
+        err = tx.QueryRow(`
+            UPDATE songs
+            SET artist_id = $2, song_name = $3, release_date = $4, text = $5, link = $6, language = $7,
+                deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1
+            RETURNING version, updated_at`,
+            id, restored.ArtistID, restored.SongName, restored.ReleaseDate, restored.Text, restored.Link, restored.Language,
+        ).Scan(&restored.Version, &restored.UpdatedAt)
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := recordProvenance(tx, id, fields, models.SourceUser); err != nil {
+            return err
+        }
+        change := models.NewSongRevision(models.RevisionRestore, actor, old, &restored)
+        change.RestoredFrom = revision
+        if err := insertRevision(tx, change); err != nil {
+            return err
+        }
+        song = &restored
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return song, nil
+}
+
+// recreateSong inserts a purged song again as rev left it. Its version
+// continues from the last one in its history.
+func recreateSong(tx *sql.Tx, rev *models.SongRevision, actor string) (*models.Song, error) {
+    song := *rev.Song
+    song.ID = rev.SongID
+    song.DeletedAt = nil
+    if err := ensureArtist(tx, &song); err != nil {
+        return nil, err
+    }
+
+    err := tx.QueryRow(`
+        INSERT INTO songs (id, artist_id, song_name, release_date, text, link, language, enrichment_status, version, created_at)
+        SELECT $1, $2, $3, $4, $5, $6, $7, $8, MAX(version) + 1, $9
+        FROM song_revisions
+        WHERE song_id = $1
+        RETURNING version, updated_at`,
+        song.ID, song.ArtistID, song.SongName, song.ReleaseDate, song.Text, song.Link, song.Language,
+        song.EnrichmentStatus, song.CreatedAt,
+    ).Scan(&song.Version, &song.UpdatedAt)
+    if err != nil {
+        return nil, translateError(err)
+    }
+
+    if err := recordProvenance(tx, song.ID, song.CreatedFields(), models.SourceUser); err != nil {
+        return nil, err
+    }
+    if song.EnrichmentStatus == models.EnrichmentPending {
+        if _, err := tx.Exec("INSERT INTO enrichment_jobs (song_id) VALUES ($1)", song.ID); err != nil {
+            return nil, translateError(err)
+        }
+    }
+
+    change := models.NewSongRevision(models.RevisionRestore, actor, nil, &song)
+    change.RestoredFrom = rev.Revision
+    if err := insertRevision(tx, change); err != nil {
+        return nil, err
+    }
+    return &song, nil
+}
+
+// recordRevision is the in-memory counterpart of insertRevision. Callers
+// must hold the write lock.
+func (db *MemoryDB) recordRevision(rev *models.SongRevision) {
+    rev.Revision = len(db.revisions[rev.SongID]) + 1
+    rev.CreatedAt = time.Now()
+    db.revisions[rev.SongID] = append(db.revisions[rev.SongID], *rev)
+}
+
+func (r *MemorySongRepository) Revisions(id int, filter *models.RevisionFilter, includeDeleted bool) ([]models.SongRevision, int, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    if !includeDeleted && !r.db.isLive(id) {
+        return nil, 0, songNotFound(id)
+    }
+
+    history := r.db.revisions[id]
+    if len(history) == 0 {
+        if _, ok := r.db.songs[id]; !ok {
+            return nil, 0, songNotFound(id)
+        }
+        return nil, 0, nil
+    }
+
+    var revisions []models.SongRevision
+    offset := (filter.Page - 1) * filter.PageSize
+    for i := len(history) - 1 - offset; i >= 0 && len(revisions) < filter.PageSize; i-- {
+        rev := history[i]
+        rev.Changes = nil
+        rev.Song = nil
+        revisions = append(revisions, rev)
+    }
+    return revisions, len(history), nil
+}
+
+func (r *MemorySongRepository) Revision(id int, revision int, includeDeleted bool) (*models.SongRevision, error) {
+    r.db.mu.RLock()
+    defer r.db.mu.RUnlock()
+
+    if !includeDeleted && !r.db.isLive(id) {
+        return nil, songNotFound(id)
+    }
+
+    return r.db.revision(id, revision)
+}
+
+// isLive reports whether a song exists outside the trash. Callers must hold
+// the lock.
+func (db *MemoryDB) isLive(id int) bool {
+    song, ok := db.songs[id]
+    return ok && song.DeletedAt == nil
+}
+
+// revision copies one revision of a song. Callers must hold the lock.
+func (db *MemoryDB) revision(id int, revision int) (*models.SongRevision, error) {
+    history := db.revisions[id]
+    if revision < 1 || revision > len(history) {
+        return nil, revisionNotFound(id, revision)
+    }
+    rev := history[revision-1]
+    song := *rev.Song
+    rev.Song = &song
+    return &rev, nil
+}
+
+func (r *MemorySongRepository) RestoreRevision(id int, revision int, expectedVersion int, actor string) (*models.Song, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    rev, err := r.db.revision(id, revision)
+    if err != nil {
+        return nil, err
+    }
+
+    old, ok := r.db.songs[id]
+    if !ok {
+        if expectedVersion != 0 {
+            return nil, versionMismatch(id)
+        }
+        return r.recreateSong(rev, actor)
+    }
+    if expectedVersion != 0 && old.Version != expectedVersion {
+        return nil, versionMismatch(id)
+    }
+
+    restored := old
+    restored.CopyContent(rev.Song)
+    restored.DeletedAt = nil
+    if err := r.db.ensureArtist(&restored); err != nil {
+        return nil, err
+    }
+    fields := restored.ChangedFields(&old)
+    if len(fields) == 0 && old.DeletedAt == nil {
+        return &old, nil
+    }
+
+    restored.Version++
+    restored.UpdatedAt = time.Now()
+    r.db.songs[id] = restored
+    r.db.recordProvenance(id, fields, models.SourceUser)
+    change := models.NewSongRevision(models.RevisionRestore, actor, &old, &restored)
+    change.RestoredFrom = revision
+    r.db.recordRevision(change)
+    return &restored, nil
+}
+
+// recreateSong mirrors the Postgres recreateSong. Callers must hold the
+// write lock.
+func (r *MemorySongRepository) recreateSong(rev *models.SongRevision, actor string) (*models.Song, error) {
+    song := *rev.Song
+    song.ID = rev.SongID
+    song.DeletedAt = nil
+    if err := r.db.ensureArtist(&song); err != nil {
+        return nil, err
+    }
+
+    history := r.db.revisions[song.ID]
+    for _, earlier := range history {
+        song.Version = max(song.Version, earlier.Version)
+    }
+    song.Version++
+    song.UpdatedAt = time.Now()
+    r.db.songs[song.ID] = song
+    r.db.recordProvenance(song.ID, song.CreatedFields(), models.SourceUser)
+    if song.EnrichmentStatus == models.EnrichmentPending {
+        r.db.enqueueEnrichment(song.ID, song.UpdatedAt, false)
+    }
+
+    change := models.NewSongRevision(models.RevisionRestore, actor, nil, &song)
+    change.RestoredFrom = rev.Revision
+    r.db.recordRevision(change)
+    return &song, nil
+}
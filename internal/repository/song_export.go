@@ -0,0 +1,104 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "music-library/internal/models"
+    "sort"
+    "strconv"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor at a
+// time.
+const exportFetchSize = 500
+
+// Export calls fn with every song matching filter, ordered by
+// filter.SortKeys and then id. Rows are read through a server-side cursor
+// in a read-only transaction, so only exportFetchSize rows are in memory at
+// once and the export sees a single snapshot. An error from fn stops the
+// export and is returned.
+func (r *SongRepository) Export(filter *models.SongFilter, fn func(song *models.Song) error) error {
+    tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+    if err != nil {
+        return translateError(err)
+    }
+    defer tx.Rollback()
+
+    if filter.Fuzzy {
+        threshold := strconv.FormatFloat(models.FuzzyThreshold, 'f', -1, 64)
+        if _, err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', $1, true)", threshold); err != nil {
+            return translateError(err)
+        }
+    }
+
+    where, args := songFilterWhere(filter)
+    _, err = tx.Exec(`
+        DECLARE songs_export NO SCROLL CURSOR FOR
+        SELECT `+songColumns+`
+        FROM `+songFrom+`
+        `+where+`
+        ORDER BY `+orderByClause(filter.SortKeys, false), args...)
+    if err != nil {
+        return translateError(err)
+    }
+
+    fetch := "FETCH FORWARD " + strconv.Itoa(exportFetchSize) + " FROM songs_export"
+    for {
+        n, err := fetchSongs(tx, fetch, fn)
+        if err != nil {
+            return err
+        }
+        if n < exportFetchSize {
+            break
+        }
+    }
+
+    return translateError(tx.Commit())
+}
+
+// fetchSongs runs one FETCH and passes the rows to fn, returning how many
+// there were.
+func fetchSongs(tx *sql.Tx, fetch string, fn func(song *models.Song) error) (int, error) {
+    rows, err := tx.Query(fetch)
+    if err != nil {
+        return 0, translateError(err)
+    }
+    defer rows.Close()
+
+    n := 0
+    for rows.Next() {
+        var song models.Song
+        if err := scanSong(rows, &song); err != nil {
+            return n, err
+        }
+        n++
+        if err := fn(&song); err != nil {
+            return n, err
+        }
+    }
+    return n, rows.Err()
+}
+
+// Export mirrors SongRepository.Export. It works on a copy of the matching
+// songs, so fn may be slow without holding up writers.
+func (r *MemorySongRepository) Export(filter *models.SongFilter, fn func(song *models.Song) error) error {
+    r.db.mu.RLock()
+    matched, err := r.match(filter)
+    r.db.mu.RUnlock()
+    if err != nil {
+        return err
+    }
+
+    if filter.Fuzzy && len(filter.SortKeys) == 0 {
+        // Like the SQL export, fuzzy matches are not ranked.
+        sort.Slice(matched, func(i, j int) bool {
+            return matched[i].ID < matched[j].ID
+        })
+    }
+    for i := range matched {
+        if err := fn(&matched[i]); err != nil {
+            return err
+        }
+    }
+    return nil
+}
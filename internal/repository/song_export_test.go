@@ -0,0 +1,118 @@
+package repository_test
+
+import (
+    "errors"
+    "fmt"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "testing"
+)
+
+func TestExportStreamsEverySongInOrder(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    // More songs than one FETCH from the cursor returns.
+    songs := make([]models.Song, 1234)
+    for i := range songs {
+        songs[i] = newTestSong("Muse", fmt.Sprintf("Song %04d", i))
+    }
+    songs = append(songs, newTestSong("Blur", "Song 2"))
+    if _, err := repo.Import(songs, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+
+    sort, err := models.ParseSongSort("-song")
+    if err != nil {
+        t.Fatalf("ParseSongSort: %v", err)
+    }
+    filter := &models.SongFilter{GroupName: "Muse", SortKeys: sort}
+    var exported []models.Song
+    err = repo.Export(filter, func(song *models.Song) error {
+        exported = append(exported, *song)
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("Export: %v", err)
+    }
+
+    if len(exported) != len(songs)-1 {
+        t.Fatalf("exported %d songs, want %d", len(exported), len(songs)-1)
+    }
+    for i := 1; i < len(exported); i++ {
+        if exported[i-1].SongName < exported[i].SongName {
+            t.Fatalf("%q exported before %q, want descending names", exported[i-1].SongName, exported[i].SongName)
+        }
+    }
+    for _, song := range exported {
+        if song.GroupName != "Muse" || song.Text == "" {
+            t.Fatalf("exported %+v, want a Muse song with its lyrics", song)
+        }
+    }
+}
+
+func TestExportSeesOneSnapshot(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    songs := []models.Song{newTestSong("Muse", "Uprising"), newTestSong("Muse", "Resistance")}
+    if _, err := repo.Import(songs, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+
+    count := 0
+    err := repo.Export(&models.SongFilter{}, func(song *models.Song) error {
+        if count == 0 {
+            // Written on another connection while the cursor is open.
+            late := newTestSong("Muse", "Undisclosed Desires")
+            if err := repo.Create(&late, models.ActorAnonymous); err != nil {
+                return err
+            }
+            if err := repo.Delete(songs[1].ID, 0, models.ActorAnonymous); err != nil {
+                return err
+            }
+        }
+        count++
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("Export: %v", err)
+    }
+    if count != 2 {
+        t.Errorf("exported %d songs, want the 2 there were when the export started", count)
+    }
+}
+
+func TestExportStopsOnCallbackError(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    songs := make([]models.Song, 20)
+    for i := range songs {
+        songs[i] = newTestSong("Muse", fmt.Sprintf("Song %d", i))
+    }
+    if _, err := repo.Import(songs, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+
+    stop := errors.New("stop")
+    count := 0
+    err := repo.Export(&models.SongFilter{}, func(song *models.Song) error {
+        count++
+        if count == 3 {
+            return stop
+        }
+        return nil
+    })
+    if !errors.Is(err, stop) {
+        t.Errorf("Export = %v, want the callback's error", err)
+    }
+    if count != 3 {
+        t.Errorf("callback ran %d times, want 3", count)
+    }
+
+    // The cursor's transaction was rolled back, freeing its connection.
+    if n := countRows(t, db, "songs"); n != len(songs) {
+        t.Errorf("songs = %d, want %d", n, len(songs))
+    }
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "github.com/lib/pq"
+    "music-library/internal/models"
+    "strings"
+    "time"
+)
+
+// importBatchSize is the number of rows sent per COPY statement.
+const importBatchSize = 1000
+
+// errDryRun rolls back a dry-run import once it has been checked.
+var errDryRun = errors.New("dry run")
+
+// songKey identifies a song for duplicate detection: the same artist and
+// the same name, ignoring case.
+type songKey struct {
+    artistID int
+    name     string
+}
+
+func songNameKey(name string) string {
+    return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Import stores songs in one transaction, creating their artists as needed.
+// Songs already in the library, or earlier in songs, are skipped as
+// duplicates; songs in the trash do not count. The rest are sent with COPY to
+// a staging table, which hands out their ids, and moved into songs with one
+// INSERT, so each created song gets the id of its own row even if a song with
+// the same name is created concurrently. Created songs get a create revision
+// naming actor. With dryRun everything is checked but rolled back.
+func (r *SongRepository) Import(songs []models.Song, dryRun bool, actor string) ([]models.ImportStatus, error) {
+    statuses := make([]models.ImportStatus, len(songs))
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        if err := importArtists(tx, songs); err != nil {
+            return err
+        }
+
+        existing, err := existingSongKeys(tx, songs)
+        if err != nil {
+            return err
+        }
+
+        var created []int
+        for i := range songs {
+            key := songKey{songs[i].ArtistID, songNameKey(songs[i].SongName)}
+            if existing[key] {
+                statuses[i] = models.ImportDuplicate
+                continue
+            }
+            existing[key] = true
+            statuses[i] = models.ImportCreated
+            created = append(created, i)
+        }
+        if len(created) == 0 {
+            return nil
+        }
+
+        if err := createImportTable(tx); err != nil {
+            return err
+        }
+        for start := 0; start < len(created); start += importBatchSize {
+            end := start + importBatchSize
+            if end > len(created) {
+                end = len(created)
+            }
+            if err := copySongs(tx, songs, created[start:end]); err != nil {
+                return err
+            }
+        }
+        if err := insertImportedSongs(tx, songs); err != nil {
+            return err
+        }
+
+        if dryRun {
+            return errDryRun
+        }
+        if err := recordImportProvenance(tx, songs, created); err != nil {
+            return err
+        }
+        return insertImportRevisions(tx, songs, created, actor)
+    })
+    if errors.Is(err, errDryRun) {
+        return statuses, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return statuses, nil
+}
+
+// importArtists upserts the artists named by songs in one statement and
+// points every song at its artist.
+func importArtists(tx *sql.Tx, songs []models.Song) error {
+    var names []string
+    seen := make(map[string]bool)
+    for _, song := range songs {
+        key := models.ArtistNameKey(song.GroupName)
+        if !seen[key] {
+            seen[key] = true
+            names = append(names, models.NormalizeArtistName(song.GroupName))
+        }
+    }
+
+    rows, err := tx.Query(`
+        INSERT INTO artists (name)
+        SELECT unnest($1::text[])
+        ON CONFLICT (name_key) DO UPDATE SET name = artists.name
+        RETURNING id, name`,
+        pq.Array(names))
+    if err != nil {
+        return translateError(err)
+    }
+    defer rows.Close()
+
+    artists := make(map[string]models.Artist)
+    for rows.Next() {
+        var artist models.Artist
+        if err := rows.Scan(&artist.ID, &artist.Name); err != nil {
+            return err
+        }
+        artists[models.ArtistNameKey(artist.Name)] = artist
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    for i := range songs {
+        artist, ok := artists[models.ArtistNameKey(songs[i].GroupName)]
+        if !ok {
+            return errors.New("artist missing after upsert: " + songs[i].GroupName)
+        }
+        songs[i].ArtistID = artist.ID
+        songs[i].GroupName = artist.Name
+    }
+    return nil
+}
+
+// existingSongKeys returns the keys of songs that are already stored
+// outside the trash.
+func existingSongKeys(tx *sql.Tx, songs []models.Song) (map[songKey]bool, error) {
+    artistIDs := make([]int, len(songs))
+    names := make([]string, len(songs))
+    for i, song := range songs {
+        artistIDs[i] = song.ArtistID
+        names[i] = songNameKey(song.SongName)
+    }
+
+    rows, err := tx.Query(`
+        SELECT DISTINCT s.artist_id, lower(btrim(s.song_name))
+        FROM songs s
+        JOIN unnest($1::int[], $2::text[]) AS k (artist_id, name)
+            ON s.artist_id = k.artist_id AND lower(btrim(s.song_name)) = k.name
+        WHERE s.deleted_at IS NULL`,
+        pq.Array(artistIDs), pq.Array(names))
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    existing := make(map[songKey]bool)
+    for rows.Next() {
+        var key songKey
+        if err := rows.Scan(&key.artistID, &key.name); err != nil {
+            return nil, err
+        }
+        existing[key] = true
+    }
+    return existing, rows.Err()
+}
+
+// createImportTable creates the staging table songs are copied into. Each
+// row takes its id from the songs sequence as it arrives; ord is its index
+// in the imported songs. The table is dropped with the transaction.
+func createImportTable(tx *sql.Tx) error {
+    _, err := tx.Exec(`
+        CREATE TEMPORARY TABLE import_songs (
+            ord INTEGER NOT NULL,
+            id INTEGER NOT NULL DEFAULT nextval(pg_get_serial_sequence('songs', 'id')),
+            artist_id INTEGER NOT NULL,
+            song_name TEXT NOT NULL,
+            release_date DATE,
+            text TEXT NOT NULL,
+            link TEXT NOT NULL,
+            language REGCONFIG NOT NULL
+        ) ON COMMIT DROP`)
+    return translateError(err)
+}
+
+// copySongs sends the songs at indexes to the staging table with a single
+// COPY.
+func copySongs(tx *sql.Tx, songs []models.Song, indexes []int) error {
+    stmt, err := tx.Prepare(pq.CopyIn("import_songs", "ord", "artist_id", "song_name", "release_date", "text", "link", "language"))
+    if err != nil {
+        return translateError(err)
+    }
+    defer stmt.Close()
+
+    for _, i := range indexes {
+        song := &songs[i]
+        if _, err := stmt.Exec(i, song.ArtistID, song.SongName, song.ReleaseDate, song.Text, song.Link, song.Language); err != nil {
+            return translateError(err)
+        }
+    }
+    if _, err := stmt.Exec(); err != nil {
+        return translateError(err)
+    }
+    return translateError(stmt.Close())
+}
+
+// insertImportedSongs moves the staged songs into songs and sets the id and
+// defaults of each from the row inserted for it, matched by id.
+func insertImportedSongs(tx *sql.Tx, songs []models.Song) error {
+    rows, err := tx.Query(`
+        WITH inserted AS (
+            INSERT INTO songs (id, artist_id, song_name, release_date, text, link, language)
+            SELECT id, artist_id, song_name, release_date, text, link, language
+            FROM import_songs
+            RETURNING id, version, created_at, updated_at, enrichment_status
+        )
+        SELECT i.ord, s.id, s.version, s.created_at, s.updated_at, s.enrichment_status
+        FROM inserted s
+        JOIN import_songs i ON i.id = s.id`)
+    if err != nil {
+        return translateError(err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var i int
+        var song models.Song
+        err := rows.Scan(&i, &song.ID, &song.Version, &song.CreatedAt, &song.UpdatedAt, &song.EnrichmentStatus)
+        if err != nil {
+            return err
+        }
+        songs[i].ID = song.ID
+        songs[i].Version = song.Version
+        songs[i].CreatedAt = song.CreatedAt
+        songs[i].UpdatedAt = song.UpdatedAt
+        songs[i].EnrichmentStatus = song.EnrichmentStatus
+    }
+    return rows.Err()
+}
+
+// insertImportRevisions records the create revision of each created song in
+// one statement.
+func insertImportRevisions(tx *sql.Tx, songs []models.Song, created []int, actor string) error {
+    if len(created) == 0 {
+        return nil
+    }
+
+    songIDs := make([]int, len(created))
+    changes := make([]string, len(created))
+    snapshots := make([]string, len(created))
+    for j, i := range created {
+        rev := models.NewSongRevision(models.RevisionCreate, actor, nil, &songs[i])
+        changesJSON, err := json.Marshal(rev.Changes)
+        if err != nil {
+            return err
+        }
+        songJSON, err := json.Marshal(rev.Song)
+        if err != nil {
+            return err
+        }
+        songIDs[j] = songs[i].ID
+        changes[j] = string(changesJSON)
+        snapshots[j] = string(songJSON)
+    }
+
+    _, err := tx.Exec(`
+        INSERT INTO song_revisions (song_id, revision, action, actor, version, changes, song)
+        SELECT r.song_id, 1, 'create', $4, 1, r.changes::jsonb, r.song::jsonb
+        FROM unnest($1::int[], $2::text[], $3::text[]) AS r (song_id, changes, song)`,
+        pq.Array(songIDs), pq.Array(changes), pq.Array(snapshots), actor)
+    return translateError(err)
+}
+
+// recordImportProvenance marks the fields of the created songs as imported.
+func recordImportProvenance(tx *sql.Tx, songs []models.Song, created []int) error {
+    var songIDs []int
+    var fields []string
+    for _, i := range created {
+        for _, field := range songs[i].CreatedFields() {
+            songIDs = append(songIDs, songs[i].ID)
+            fields = append(fields, field)
+        }
+    }
+    if len(songIDs) == 0 {
+        return nil
+    }
+
+    _, err := tx.Exec(`
+        INSERT INTO song_field_provenance (song_id, field, source)
+        SELECT song_id, field, $3 FROM unnest($1::int[], $2::text[]) AS p (song_id, field)`,
+        pq.Array(songIDs), pq.Array(fields), models.SourceImport)
+    return translateError(err)
+}
+
+// Import mirrors SongRepository.Import.
+func (r *MemorySongRepository) Import(songs []models.Song, dryRun bool, actor string) ([]models.ImportStatus, error) {
+    r.db.mu.Lock()
+    defer r.db.mu.Unlock()
+
+    existing := make(map[string]bool)
+    for _, song := range r.db.songs {
+        if song.DeletedAt != nil {
+            continue
+        }
+        existing[models.ArtistNameKey(song.GroupName)+"\x00"+songNameKey(song.SongName)] = true
+    }
+
+    statuses := make([]models.ImportStatus, len(songs))
+    for i := range songs {
+        song := &songs[i]
+        if artist, ok := r.db.artistByName(song.GroupName); ok {
+            song.ArtistID = artist.ID
+            song.GroupName = artist.Name
+        }
+        key := models.ArtistNameKey(song.GroupName) + "\x00" + songNameKey(song.SongName)
+        if existing[key] {
+            statuses[i] = models.ImportDuplicate
+            continue
+        }
+        existing[key] = true
+        statuses[i] = models.ImportCreated
+        if dryRun {
+            continue
+        }
+
+        if err := r.db.ensureArtist(song); err != nil {
+            return nil, err
+        }
+        now := time.Now()
+        song.ID = r.db.nextSongID
+        song.Version = 1
+        song.EnrichmentStatus = models.EnrichmentEnriched
+        song.CreatedAt = now
+        song.UpdatedAt = now
+        r.db.nextSongID++
+        r.db.songs[song.ID] = *song
+        r.db.recordProvenance(song.ID, song.CreatedFields(), models.SourceImport)
+        r.db.recordRevision(models.NewSongRevision(models.RevisionCreate, actor, nil, song))
+    }
+    return statuses, nil
+}
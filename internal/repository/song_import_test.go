@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+    "fmt"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "reflect"
+    "testing"
+)
+
+func TestImportDryRunRollsBack(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    songs := []models.Song{
+        newTestSong("Muse", "Uprising"),
+        newTestSong("Muse", "Resistance"),
+        newTestSong("muse", "uprising"),
+    }
+    statuses, err := repo.Import(songs, true, models.ActorAnonymous)
+    if err != nil {
+        t.Fatalf("Import dry run: %v", err)
+    }
+    want := []models.ImportStatus{models.ImportCreated, models.ImportCreated, models.ImportDuplicate}
+    if !reflect.DeepEqual(statuses, want) {
+        t.Errorf("dry run statuses = %v, want %v", statuses, want)
+    }
+
+    for _, table := range []string{"songs", "artists", "song_revisions", "song_field_provenance"} {
+        if n := countRows(t, db, table); n != 0 {
+            t.Errorf("%s has %d rows after a dry run, want 0", table, n)
+        }
+    }
+
+    // The staging table went with the rolled back transaction.
+    if _, err := repo.Import([]models.Song{newTestSong("Muse", "Uprising")}, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import after a dry run: %v", err)
+    }
+}
+
+func TestImportCopiesSongsThroughStaging(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    // More songs than one COPY batch holds.
+    songs := make([]models.Song, 2500)
+    for i := range songs {
+        songs[i] = newTestSong(fmt.Sprintf("Group %d", i%7), fmt.Sprintf("Song %d", i))
+    }
+    statuses, err := repo.Import(songs, false, models.ActorAnonymous)
+    if err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+
+    names := make(map[int]string)
+    rows, err := db.Query("SELECT id, song_name FROM songs")
+    if err != nil {
+        t.Fatalf("query songs: %v", err)
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var id int
+        var name string
+        if err := rows.Scan(&id, &name); err != nil {
+            t.Fatalf("scan song: %v", err)
+        }
+        names[id] = name
+    }
+    if err := rows.Err(); err != nil {
+        t.Fatalf("query songs: %v", err)
+    }
+
+    if len(names) != len(songs) {
+        t.Fatalf("stored %d songs, want %d", len(names), len(songs))
+    }
+    for i, song := range songs {
+        if statuses[i] != models.ImportCreated {
+            t.Fatalf("status of song %d = %s, want created", i, statuses[i])
+        }
+        if names[song.ID] != song.SongName {
+            t.Fatalf("song %q got id %d, which holds %q", song.SongName, song.ID, names[song.ID])
+        }
+        if song.Version != 1 || song.CreatedAt.IsZero() {
+            t.Fatalf("song %q = version %d, created %s; want the stored defaults", song.SongName, song.Version, song.CreatedAt)
+        }
+    }
+    if n := countRows(t, db, "artists"); n != 7 {
+        t.Errorf("artists = %d, want 7", n)
+    }
+    if n := countRows(t, db, "song_revisions"); n != len(songs) {
+        t.Errorf("revisions = %d, want one per song", n)
+    }
+}
+
+func TestImportSkipsDuplicatesOutsideTheTrash(t *testing.T) {
+    db := openTestDB(t)
+    repo := repository.NewSongRepository(db)
+
+    first := []models.Song{newTestSong("Muse", "Uprising"), newTestSong("Muse", "Resistance")}
+    if _, err := repo.Import(first, false, models.ActorAnonymous); err != nil {
+        t.Fatalf("Import: %v", err)
+    }
+    if err := repo.Delete(first[1].ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("Delete: %v", err)
+    }
+
+    again := []models.Song{newTestSong("MUSE", "uprising"), newTestSong("Muse", "Resistance")}
+    statuses, err := repo.Import(again, false, models.ActorAnonymous)
+    if err != nil {
+        t.Fatalf("Import again: %v", err)
+    }
+    want := []models.ImportStatus{models.ImportDuplicate, models.ImportCreated}
+    if !reflect.DeepEqual(statuses, want) {
+        t.Errorf("statuses = %v, want %v", statuses, want)
+    }
+    if again[1].ID == first[1].ID {
+        t.Errorf("re-imported song reused id %d of the song in the trash", again[1].ID)
+    }
+    if n := countRows(t, db, "songs"); n != 3 {
+        t.Errorf("songs = %d, want 3", n)
+    }
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "music-library/internal/models"
+)
+
+// SongInfoCacheStore is a cache of music info API lookups shared between
+// server instances.
+type SongInfoCacheStore interface {
+    // Get returns the unexpired entry for key, or models.ErrNotFound.
+    Get(key string) (*models.CachedSongInfo, error)
+    Put(key string, entry *models.CachedSongInfo) error
+}
+
+var _ SongInfoCacheStore = (*SongInfoCacheRepository)(nil)
+
+type SongInfoCacheRepository struct {
+    db *sql.DB
+}
+
+func NewSongInfoCacheRepository(db *sql.DB) *SongInfoCacheRepository {
+    return &SongInfoCacheRepository{db: db}
+}
+
+func (r *SongInfoCacheRepository) Get(key string) (*models.CachedSongInfo, error) {
+    var detail models.SongDetail
+    entry := &models.CachedSongInfo{}
+    err := r.db.QueryRow(`
+        SELECT release_date, text, link, not_found, expires_at
+        FROM song_info_cache
+        WHERE key = $1 AND expires_at > CURRENT_TIMESTAMP`, key).
+        Scan(&detail.ReleaseDate, &detail.Text, &detail.Link, &entry.NotFound, &entry.ExpiresAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, models.ErrNotFound
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+
+    if !entry.NotFound {
+        entry.Detail = &detail
+    }
+    return entry, nil
+}
+
+func (r *SongInfoCacheRepository) Put(key string, entry *models.CachedSongInfo) error {
+    var detail models.SongDetail
+    if entry.Detail != nil {
+        detail = *entry.Detail
+    }
+
+    _, err := r.db.Exec(`
+        INSERT INTO song_info_cache (key, release_date, text, link, not_found, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (key) DO UPDATE
+        SET release_date = EXCLUDED.release_date, text = EXCLUDED.text, link = EXCLUDED.link,
+            not_found = EXCLUDED.not_found, expires_at = EXCLUDED.expires_at,
+            updated_at = CURRENT_TIMESTAMP`,
+        key, detail.ReleaseDate, detail.Text, detail.Link, entry.NotFound, entry.ExpiresAt)
+    return translateError(err)
+}
@@ -2,10 +2,50 @@ package repository
 
 import (
     "database/sql"
+    "errors"
     "fmt"
     "music-library/internal/models"
+    "strconv"
+    "strings"
+    "time"
 )
 
+const songColumns = "s.id, s.artist_id, a.name, s.song_name, s.release_date, s.text, s.link, s.language, s.version, s.created_at, s.updated_at, s.enrichment_status, s.deleted_at"
+
+// songFrom joins every song to its artist, whose name is the song's group.
+const songFrom = "songs s JOIN artists a ON a.id = s.artist_id"
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanSong scans songColumns into song, followed by any extra columns.
+func scanSong(row rowScanner, song *models.Song, extra ...interface{}) error {
+    dest := []interface{}{
+        &song.ID,
+        &song.ArtistID,
+        &song.GroupName,
+        &song.SongName,
+        &song.ReleaseDate,
+        &song.Text,
+        &song.Link,
+        &song.Language,
+        &song.Version,
+        &song.CreatedAt,
+        &song.UpdatedAt,
+        &song.EnrichmentStatus,
+        &song.DeletedAt,
+    }
+    return row.Scan(append(dest, extra...)...)
+}
+
 type SongRepository struct {
     db *sql.DB
 }
@@ -14,125 +54,649 @@ func NewSongRepository(db *sql.DB) *SongRepository {
     return &SongRepository{db: db}
 }
 
-func (r *SongRepository) Create(song *models.Song) error {
-    query := `
-        INSERT INTO songs (group_name, song_name, release_date, text, link)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id, created_at, updated_at`
+// Create stores song under the artist named by song.GroupName, creating the
+// artist if it does not exist yet. A pending song is queued for enrichment in
+// the same transaction.
+func (r *SongRepository) Create(song *models.Song, actor string) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        if err := ensureArtist(tx, song); err != nil {
+            return err
+        }
+
+        query := `
+            INSERT INTO songs (artist_id, song_name, release_date, text, link, language, enrichment_status)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            RETURNING id, version, created_at, updated_at`
+
+        err := tx.QueryRow(
+            query,
+            song.ArtistID,
+            song.SongName,
+            song.ReleaseDate,
+            song.Text,
+            song.Link,
+            song.Language,
+            song.EnrichmentStatus,
+        ).Scan(&song.ID, &song.Version, &song.CreatedAt, &song.UpdatedAt)
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := recordProvenance(tx, song.ID, song.CreatedFields(), models.SourceUser); err != nil {
+            return err
+        }
+        if err := insertRevision(tx, models.NewSongRevision(models.RevisionCreate, actor, nil, song)); err != nil {
+            return err
+        }
 
-    return r.db.QueryRow(
-        query,
-        song.GroupName,
-        song.SongName,
-        song.ReleaseDate,
-        song.Text,
-        song.Link,
-    ).Scan(&song.ID, &song.CreatedAt, &song.UpdatedAt)
+        if song.EnrichmentStatus == models.EnrichmentPending {
+            _, err = tx.Exec("INSERT INTO enrichment_jobs (song_id, refresh) VALUES ($1, $2)", song.ID, song.RefreshInfo)
+        }
+        return translateError(err)
+    })
 }
 
-func (r *SongRepository) Update(song *models.Song) error {
-    query := `
-        UPDATE songs 
-        SET group_name = $1, song_name = $2, release_date = $3, text = $4, link = $5, updated_at = CURRENT_TIMESTAMP
-        WHERE id = $6
-        RETURNING updated_at`
-
-    return r.db.QueryRow(
-        query,
-        song.GroupName,
-        song.SongName,
-        song.ReleaseDate,
-        song.Text,
-        song.Link,
-        song.ID,
-    ).Scan(&song.UpdatedAt)
-}
-
-func (r *SongRepository) Delete(id int) error {
-    query := "DELETE FROM songs WHERE id = $1"
-    result, err := r.db.Exec(query, id)
+// Update replaces song and marks the fields it changed as set by the user.
+func (r *SongRepository) Update(song *models.Song, expectedVersion int, actor string) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        old, err := lockLiveSong(tx, song.ID)
+        if err != nil {
+            return err
+        }
+        if err := ensureArtist(tx, song); err != nil {
+            return err
+        }
+
+        query := `
+            UPDATE songs 
+            SET artist_id = $1, song_name = $2, release_date = $3, text = $4, link = $5, language = $6,
+                version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $7 AND ($8 = 0 OR version = $8)
+            RETURNING version, created_at, updated_at, enrichment_status`
+
+        err = tx.QueryRow(
+            query,
+            song.ArtistID,
+            song.SongName,
+            song.ReleaseDate,
+            song.Text,
+            song.Link,
+            song.Language,
+            song.ID,
+            expectedVersion,
+        ).Scan(&song.Version, &song.CreatedAt, &song.UpdatedAt, &song.EnrichmentStatus)
+        if errors.Is(err, sql.ErrNoRows) {
+            return missingSongError(tx, song.ID)
+        }
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := recordProvenance(tx, song.ID, song.ChangedFields(old), models.SourceUser); err != nil {
+            return err
+        }
+        return insertRevision(tx, models.NewSongRevision(models.RevisionUpdate, actor, old, song))
+    })
+}
+
+// Patch updates only the columns set in patch and returns the resulting song.
+func (r *SongRepository) Patch(id int, patch *models.SongPatch, expectedVersion int, actor string) (*models.Song, error) {
+    var sets []string
+    var args []interface{}
+    set := func(column string, value interface{}) {
+        args = append(args, value)
+        sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+    }
+
+    if patch.SongName != nil {
+        set("song_name", *patch.SongName)
+    }
+    if patch.ReleaseDate != nil {
+        set("release_date", *patch.ReleaseDate)
+    }
+    if patch.Text != nil {
+        set("text", *patch.Text)
+    }
+    if patch.Link != nil {
+        set("link", *patch.Link)
+    }
+    if patch.Language != nil {
+        set("language", *patch.Language)
+    }
+    sets = append(sets, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+
+    var song *models.Song
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        old, err := lockLiveSong(tx, id)
+        if err != nil {
+            return err
+        }
+        if patch.GroupName != nil {
+            artist := &models.Song{GroupName: *patch.GroupName}
+            if err := ensureArtist(tx, artist); err != nil {
+                return err
+            }
+            set("artist_id", artist.ArtistID)
+        }
+        args = append(args, id, expectedVersion)
+
+        query := fmt.Sprintf(`
+            UPDATE songs
+            SET %s
+            WHERE id = $%d AND ($%d = 0 OR version = $%d)
+            RETURNING id`, strings.Join(sets, ", "), len(args)-1, len(args), len(args))
+
+        err = tx.QueryRow(query, args...).Scan(&id)
+        if errors.Is(err, sql.ErrNoRows) {
+            return missingSongError(tx, id)
+        }
+        if err != nil {
+            return translateError(err)
+        }
+
+        if err := recordProvenance(tx, id, patch.Fields(), models.SourceUser); err != nil {
+            return err
+        }
+
+        song, err = getSong(tx, id)
+        if err != nil {
+            return err
+        }
+        return insertRevision(tx, models.NewSongRevision(models.RevisionUpdate, actor, old, song))
+    })
     if err != nil {
-        return err
+        return nil, err
     }
+    return song, nil
+}
+
+// Delete moves a song to the trash, bumping its version like any other
+// change.
+func (r *SongRepository) Delete(id int, expectedVersion int, actor string) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        old, err := lockLiveSong(tx, id)
+        if err != nil {
+            return err
+        }
+        if expectedVersion != 0 && old.Version != expectedVersion {
+            return versionMismatch(id)
+        }
+
+        deleted := *old
+        err = tx.QueryRow(`
+            UPDATE songs
+            SET deleted_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1
+            RETURNING deleted_at, version, updated_at`, id,
+        ).Scan(&deleted.DeletedAt, &deleted.Version, &deleted.UpdatedAt)
+        if err != nil {
+            return translateError(err)
+        }
+        return insertRevision(tx, models.NewSongRevision(models.RevisionDelete, actor, &deleted, nil))
+    })
+}
+
+// Restore takes a song out of the trash. Restoring a song that is not in
+// the trash changes nothing.
+func (r *SongRepository) Restore(id int, expectedVersion int, actor string) (*models.Song, error) {
+    var song *models.Song
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        old, err := lockSong(tx, id)
+        if err != nil {
+            return err
+        }
+        if expectedVersion != 0 && old.Version != expectedVersion {
+            return versionMismatch(id)
+        }
+        if old.DeletedAt == nil {
+            song = old
+            return nil
+        }
 
-    rowsAffected, err := result.RowsAffected()
+        restored := *old
+        restored.DeletedAt = nil
+        err = tx.QueryRow(`
+            UPDATE songs
+            SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1
+            RETURNING version, updated_at`, id,
+        ).Scan(&restored.Version, &restored.UpdatedAt)
+        if err != nil {
+            return translateError(err)
+        }
+        if err := insertRevision(tx, models.NewSongRevision(models.RevisionRestore, actor, old, &restored)); err != nil {
+            return err
+        }
+        song = &restored
+        return nil
+    })
     if err != nil {
-        return err
+        return nil, err
     }
+    return song, nil
+}
 
-    if rowsAffected == 0 {
-        return fmt.Errorf("song with id %d not found", id)
+// PurgeDeleted permanently removes up to limit songs that were moved to the
+// trash before cutoff, recording a purge revision for each, and returns how
+// many it removed.
+func (r *SongRepository) PurgeDeleted(cutoff time.Time, limit int) (int, error) {
+    purged := 0
+    err := withTx(r.db, func(tx *sql.Tx) error {
+        rows, err := tx.Query(`
+            SELECT `+songColumns+`
+            FROM `+songFrom+`
+            WHERE s.deleted_at < $1
+            ORDER BY s.deleted_at
+            LIMIT $2
+            FOR UPDATE OF s SKIP LOCKED`, cutoff, limit)
+        if err != nil {
+            return translateError(err)
+        }
+        var songs []models.Song
+        for rows.Next() {
+            var song models.Song
+            if err := scanSong(rows, &song); err != nil {
+                rows.Close()
+                return err
+            }
+            songs = append(songs, song)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return err
+        }
+
+        for i := range songs {
+            if _, err := tx.Exec("DELETE FROM songs WHERE id = $1", songs[i].ID); err != nil {
+                return translateError(err)
+            }
+            if err := insertRevision(tx, models.NewSongRevision(models.RevisionPurge, models.ActorPurge, &songs[i], nil)); err != nil {
+                return err
+            }
+        }
+        purged = len(songs)
+        return nil
+    })
+    if err != nil {
+        return 0, err
     }
+    return purged, nil
+}
+
+// missingSongError explains why a conditional write matched no rows: either
+// the song does not exist or its version has moved on.
+func missingSongError(q querier, id int) error {
+    var exists bool
+    err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM songs WHERE id = $1)", id).Scan(&exists)
+    if err != nil {
+        return translateError(err)
+    }
+    if exists {
+        return versionMismatch(id)
+    }
+    return songNotFound(id)
+}
 
-    return nil
+// GetByID returns a song. Songs in the trash are only found with
+// includeDeleted.
+func (r *SongRepository) GetByID(id int, includeDeleted bool) (*models.Song, error) {
+    song, err := getSong(r.db, id)
+    if err == nil && song.DeletedAt != nil && !includeDeleted {
+        return nil, songNotFound(id)
+    }
+    return song, err
 }
 
-func (r *SongRepository) GetByID(id int) (*models.Song, error) {
+func getSong(q querier, id int) (*models.Song, error) {
     song := &models.Song{}
     query := `
-        SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
-        FROM songs
-        WHERE id = $1`
+        SELECT ` + songColumns + `
+        FROM ` + songFrom + `
+        WHERE s.id = $1`
 
-    err := r.db.QueryRow(query, id).Scan(
-        &song.ID,
-        &song.GroupName,
-        &song.SongName,
-        &song.ReleaseDate,
-        &song.Text,
-        &song.Link,
-        &song.CreatedAt,
-        &song.UpdatedAt,
-    )
-    if err == sql.ErrNoRows {
-        return nil, fmt.Errorf("song with id %d not found", id)
+    err := scanSong(q.QueryRow(query, id), song)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, songNotFound(id)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return song, nil
+}
+
+// lockSong reads a song, whether or not it is in the trash, and locks its
+// row until the transaction ends.
+func lockSong(tx *sql.Tx, id int) (*models.Song, error) {
+    song := &models.Song{}
+    query := `
+        SELECT ` + songColumns + `
+        FROM ` + songFrom + `
+        WHERE s.id = $1
+        FOR UPDATE OF s`
+
+    err := scanSong(tx.QueryRow(query, id), song)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, songNotFound(id)
+    }
+    if err != nil {
+        return nil, translateError(err)
+    }
+    return song, nil
+}
+
+// lockLiveSong is lockSong for songs that are not in the trash.
+func lockLiveSong(tx *sql.Tx, id int) (*models.Song, error) {
+    song, err := lockSong(tx, id)
+    if err == nil && song.DeletedAt != nil {
+        return nil, songNotFound(id)
     }
     return song, err
 }
 
+// List returns one page of songs matching filter, ordered by filter.SortKeys
+// and then id. With filter.Keyset set it seeks past the cursor instead of
+// using OFFSET.
 func (r *SongRepository) List(filter *models.SongFilter) ([]models.Song, error) {
+    if !filter.Fuzzy {
+        return listSongs(r.db, filter)
+    }
+
+    var songs []models.Song
+    err := r.withSimilarityThreshold(func(q querier) error {
+        var err error
+        songs, err = listSongs(q, filter)
+        return err
+    })
+    return songs, err
+}
+
+func listSongs(q querier, filter *models.SongFilter) ([]models.Song, error) {
+    where, args := songFilterWhere(filter)
+
+    cursor := filter.Keyset
+    backwards := cursor != nil && cursor.Before
+    if cursor != nil {
+        var condition string
+        condition, args = keysetCondition(filter.SortKeys, cursor, args)
+        where += " AND " + condition
+    }
+
+    order := orderByClause(filter.SortKeys, backwards)
+    if filter.Fuzzy && len(filter.SortKeys) == 0 {
+        var rank string
+        rank, args = similarityRank(filter, args)
+        order = rank + " DESC, s.id ASC"
+    }
+
+    args = append(args, filter.RowLimit())
+    limit := fmt.Sprintf("LIMIT $%d", len(args))
+    if cursor == nil {
+        args = append(args, (filter.Page-1)*filter.PageSize)
+        limit += fmt.Sprintf(" OFFSET $%d", len(args))
+    }
+
     query := `
-        SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
-        FROM songs
-        WHERE ($1 = '' OR group_name ILIKE '%' || $1 || '%')
-        AND ($2 = '' OR song_name ILIKE '%' || $2 || '%')
-        AND ($3 = '' OR release_date::text LIKE $3)
-        ORDER BY id
-        LIMIT $4 OFFSET $5`
-
-    offset := (filter.Page - 1) * filter.PageSize
-
-    rows, err := r.db.Query(
-        query,
-        filter.GroupName,
-        filter.SongName,
-        filter.ReleaseDate,
-        filter.PageSize,
-        offset,
-    )
+        SELECT ` + songColumns + `
+        FROM ` + songFrom + `
+        ` + where + `
+        ORDER BY ` + order + `
+        ` + limit
+
+    rows, err := q.Query(query, args...)
     if err != nil {
-        return nil, err
+        return nil, translateError(err)
     }
     defer rows.Close()
 
     var songs []models.Song
     for rows.Next() {
         var song models.Song
-        err := rows.Scan(
-            &song.ID,
-            &song.GroupName,
-            &song.SongName,
-            &song.ReleaseDate,
-            &song.Text,
-            &song.Link,
-            &song.CreatedAt,
-            &song.UpdatedAt,
-        )
-        if err != nil {
+        if err := scanSong(rows, &song); err != nil {
             return nil, err
         }
         songs = append(songs, song)
     }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
 
+    if backwards {
+        reverseSongs(songs)
+    }
     return songs, nil
 }
+
+// Count returns the number of songs matching filter, ignoring pagination.
+func (r *SongRepository) Count(filter *models.SongFilter) (int, error) {
+    if !filter.Fuzzy {
+        return countSongs(r.db, filter)
+    }
+
+    var total int
+    err := r.withSimilarityThreshold(func(q querier) error {
+        var err error
+        total, err = countSongs(q, filter)
+        return err
+    })
+    return total, err
+}
+
+func countSongs(q querier, filter *models.SongFilter) (int, error) {
+    where, args := songFilterWhere(filter)
+
+    var total int
+    err := q.QueryRow("SELECT COUNT(*) FROM "+songFrom+" "+where, args...).Scan(&total)
+    if err != nil {
+        return 0, translateError(err)
+    }
+    return total, nil
+}
+
+// withSimilarityThreshold runs fn in a transaction whose pg_trgm similarity
+// threshold is models.FuzzyThreshold, so the % operator can use the
+// trigram indexes with our threshold instead of the server default.
+func (r *SongRepository) withSimilarityThreshold(fn func(q querier) error) error {
+    return withTx(r.db, func(tx *sql.Tx) error {
+        threshold := strconv.FormatFloat(models.FuzzyThreshold, 'f', -1, 64)
+        if _, err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', $1, true)", threshold); err != nil {
+            return translateError(err)
+        }
+        return fn(tx)
+    })
+}
+
+// withTx runs fn in a transaction, committing only if fn succeeds.
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+    tx, err := db.Begin()
+    if err != nil {
+        return translateError(err)
+    }
+    defer tx.Rollback()
+
+    if err := fn(tx); err != nil {
+        return err
+    }
+    return translateError(tx.Commit())
+}
+
+// ensureArtist points song at the artist named song.GroupName, creating the
+// artist on first use, and replaces GroupName with the artist's stored
+// spelling.
+func ensureArtist(q querier, song *models.Song) error {
+    name := models.NormalizeArtistName(song.GroupName)
+    if name == "" {
+        return &models.ValidationError{Field: "group", Message: "must not be empty"}
+    }
+
+    var err error
+    song.ArtistID, song.GroupName, err = upsertArtist(q, name)
+    return err
+}
+
+// upsertArtist returns the id and stored name of the artist called name,
+// creating it if needed.
+func upsertArtist(q querier, name string) (int, string, error) {
+    var id int
+    // The no-op update makes RETURNING yield the existing row on conflict.
+    err := q.QueryRow(`
+        INSERT INTO artists (name)
+        VALUES ($1)
+        ON CONFLICT (name_key) DO UPDATE SET name = artists.name
+        RETURNING id, name`,
+        name,
+    ).Scan(&id, &name)
+    return id, name, translateError(err)
+}
+
+// similarityRank returns an expression scoring how closely a row matches
+// the fuzzy group and song filters.
+func similarityRank(filter *models.SongFilter, args []interface{}) (string, []interface{}) {
+    var scores []string
+    if filter.GroupName != "" {
+        args = append(args, filter.GroupName)
+        scores = append(scores, fmt.Sprintf("similarity(a.name, $%d)", len(args)))
+    }
+    if filter.SongName != "" {
+        args = append(args, filter.SongName)
+        scores = append(scores, fmt.Sprintf("similarity(s.song_name, $%d)", len(args)))
+    }
+    if len(scores) == 0 {
+        return "0", args
+    }
+    return "(" + strings.Join(scores, " + ") + ")", args
+}
+
+// songFilterWhere builds the WHERE clause shared by List and Count. Only
+// the filters that are set become conditions, so the planner can use the
+// matching indexes.
+func songFilterWhere(filter *models.SongFilter) (string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+    arg := func(value interface{}) string {
+        args = append(args, value)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    match := "%s ILIKE '%%' || %s || '%%'"
+    if filter.Fuzzy {
+        match = "%s %% %s"
+    }
+    if filter.ArtistID != 0 {
+        conditions = append(conditions, "s.artist_id = "+arg(filter.ArtistID))
+    }
+    if filter.AlbumID != 0 {
+        conditions = append(conditions,
+            "EXISTS (SELECT 1 FROM album_tracks t WHERE t.song_id = s.id AND t.album_id = "+arg(filter.AlbumID)+")")
+    }
+    if filter.GroupName != "" {
+        conditions = append(conditions, fmt.Sprintf(match, "a.name", arg(filter.GroupName)))
+    }
+    if filter.SongName != "" {
+        conditions = append(conditions, fmt.Sprintf(match, "s.song_name", arg(filter.SongName)))
+    }
+    if !filter.ReleasedFrom.IsZero() {
+        conditions = append(conditions, "s.release_date >= "+arg(filter.ReleasedFrom))
+    }
+    if !filter.ReleasedUntil.IsZero() {
+        conditions = append(conditions, "s.release_date < "+arg(filter.ReleasedUntil))
+    }
+    if !filter.IncludeDeleted {
+        conditions = append(conditions, "s.deleted_at IS NULL")
+    }
+
+    if len(conditions) == 0 {
+        return "WHERE TRUE", args
+    }
+    return "WHERE " + strings.Join(conditions, "\n        AND "), args
+}
+
+// orderByClause renders the ORDER BY list for keys, with id as the final
+// tie-breaker. NULLs come last in either direction. backwards flips every
+// direction, NULLs included, for paging towards the start.
+func orderByClause(keys []models.SortKey, backwards bool) string {
+    parts := make([]string, 0, len(keys)+1)
+    for _, key := range keys {
+        // key.Column comes from the whitelist in models.ParseSongSort.
+        part := sortExpression(key.Column) + " " + sortDirection(key.Desc != backwards)
+        if key.Nullable() {
+            if backwards {
+                part += " NULLS FIRST"
+            } else {
+                part += " NULLS LAST"
+            }
+        }
+        parts = append(parts, part)
+    }
+    parts = append(parts, "s.id "+sortDirection(backwards))
+    return strings.Join(parts, ", ")
+}
+
+// keysetCondition selects the rows after (or, for a Before cursor, before)
+// the cursor row in the order given by keys:
+//
+//     k1 > v1 OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND id > vid)
+//
+// with ">" flipped to "<" for descending keys. NULLs of nullable keys sort
+// last, as in orderByClause.
+func keysetCondition(keys []models.SortKey, cursor *models.SongCursor, args []interface{}) (string, []interface{}) {
+    var equal []string
+    var branches []string
+    for i, key := range keys {
+        column := sortExpression(key.Column)
+
+        var beyond, same string
+        if value := cursor.Keys[i]; value == nil {
+            // Only non-NULL values come before a NULL and none after it.
+            beyond = "FALSE"
+            if cursor.Before {
+                beyond = column + " IS NOT NULL"
+            }
+            same = column + " IS NULL"
+        } else {
+            args = append(args, *value)
+            placeholder := fmt.Sprintf("$%d", len(args))
+            op := ">"
+            if key.Desc != cursor.Before {
+                op = "<"
+            }
+            beyond = column + " " + op + " " + placeholder
+            if key.Nullable() && !cursor.Before {
+                beyond = "(" + beyond + " OR " + column + " IS NULL)"
+            }
+            same = column + " = " + placeholder
+        }
+        branches = append(branches, "("+strings.Join(append(equal, beyond), " AND ")+")")
+        equal = append(equal, same)
+    }
+
+    args = append(args, cursor.ID)
+    op := ">"
+    if cursor.Before {
+        op = "<"
+    }
+    branches = append(branches, "("+strings.Join(append(equal, fmt.Sprintf("s.id %s $%d", op, len(args))), " AND ")+")")
+
+    return "(" + strings.Join(branches, " OR ") + ")", args
+}
+
+// sortExpression maps a sort column to its expression in songFrom; the
+// group is the joined artist's name.
+func sortExpression(column string) string {
+    if column == "group_name" {
+        return "a.name"
+    }
+    return "s." + column
+}
+
+func sortDirection(desc bool) string {
+    if desc {
+        return "DESC"
+    }
+    return "ASC"
+}
+
+func reverseSongs(songs []models.Song) {
+    for i, j := 0, len(songs)-1; i < j; i, j = i+1, j-1 {
+        songs[i], songs[j] = songs[j], songs[i]
+    }
+}
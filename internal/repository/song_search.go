@@ -0,0 +1,161 @@
+package repository
+
+import (
+    "html"
+    "music-library/internal/models"
+    "sort"
+    "strings"
+)
+
+// Headlines mark matches with control characters rather than <mark> tags,
+// so that markHeadline can escape the rest of the text first. The song text
+// has them stripped beforehand.
+const (
+    headlineStart   = "\x02"
+    headlineStop    = "\x03"
+    headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", HighlightAll=true"
+)
+
+// Search runs a full-text query against the generated search_vector columns,
+// best matches first. It also returns the total number of matches. Without a
+// query language the query is parsed with each song's own language.
+func (r *SongRepository) Search(query *models.SearchQuery) ([]models.SearchHit, int, error) {
+    matches := searchMatches(query.Language)
+    args := []interface{}{query.Query}
+    if query.Language != "" {
+        args = append(args, query.Language)
+    }
+
+    var total int
+    err := r.db.QueryRow(`
+        WITH matches AS (`+matches+`)
+        SELECT COUNT(*) FROM matches`,
+        args...,
+    ).Scan(&total)
+    if err != nil {
+        return nil, 0, translateError(err)
+    }
+    if total == 0 {
+        return nil, 0, nil
+    }
+
+    rows, err := r.db.Query(`
+        WITH matches AS (`+matches+`)
+        SELECT `+songColumns+`,
+            ts_rank_cd(setweight(a.search_vector, 'A') || s.search_vector, q) AS rank,
+            ts_headline(COALESCE(NULLIF($2, '')::regconfig, s.language), translate(s.text, chr(2) || chr(3), ''), q, '`+headlineOptions+`') AS headline
+        FROM `+songFrom+`
+        JOIN matches m ON m.id = s.id,
+            websearch_to_tsquery(COALESCE(NULLIF($2, '')::regconfig, s.language), $1) AS q
+        ORDER BY rank DESC, s.id
+        LIMIT $3 OFFSET $4`,
+        query.Query, query.Language, query.PageSize, (query.Page-1)*query.PageSize,
+    )
+    if err != nil {
+        return nil, 0, translateError(err)
+    }
+    defer rows.Close()
+
+    var hits []models.SearchHit
+    for rows.Next() {
+        var hit models.SearchHit
+        err := scanSong(rows, &hit.Song, &hit.Rank, &hit.Headline)
+        if err != nil {
+            return nil, 0, err
+        }
+        hit.Headline = markHeadline(hit.Headline)
+        hits = append(hits, hit)
+    }
+
+    return hits, total, rows.Err()
+}
+
+// searchMatches returns a query for the ids of the songs outside the trash
+// matching the search text $1. Each branch compares a search_vector with a
+// constant tsquery so that it can use its GIN index: with a query language,
+// passed as $2, there is one branch for the songs, and without one a branch
+// per language restricted to the songs in it. Artist names, indexed with the
+// simple configuration, are searched in a branch of their own.
+func searchMatches(language string) string {
+    var branches []string
+    if language != "" {
+        branches = append(branches, `
+            SELECT s.id FROM songs s
+            WHERE s.search_vector @@ websearch_to_tsquery($2::regconfig, $1) AND s.deleted_at IS NULL`)
+    } else {
+        for _, lang := range searchLanguages() {
+            branches = append(branches, `
+            SELECT s.id FROM songs s
+            WHERE s.language = '`+lang+`'::regconfig
+              AND s.search_vector @@ websearch_to_tsquery('`+lang+`', $1) AND s.deleted_at IS NULL`)
+        }
+    }
+    branches = append(branches, `
+            SELECT s.id FROM artists a
+            JOIN songs s ON s.artist_id = a.id
+            WHERE a.search_vector @@ websearch_to_tsquery('simple', $1) AND s.deleted_at IS NULL`)
+    return strings.Join(branches, "\n            UNION")
+}
+
+// searchLanguages returns models.SearchLanguages in a stable order. They are
+// known configuration names, safe to put in a query.
+func searchLanguages() []string {
+    languages := make([]string, 0, len(models.SearchLanguages))
+    for language := range models.SearchLanguages {
+        languages = append(languages, language)
+    }
+    sort.Strings(languages)
+    return languages
+}
+
+// markHeadline HTML-escapes a headline and turns its match markers into
+// <mark> tags, so that lyrics can never inject markup into a snippet.
+func markHeadline(headline string) string {
+    headline = html.EscapeString(headline)
+    return strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>").Replace(headline)
+}
+
+// Suggest returns group and song names starting with prefix, at the start
+// of the name or of any word in it, closest matches first. Artists are only
+// suggested while they have a song outside the trash.
+func (r *SongRepository) Suggest(query *models.SuggestQuery) ([]models.Suggestion, error) {
+    pattern := escapeLike(query.Prefix) + "%"
+
+    rows, err := r.db.Query(`
+        SELECT kind, text, grp, song_id, score
+        FROM (
+            SELECT 'group' AS kind, name AS text, '' AS grp, 0 AS song_id,
+                similarity(name, $1) AS score
+            FROM artists
+            WHERE (name ILIKE $2 OR name ILIKE '% ' || $2)
+              AND EXISTS (SELECT 1 FROM songs WHERE songs.artist_id = artists.id AND songs.deleted_at IS NULL)
+            UNION ALL
+            SELECT 'song', s.song_name, a.name, s.id, similarity(s.song_name, $1)
+            FROM `+songFrom+`
+            WHERE (s.song_name ILIKE $2 OR s.song_name ILIKE '% ' || $2) AND s.deleted_at IS NULL
+        ) AS suggestions
+        ORDER BY score DESC, text
+        LIMIT $3`,
+        query.Prefix, pattern, query.Limit,
+    )
+    if err != nil {
+        return nil, translateError(err)
+    }
+    defer rows.Close()
+
+    var suggestions []models.Suggestion
+    for rows.Next() {
+        var s models.Suggestion
+        if err := rows.Scan(&s.Kind, &s.Text, &s.Group, &s.SongID, &s.Score); err != nil {
+            return nil, err
+        }
+        suggestions = append(suggestions, s)
+    }
+
+    return suggestions, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in value so it matches literally.
+func escapeLike(value string) string {
+    return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+    "music-library/internal/models"
+    "time"
+)
+
+// SongStore is the persistence contract used by the service layer.
+//
+// Update, Patch, Delete, Restore and RestoreRevision take the version the
+// caller expects the song to be at; 0 skips the check. A mismatch returns
+// models.ErrPreconditionFailed. Every write records a revision naming actor.
+//
+// Delete moves a song to the trash, where only GetByID and List with
+// includeDeleted still find it, until Restore takes it out or PurgeDeleted
+// removes it for good.
+type SongStore interface {
+    Create(song *models.Song, actor string) error
+    Update(song *models.Song, expectedVersion int, actor string) error
+    Patch(id int, patch *models.SongPatch, expectedVersion int, actor string) (*models.Song, error)
+    Delete(id int, expectedVersion int, actor string) error
+    Restore(id int, expectedVersion int, actor string) (*models.Song, error)
+    PurgeDeleted(cutoff time.Time, limit int) (int, error)
+    GetByID(id int, includeDeleted bool) (*models.Song, error)
+    List(filter *models.SongFilter) ([]models.Song, error)
+    Count(filter *models.SongFilter) (int, error)
+    Search(query *models.SearchQuery) ([]models.SearchHit, int, error)
+    Suggest(query *models.SuggestQuery) ([]models.Suggestion, error)
+    Import(songs []models.Song, dryRun bool, actor string) ([]models.ImportStatus, error)
+    Export(filter *models.SongFilter, fn func(song *models.Song) error) error
+    Provenance(id int, includeDeleted bool) (*models.SongProvenance, error)
+    SetFieldLock(id int, field string, locked bool) (*models.SongProvenance, error)
+    Revisions(id int, filter *models.RevisionFilter, includeDeleted bool) ([]models.SongRevision, int, error)
+    Revision(id int, revision int, includeDeleted bool) (*models.SongRevision, error)
+    RestoreRevision(id int, revision int, expectedVersion int, actor string) (*models.Song, error)
+}
+
+var (
+    _ SongStore = (*SongRepository)(nil)
+    _ SongStore = (*MemorySongRepository)(nil)
+)
@@ -0,0 +1,170 @@
+package service
+
+import (
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "strings"
+)
+
+type AlbumService struct {
+    repo   repository.AlbumStore
+    logger *zap.Logger
+}
+
+func NewAlbumService(repo repository.AlbumStore, logger *zap.Logger) *AlbumService {
+    return &AlbumService{
+        repo:   repo,
+        logger: logger,
+    }
+}
+
+// CreateAlbum stores an album with its initial track listing and returns it
+// with its tracks.
+func (s *AlbumService) CreateAlbum(album *models.Album) (*models.AlbumWithTracks, error) {
+    s.logger.Info("Creating new album",
+        zap.String("artist", album.Artist),
+        zap.String("title", album.Title))
+
+    if models.NormalizeArtistName(album.Artist) == "" {
+        return nil, &models.ValidationError{Field: "artist", Message: "must not be empty"}
+    }
+    album.Title = strings.TrimSpace(album.Title)
+    if album.Title == "" {
+        return nil, &models.ValidationError{Field: "title", Message: "must not be empty"}
+    }
+    if err := validateTrackListing(album.SongIDs); err != nil {
+        return nil, err
+    }
+
+    if err := s.repo.Create(album); err != nil {
+        s.logger.Error("Failed to create album",
+            zap.Error(err),
+            zap.String("artist", album.Artist),
+            zap.String("title", album.Title))
+        return nil, fmt.Errorf("failed to create album: %w", err)
+    }
+
+    s.logger.Info("Successfully created album",
+        zap.Int("id", album.ID),
+        zap.String("title", album.Title))
+
+    return s.GetAlbum(album.ID)
+}
+
+func (s *AlbumService) DeleteAlbum(id int) error {
+    s.logger.Info("Deleting album", zap.Int("id", id))
+
+    if err := s.repo.Delete(id); err != nil {
+        s.logger.Error("Failed to delete album",
+            zap.Error(err),
+            zap.Int("id", id))
+        return fmt.Errorf("failed to delete album: %w", err)
+    }
+
+    s.logger.Info("Successfully deleted album", zap.Int("id", id))
+    return nil
+}
+
+// GetAlbum returns an album with its songs in track order.
+func (s *AlbumService) GetAlbum(id int) (*models.AlbumWithTracks, error) {
+    s.logger.Debug("Getting album by ID", zap.Int("id", id))
+
+    album, err := s.repo.GetByID(id)
+    if err != nil {
+        s.logger.Error("Failed to get album",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get album: %w", err)
+    }
+
+    tracks, err := s.repo.Tracks(id)
+    if err != nil {
+        s.logger.Error("Failed to get album tracks",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get album tracks: %w", err)
+    }
+    if tracks == nil {
+        tracks = []models.AlbumTrack{}
+    }
+
+    return &models.AlbumWithTracks{Album: *album, Tracks: tracks}, nil
+}
+
+// SetTracks replaces the album's track listing. It is used both to reorder
+// tracks and to add or remove them.
+func (s *AlbumService) SetTracks(id int, songIDs []int) (*models.AlbumWithTracks, error) {
+    s.logger.Info("Setting album tracks",
+        zap.Int("id", id),
+        zap.Ints("song_ids", songIDs))
+
+    if err := validateTrackListing(songIDs); err != nil {
+        return nil, err
+    }
+
+    if err := s.repo.SetTracks(id, songIDs); err != nil {
+        s.logger.Error("Failed to set album tracks",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to set album tracks: %w", err)
+    }
+
+    return s.GetAlbum(id)
+}
+
+func (s *AlbumService) ListAlbums(filter *models.AlbumFilter) (*models.AlbumList, error) {
+    s.logger.Debug("Listing albums with filter", zap.Any("filter", filter))
+
+    if filter.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if filter.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    albums, err := s.repo.List(filter)
+    if err != nil {
+        s.logger.Error("Failed to list albums",
+            zap.Error(err),
+            zap.Any("filter", filter))
+        return nil, fmt.Errorf("failed to list albums: %w", err)
+    }
+
+    total, err := s.repo.Count(filter)
+    if err != nil {
+        s.logger.Error("Failed to count albums",
+            zap.Error(err),
+            zap.Any("filter", filter))
+        return nil, fmt.Errorf("failed to count albums: %w", err)
+    }
+
+    if albums == nil {
+        albums = []models.Album{}
+    }
+    totalPages := (total + filter.PageSize - 1) / filter.PageSize
+    return &models.AlbumList{
+        Items: albums,
+        Total: total,
+        PageInfo: models.PageInfo{
+            Page:       filter.Page,
+            PageSize:   filter.PageSize,
+            TotalPages: totalPages,
+            HasNext:    filter.Page < totalPages,
+            HasPrev:    filter.Page > 1,
+        },
+    }, nil
+}
+
+// validateTrackListing rejects listings that name a song twice.
+func validateTrackListing(songIDs []int) error {
+    seen := make(map[int]bool, len(songIDs))
+    for _, id := range songIDs {
+        if seen[id] {
+            return &models.ValidationError{Field: "song_ids", Message: fmt.Sprintf("contains song %d more than once", id)}
+        }
+        seen[id] = true
+    }
+    return nil
+}
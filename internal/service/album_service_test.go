@@ -0,0 +1,33 @@
+package service_test
+
+import (
+    "errors"
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "testing"
+)
+
+func TestAlbumTrackListing(t *testing.T) {
+    services := testutil.NewServices()
+    uprising := createTestSong(t, services.Songs, "Muse", "Uprising", models.Date{})
+    resistance := createTestSong(t, services.Songs, "Muse", "Resistance", models.Date{})
+
+    album, err := services.Albums.CreateAlbum(&models.Album{Artist: "Muse", Title: "The Resistance"})
+    if err != nil {
+        t.Fatalf("CreateAlbum: %v", err)
+    }
+    album, err = services.Albums.SetTracks(album.ID, []int{resistance.ID, uprising.ID})
+    if err != nil {
+        t.Fatalf("SetTracks: %v", err)
+    }
+    if len(album.Tracks) != 2 || album.Tracks[0].Song.ID != resistance.ID || album.Tracks[1].Position != 2 {
+        t.Errorf("tracks = %+v, want Resistance then Uprising", album.Tracks)
+    }
+
+    if _, err := services.Albums.SetTracks(album.ID, []int{uprising.ID, uprising.ID}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("SetTracks with a repeated song: error = %v, want a validation error", err)
+    }
+    if _, err := services.Albums.SetTracks(album.ID, []int{42}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("SetTracks with an unknown song: error = %v, want a validation error", err)
+    }
+}
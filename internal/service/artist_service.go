@@ -0,0 +1,164 @@
+package service
+
+import (
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+)
+
+type ArtistService struct {
+    repo   repository.ArtistStore
+    songs  *SongService
+    logger *zap.Logger
+}
+
+func NewArtistService(repo repository.ArtistStore, songs *SongService, logger *zap.Logger) *ArtistService {
+    return &ArtistService{
+        repo:   repo,
+        songs:  songs,
+        logger: logger,
+    }
+}
+
+func (s *ArtistService) CreateArtist(artist *models.Artist) error {
+    s.logger.Info("Creating new artist", zap.String("name", artist.Name))
+
+    if err := validateArtistName(artist); err != nil {
+        return err
+    }
+
+    if err := s.repo.Create(artist); err != nil {
+        s.logger.Error("Failed to create artist",
+            zap.Error(err),
+            zap.String("name", artist.Name))
+        return fmt.Errorf("failed to create artist: %w", err)
+    }
+
+    s.logger.Info("Successfully created artist",
+        zap.Int("id", artist.ID),
+        zap.String("name", artist.Name))
+    return nil
+}
+
+// UpdateArtist renames an artist, which renames the group of all its songs.
+func (s *ArtistService) UpdateArtist(artist *models.Artist) error {
+    s.logger.Info("Updating artist",
+        zap.Int("id", artist.ID),
+        zap.String("name", artist.Name))
+
+    if err := validateArtistName(artist); err != nil {
+        return err
+    }
+
+    if err := s.repo.Update(artist); err != nil {
+        s.logger.Error("Failed to update artist",
+            zap.Error(err),
+            zap.Int("id", artist.ID))
+        return fmt.Errorf("failed to update artist: %w", err)
+    }
+
+    s.logger.Info("Successfully updated artist", zap.Int("id", artist.ID))
+    return nil
+}
+
+// DeleteArtist removes an artist without songs or albums.
+func (s *ArtistService) DeleteArtist(id int) error {
+    s.logger.Info("Deleting artist", zap.Int("id", id))
+
+    if err := s.repo.Delete(id); err != nil {
+        s.logger.Error("Failed to delete artist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return fmt.Errorf("failed to delete artist: %w", err)
+    }
+
+    s.logger.Info("Successfully deleted artist", zap.Int("id", id))
+    return nil
+}
+
+func (s *ArtistService) GetArtist(id int) (*models.Artist, error) {
+    s.logger.Debug("Getting artist by ID", zap.Int("id", id))
+
+    artist, err := s.repo.GetByID(id)
+    if err != nil {
+        s.logger.Error("Failed to get artist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get artist: %w", err)
+    }
+    return artist, nil
+}
+
+func (s *ArtistService) ListArtists(filter *models.ArtistFilter) (*models.ArtistList, error) {
+    s.logger.Debug("Listing artists with filter", zap.Any("filter", filter))
+
+    if filter.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if filter.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    artists, err := s.repo.List(filter)
+    if err != nil {
+        s.logger.Error("Failed to list artists",
+            zap.Error(err),
+            zap.Any("filter", filter))
+        return nil, fmt.Errorf("failed to list artists: %w", err)
+    }
+
+    total, err := s.repo.Count(filter)
+    if err != nil {
+        s.logger.Error("Failed to count artists",
+            zap.Error(err),
+            zap.Any("filter", filter))
+        return nil, fmt.Errorf("failed to count artists: %w", err)
+    }
+
+    if artists == nil {
+        artists = []models.Artist{}
+    }
+    totalPages := (total + filter.PageSize - 1) / filter.PageSize
+    return &models.ArtistList{
+        Items: artists,
+        Total: total,
+        PageInfo: models.PageInfo{
+            Page:       filter.Page,
+            PageSize:   filter.PageSize,
+            TotalPages: totalPages,
+            HasNext:    filter.Page < totalPages,
+            HasPrev:    filter.Page > 1,
+        },
+    }, nil
+}
+
+// ListArtistSongs lists the songs of one artist, with the same filters,
+// sorting and paging as SongService.ListSongs.
+func (s *ArtistService) ListArtistSongs(id int, filter *models.SongFilter) (*models.SongList, error) {
+    if _, err := s.GetArtist(id); err != nil {
+        return nil, err
+    }
+
+    filter.ArtistID = id
+    return s.songs.ListSongs(filter)
+}
+
+// ExportArtistSongs calls fn with every song of one artist matching filter,
+// like SongService.ExportSongs.
+func (s *ArtistService) ExportArtistSongs(id int, filter *models.SongFilter, fn func(song *models.Song) error) error {
+    if _, err := s.GetArtist(id); err != nil {
+        return err
+    }
+
+    filter.ArtistID = id
+    return s.songs.ExportSongs(filter, fn)
+}
+
+func validateArtistName(artist *models.Artist) error {
+    artist.Name = models.NormalizeArtistName(artist.Name)
+    if artist.Name == "" {
+        return &models.ValidationError{Field: "name", Message: "must not be empty"}
+    }
+    return nil
+}
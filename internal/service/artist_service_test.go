@@ -0,0 +1,39 @@
+package service_test
+
+import (
+    "errors"
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "testing"
+)
+
+func TestRenameArtistRenamesItsSongs(t *testing.T) {
+    services := testutil.NewServices()
+    song := createTestSong(t, services.Songs, "Muse", "Uprising", models.Date{})
+    createTestSong(t, services.Songs, " Muse ", "Resistance", models.Date{})
+
+    artists, err := services.Artists.ListArtists(&models.ArtistFilter{Page: 1, PageSize: 10})
+    if err != nil {
+        t.Fatalf("ListArtists: %v", err)
+    }
+    if artists.Total != 1 || artists.Items[0].SongCount != 2 {
+        t.Fatalf("ListArtists = %+v, want Muse with 2 songs", artists.Items)
+    }
+
+    artist := artists.Items[0]
+    artist.Name = "MUSE"
+    if err := services.Artists.UpdateArtist(&artist); err != nil {
+        t.Fatalf("UpdateArtist: %v", err)
+    }
+    renamed, err := services.Songs.GetSong(song.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if renamed.GroupName != "MUSE" {
+        t.Errorf("group after rename = %q, want MUSE", renamed.GroupName)
+    }
+
+    if err := services.Artists.DeleteArtist(artist.ID); !errors.Is(err, models.ErrConflict) {
+        t.Errorf("DeleteArtist with songs: error = %v, want a conflict", err)
+    }
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "gopkg.in/yaml.v3"
+    "music-library/internal/models"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// catalogueEntry is one song in a catalogue file.
+type catalogueEntry struct {
+    GroupName   string `json:"group" yaml:"group"`
+    SongName    string `json:"song" yaml:"song"`
+    ReleaseDate string `json:"releaseDate" yaml:"releaseDate"`
+    Text        string `json:"text" yaml:"text"`
+    Link        string `json:"link" yaml:"link"`
+}
+
+// CatalogueProvider serves song details from a local JSON or YAML file
+// holding a list of songs with the same fields as the song JSON. The file is
+// read once, when the provider is created.
+type CatalogueProvider struct {
+    songs map[string]models.SongDetail
+}
+
+// NewCatalogueProvider loads the catalogue at path. Files ending in .yaml or
+// .yml are read as YAML, anything else as JSON.
+func NewCatalogueProvider(path string) (*CatalogueProvider, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read catalogue: %w", err)
+    }
+
+    var entries []catalogueEntry
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &entries)
+    default:
+        err = json.Unmarshal(data, &entries)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse catalogue %s: %w", path, err)
+    }
+
+    provider := &CatalogueProvider{songs: make(map[string]models.SongDetail, len(entries))}
+    for i, entry := range entries {
+        if strings.TrimSpace(entry.GroupName) == "" || strings.TrimSpace(entry.SongName) == "" {
+            return nil, fmt.Errorf("catalogue %s: entry %d: group and song are required", path, i+1)
+        }
+        releaseDate, err := models.ParseDate(entry.ReleaseDate)
+        if err != nil {
+            return nil, fmt.Errorf("catalogue %s: entry %d: %w", path, i+1, err)
+        }
+        provider.songs[models.SongInfoKey(entry.GroupName, entry.SongName)] = models.SongDetail{
+            ReleaseDate: releaseDate,
+            Text:        entry.Text,
+            Link:        entry.Link,
+        }
+    }
+    return provider, nil
+}
+
+func (p *CatalogueProvider) Name() string {
+    return "catalogue"
+}
+
+func (p *CatalogueProvider) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    detail, ok := p.songs[models.SongInfoKey(group, song)]
+    if !ok {
+        return nil, ErrSongInfoNotFound
+    }
+    return &detail, nil
+}
+
+// Len is the number of songs in the catalogue.
+func (p *CatalogueProvider) Len() int {
+    return len(p.songs)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+    "errors"
+    "music-library/internal/models"
+    "sync"
+    "time"
+)
+
+const (
+    CircuitClosed   = "closed"
+    CircuitOpen     = "open"
+    CircuitHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calls to a failing dependency. After threshold
+// consecutive failures it opens and rejects calls for cooldown; then it lets
+// a single probe through and closes again if the probe succeeds.
+//
+// Every change of state starts a new generation. Allow hands out the current
+// one, and outcomes reported for an older generation are ignored, so a slow
+// call started before the breaker opened cannot close it again.
+type CircuitBreaker struct {
+    mu         sync.Mutex
+    threshold  int
+    cooldown   time.Duration
+    state      string
+    generation uint64
+    failures   int
+    openedAt   time.Time
+    probing    bool
+}
+
+// NewCircuitBreaker treats a threshold below 1 as 1.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+    return &CircuitBreaker{
+        threshold: max(threshold, 1),
+        cooldown:  cooldown,
+        state:     CircuitClosed,
+    }
+}
+
+// Allow reports whether a call may proceed and returns the generation the
+// call belongs to. Every allowed call must be followed by Success, Failure
+// or Release with that generation.
+func (b *CircuitBreaker) Allow() (uint64, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    switch b.state {
+    case CircuitOpen:
+        if time.Since(b.openedAt) < b.cooldown {
+            return 0, ErrCircuitOpen
+        }
+        b.setState(CircuitHalfOpen)
+        b.probing = true
+    case CircuitHalfOpen:
+        if b.probing {
+            return 0, ErrCircuitOpen
+        }
+        b.probing = true
+    }
+    return b.generation, nil
+}
+
+func (b *CircuitBreaker) Success(generation uint64) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if generation != b.generation {
+        return
+    }
+    b.failures = 0
+    if b.state == CircuitHalfOpen {
+        b.setState(CircuitClosed)
+    }
+}
+
+func (b *CircuitBreaker) Failure(generation uint64) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if generation != b.generation {
+        return
+    }
+    b.failures++
+    if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+        b.setState(CircuitOpen)
+        b.openedAt = time.Now()
+    }
+}
+
+// Release ends an allowed call whose outcome says nothing about the
+// dependency, such as one cancelled by the caller.
+func (b *CircuitBreaker) Release(generation uint64) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if generation == b.generation && b.state == CircuitHalfOpen {
+        b.probing = false
+    }
+}
+
+// setState moves to state and starts a new generation. b.mu must be held.
+func (b *CircuitBreaker) setState(state string) {
+    b.state = state
+    b.generation++
+    b.probing = false
+}
+
+func (b *CircuitBreaker) State() models.CircuitState {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    state := models.CircuitState{State: b.state, ConsecutiveFailures: b.failures}
+    if b.state != CircuitClosed {
+        openedAt := b.openedAt
+        state.OpenedAt = &openedAt
+    }
+    if b.state == CircuitOpen {
+        retryAt := b.openedAt.Add(b.cooldown)
+        state.RetryAt = &retryAt
+    }
+    return state
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "music-library/internal/models"
+)
+
+// Cursors are opaque to clients: base64url-encoded JSON of a SongCursor.
+
+// encodeCursor builds a cursor pointing at song in the order given by keys.
+func encodeCursor(song *models.Song, keys []models.SortKey, before bool) string {
+    cursor := models.SongCursor{
+        ID:     song.ID,
+        Sort:   models.FormatSongSort(keys),
+        Before: before,
+    }
+    for _, key := range keys {
+        cursor.Keys = append(cursor.Keys, key.Value(song))
+    }
+
+    data, _ := json.Marshal(cursor)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same sort.
+func decodeCursor(value string, keys []models.SortKey) (*models.SongCursor, error) {
+    invalid := &models.ValidationError{Field: "cursor", Message: "is invalid"}
+
+    data, err := base64.RawURLEncoding.DecodeString(value)
+    if err != nil {
+        return nil, invalid
+    }
+
+    var cursor models.SongCursor
+    if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+        return nil, invalid
+    }
+    if cursor.Sort != models.FormatSongSort(keys) || len(cursor.Keys) != len(keys) {
+        return nil, &models.ValidationError{Field: "cursor", Message: "was issued for a different sort order"}
+    }
+    for i, key := range keys {
+        if cursor.Keys[i] == nil && !key.Nullable() {
+            return nil, invalid
+        }
+    }
+    return &cursor, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "go.uber.org/zap"
+    "math/rand"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "sync"
+    "time"
+)
+
+const (
+    // enrichmentPollInterval is how often idle workers look for due jobs.
+    enrichmentPollInterval = 2 * time.Second
+    // enrichmentLease is how long a claimed job is reserved for its worker.
+    // It must outlast a call to the music info API.
+    enrichmentLease = 2 * time.Minute
+    // enrichmentBaseBackoff is the delay before the first retry; it doubles
+    // with every further attempt up to enrichmentMaxBackoff.
+    enrichmentBaseBackoff = 5 * time.Second
+    enrichmentMaxBackoff  = 10 * time.Minute
+)
+
+// EnrichmentService fills in songs from the music info API in the
+// background. Its workers claim jobs from a shared store, so any number of
+// server instances can work off the same queue.
+type EnrichmentService struct {
+    store       repository.EnrichmentStore
+    client      SongInfoClient
+    workers     int
+    maxAttempts int
+    wake        chan struct{}
+    logger      *zap.Logger
+}
+
+func NewEnrichmentService(store repository.EnrichmentStore, client SongInfoClient, workers, maxAttempts int, logger *zap.Logger) *EnrichmentService {
+    return &EnrichmentService{
+        store:       store,
+        client:      client,
+        workers:     workers,
+        maxAttempts: maxAttempts,
+        wake:        make(chan struct{}, 1),
+        logger:      logger,
+    }
+}
+
+// Run starts the worker pool and blocks until ctx is cancelled and every
+// worker has finished its current job.
+func (s *EnrichmentService) Run(ctx context.Context) {
+    s.logger.Info("Starting enrichment workers",
+        zap.Int("workers", s.workers),
+        zap.Int("max_attempts", s.maxAttempts))
+
+    var wg sync.WaitGroup
+    for i := 0; i < s.workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            s.work(ctx)
+        }()
+    }
+    wg.Wait()
+}
+
+// Wake tells an idle worker to look for jobs now instead of at its next poll.
+func (s *EnrichmentService) Wake() {
+    select {
+    case s.wake <- struct{}{}:
+    default:
+    }
+}
+
+func (s *EnrichmentService) work(ctx context.Context) {
+    for ctx.Err() == nil {
+        jobs, err := s.store.Claim(1, enrichmentLease)
+        if err != nil {
+            s.logger.Error("Failed to claim enrichment job", zap.Error(err))
+        }
+        if len(jobs) > 0 {
+            s.process(ctx, &jobs[0])
+            continue
+        }
+
+        select {
+        case <-ctx.Done():
+        case <-s.wake:
+        case <-time.After(enrichmentPollInterval):
+        }
+    }
+}
+
+func (s *EnrichmentService) process(ctx context.Context, job *models.EnrichmentJob) {
+    logger := s.logger.With(
+        zap.Int("song_id", job.SongID),
+        zap.Int("attempt", job.Attempts))
+
+    fetchCtx := ctx
+    if job.Refresh {
+        fetchCtx = WithSongInfoRefresh(ctx)
+    }
+    detail, err := s.client.GetSongInfo(fetchCtx, job.GroupName, job.SongName)
+    if err == nil {
+        if err := s.store.Complete(job, detail); err != nil {
+            logger.Error("Failed to store song info", zap.Error(err))
+            return
+        }
+        logger.Info("Successfully enriched song")
+        return
+    }
+
+    // Shutting down, or the upstream is known to be down: put the job back
+    // without using up its attempts. A song the upstream does not know is
+    // not worth retrying.
+    refund := ctx.Err() != nil || errors.Is(err, ErrCircuitOpen)
+    if (job.Attempts >= s.maxAttempts || errors.Is(err, ErrSongInfoNotFound)) && !refund {
+        logger.Warn("Giving up on song enrichment", zap.Error(err))
+        if err := s.store.Fail(job, err.Error()); err != nil {
+            logger.Error("Failed to mark enrichment failed", zap.Error(err))
+        }
+        return
+    }
+
+    runAt := time.Now().Add(enrichmentBackoff(job.Attempts))
+    if ctx.Err() != nil {
+        runAt = time.Now()
+    }
+    logger.Warn("Failed to get song info, will retry", zap.Error(err), zap.Time("next_attempt_at", runAt))
+    if err := s.store.Retry(job, runAt, err.Error(), refund); err != nil {
+        logger.Error("Failed to reschedule enrichment", zap.Error(err))
+    }
+}
+
+// enrichmentBackoff is the delay after the given number of failed attempts:
+// exponential, with up to half of it taken off at random so that songs
+// failing together do not retry together.
+func enrichmentBackoff(attempts int) time.Duration {
+    backoff := enrichmentMaxBackoff
+    if attempts < 20 {
+        backoff = min(enrichmentBaseBackoff<<(attempts-1), enrichmentMaxBackoff)
+    }
+    return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// EnrichSong queues a song to be enriched again, for example after it failed.
+// With refresh the song info cache is bypassed.
+func (s *EnrichmentService) EnrichSong(songID int, refresh bool) (*models.Enrichment, error) {
+    s.logger.Info("Queueing song enrichment", zap.Int("id", songID), zap.Bool("refresh", refresh))
+
+    enrichment, err := s.store.Enqueue(songID, refresh)
+    if err != nil {
+        s.logger.Error("Failed to queue song enrichment",
+            zap.Error(err),
+            zap.Int("id", songID))
+        return nil, fmt.Errorf("failed to queue song enrichment: %w", err)
+    }
+
+    s.Wake()
+    return enrichment, nil
+}
+
+// LookupSongInfo asks the song info providers about a song without storing
+// anything. A song they do not know matches models.ErrNotFound.
+func (s *EnrichmentService) LookupSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    detail, err := s.client.GetSongInfo(ctx, group, song)
+    if err != nil {
+        s.logger.Error("Failed to look up song info",
+            zap.Error(err),
+            zap.String("group", group),
+            zap.String("song", song))
+        if errors.Is(err, ErrSongInfoNotFound) {
+            return nil, fmt.Errorf("failed to look up song info: %w: %w", models.ErrNotFound, err)
+        }
+        return nil, fmt.Errorf("failed to look up song info: %w", err)
+    }
+    return detail, nil
+}
+
+func (s *EnrichmentService) GetEnrichment(songID int) (*models.Enrichment, error) {
+    enrichment, err := s.store.Get(songID)
+    if err != nil {
+        s.logger.Error("Failed to get song enrichment",
+            zap.Error(err),
+            zap.Int("id", songID))
+        return nil, fmt.Errorf("failed to get song enrichment: %w", err)
+    }
+    return enrichment, nil
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "testing"
+    "time"
+)
+
+func TestEnrichmentFillsInANewSong(t *testing.T) {
+    services := testutil.NewServices()
+    song := createTestSong(t, services.Songs, "Muse", "Uprising", models.Date{})
+    if song.EnrichmentStatus != models.EnrichmentPending {
+        t.Fatalf("status of a new song = %s, want pending", song.EnrichmentStatus)
+    }
+
+    jobs, err := services.EnrichmentStore.Claim(10, time.Minute)
+    if err != nil || len(jobs) != 1 || jobs[0].SongID != song.ID {
+        t.Fatalf("Claim = %+v, %v; want the new song's job", jobs, err)
+    }
+    detail := &models.SongDetail{ReleaseDate: models.NewDate(2009, time.September, 7), Link: "https://example.com/uprising"}
+    if err := services.EnrichmentStore.Complete(&jobs[0], detail); err != nil {
+        t.Fatalf("Complete: %v", err)
+    }
+
+    enriched, err := services.Songs.GetSong(song.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if enriched.EnrichmentStatus != models.EnrichmentEnriched || enriched.ReleaseDate != detail.ReleaseDate || enriched.Link != detail.Link {
+        t.Errorf("song after enrichment = %+v", enriched)
+    }
+    if jobs, _ := services.EnrichmentStore.Claim(10, time.Minute); len(jobs) != 0 {
+        t.Errorf("Claim after Complete = %+v, want no jobs", jobs)
+    }
+
+    enrichment, err := services.Enrichment.EnrichSong(song.ID, false)
+    if err != nil {
+        t.Fatalf("EnrichSong: %v", err)
+    }
+    if enrichment.Status != models.EnrichmentPending || enrichment.Attempts != 0 {
+        t.Errorf("enrichment after EnrichSong = %+v, want pending with no attempts", enrichment)
+    }
+}
+
+func TestEnrichmentKeepsUserAndLockedFields(t *testing.T) {
+    services := testutil.NewServices()
+    released := models.NewDate(2009, time.September, 7)
+    song := createTestSong(t, services.Songs, "Muse", "Uprising", released)
+    if _, err := services.Songs.SetFieldLock(song.ID, models.FieldLink, true); err != nil {
+        t.Fatalf("SetFieldLock: %v", err)
+    }
+
+    jobs, err := services.EnrichmentStore.Claim(10, time.Minute)
+    if err != nil || len(jobs) != 1 {
+        t.Fatalf("Claim = %+v, %v; want one job", jobs, err)
+    }
+    detail := &models.SongDetail{
+        ReleaseDate: models.NewDate(2010, time.January, 1),
+        Text:        "Paranoia is in bloom",
+        Link:        "https://example.com/uprising",
+        Sources:     map[string]string{models.FieldText: "music_api"},
+    }
+    if err := services.EnrichmentStore.Complete(&jobs[0], detail); err != nil {
+        t.Fatalf("Complete: %v", err)
+    }
+
+    enriched, err := services.Songs.GetSong(song.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if enriched.ReleaseDate != released || enriched.Text != detail.Text || enriched.Link != "" {
+        t.Errorf("song after enrichment = %+v; want the user's release date, the upstream text and no link", enriched)
+    }
+
+    provenance, err := services.Songs.GetProvenance(song.ID, false)
+    if err != nil {
+        t.Fatalf("GetProvenance: %v", err)
+    }
+    fields := provenance.Fields
+    if fields[models.FieldReleaseDate].Source != models.SourceUser || fields[models.FieldText].Source != "music_api" || !fields[models.FieldLink].Locked {
+        t.Errorf("provenance after enrichment = %+v", fields)
+    }
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+    "context"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "sort"
+    "time"
+)
+
+// healthCheckTimeout bounds each dependency check.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthChecker reports on one dependency of the service.
+type HealthChecker interface {
+    CheckHealth(ctx context.Context) models.HealthCheck
+}
+
+type HealthService struct {
+    checkers map[string]HealthChecker
+    logger   *zap.Logger
+}
+
+func NewHealthService(logger *zap.Logger) *HealthService {
+    return &HealthService{
+        checkers: make(map[string]HealthChecker),
+        logger:   logger,
+    }
+}
+
+// Register adds a dependency to the health report. It must be called before
+// the server starts.
+func (s *HealthService) Register(name string, checker HealthChecker) {
+    s.checkers[name] = checker
+}
+
+// Check runs every registered check. The service is down if a critical
+// dependency is, and degraded if any other dependency is not up.
+func (s *HealthService) Check(ctx context.Context) *models.HealthReport {
+    names := make([]string, 0, len(s.checkers))
+    for name := range s.checkers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    report := &models.HealthReport{Status: models.HealthUp, Checks: make(map[string]models.HealthCheck)}
+    for _, name := range names {
+        checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+        check := s.checkers[name].CheckHealth(checkCtx)
+        cancel()
+
+        report.Checks[name] = check
+        switch {
+        case check.Status == models.HealthUp:
+        case check.Critical && check.Status == models.HealthDown:
+            report.Status = models.HealthDown
+        case report.Status == models.HealthUp:
+            report.Status = models.HealthDegraded
+        }
+        if check.Status != models.HealthUp {
+            s.logger.Warn("Health check failed",
+                zap.String("check", name),
+                zap.String("status", string(check.Status)),
+                zap.String("error", check.Error))
+        }
+    }
+    return report
+}
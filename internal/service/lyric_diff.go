@@ -0,0 +1,346 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "strconv"
+    "strings"
+)
+
+// maxLyricDiffLines caps the lines on each side of a lyric diff. The diff
+// needs memory quadratic in the number of changed lines.
+const maxLyricDiffLines = 1000
+
+// lyricLine is a line of song text with the verse it belongs to; blank
+// lines between verses have verse 0.
+type lyricLine struct {
+    text  string
+    verse int
+}
+
+// lineEdit is one step of the edit script turning the old lines into the
+// new ones. old and new index the lines; an added line has no old index and
+// a removed line no new one.
+type lineEdit struct {
+    kind string
+    old  int
+    new  int
+}
+
+// DiffLyrics compares the text of a song on two sides, each a revision
+// number, models.DiffSideCurrent or models.DiffSideCandidate. A song that is
+// not live is only diffed with includeDeleted.
+func (s *SongService) DiffLyrics(ctx context.Context, id int, query *models.LyricDiffQuery, includeDeleted bool) (*models.LyricDiff, error) {
+    s.logger.Debug("Diffing song lyrics",
+        zap.Int("id", id),
+        zap.Any("query", query))
+
+    if query.Context < 0 {
+        return nil, &models.ValidationError{Field: "context", Message: "must not be negative"}
+    }
+
+    from, oldText, err := s.lyricDiffSide(ctx, id, "from", query.From, includeDeleted)
+    if err != nil {
+        return nil, err
+    }
+    to, newText, err := s.lyricDiffSide(ctx, id, "to", query.To, includeDeleted)
+    if err != nil {
+        return nil, err
+    }
+
+    oldLines := splitLyricLines(oldText)
+    newLines := splitLyricLines(newText)
+    if len(oldLines) > maxLyricDiffLines || len(newLines) > maxLyricDiffLines {
+        return nil, &models.ValidationError{Field: "text", Message: fmt.Sprintf("must have at most %d lines to be diffed", maxLyricDiffLines)}
+    }
+    edits := diffLines(oldLines, newLines)
+
+    diff := &models.LyricDiff{
+        SongID: id,
+        From:   *from,
+        To:     *to,
+        Hunks:  lyricHunks(oldLines, newLines, edits, query.Context),
+    }
+    for _, edit := range edits {
+        switch edit.kind {
+        case models.DiffLineAdded:
+            diff.Added++
+        case models.DiffLineRemoved:
+            diff.Removed++
+        }
+    }
+    if query.Unified {
+        diff.Unified = unifiedDiff(diff)
+    }
+    return diff, nil
+}
+
+// lyricDiffSide looks up the text named by value; param is the query
+// parameter it came from, for validation errors.
+func (s *SongService) lyricDiffSide(ctx context.Context, id int, param, value string, includeDeleted bool) (*models.LyricDiffSide, string, error) {
+    switch value {
+    case models.DiffSideCurrent:
+        song, err := s.repo.GetByID(id, includeDeleted)
+        if err != nil {
+            s.logger.Error("Failed to get song", zap.Error(err), zap.Int("id", id))
+            return nil, "", fmt.Errorf("failed to get song: %w", err)
+        }
+        return &models.LyricDiffSide{Source: models.DiffSideCurrent}, song.Text, nil
+    case models.DiffSideCandidate:
+        song, err := s.repo.GetByID(id, includeDeleted)
+        if err != nil {
+            s.logger.Error("Failed to get song", zap.Error(err), zap.Int("id", id))
+            return nil, "", fmt.Errorf("failed to get song: %w", err)
+        }
+        detail, err := s.enrichment.LookupSongInfo(ctx, song.GroupName, song.SongName)
+        if err != nil {
+            return nil, "", err
+        }
+        if detail.Text == "" {
+            return nil, "", fmt.Errorf("song info has no text: %w", models.ErrNotFound)
+        }
+        return &models.LyricDiffSide{Source: models.DiffSideCandidate, Provider: detail.Sources[models.FieldText]}, detail.Text, nil
+    }
+
+    revision, err := strconv.Atoi(value)
+    if err != nil || revision < 1 {
+        return nil, "", &models.ValidationError{Field: param, Message: "must be a revision number, current or candidate"}
+    }
+    rev, err := s.repo.Revision(id, revision, includeDeleted)
+    if err != nil {
+        s.logger.Error("Failed to get song revision",
+            zap.Error(err),
+            zap.Int("id", id),
+            zap.Int("revision", revision))
+        return nil, "", fmt.Errorf("failed to get song revision: %w", err)
+    }
+    return &models.LyricDiffSide{Source: models.DiffSideRevision, Revision: revision}, rev.Song.Text, nil
+}
+
+// splitLyricLines splits text into lines and numbers its verses the way
+// GetSongWithVerses does: verses are separated by blank lines.
+func splitLyricLines(text string) []lyricLine {
+    text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+    if text == "" {
+        return nil
+    }
+
+    var lines []lyricLine
+    verse := 0
+    inVerse := false
+    for _, line := range strings.Split(text, "\n") {
+        if strings.TrimSpace(line) == "" {
+            inVerse = false
+            lines = append(lines, lyricLine{text: line})
+            continue
+        }
+        if !inVerse {
+            verse++
+            inVerse = true
+        }
+        lines = append(lines, lyricLine{text: line, verse: verse})
+    }
+    return lines
+}
+
+// diffLines finds a shortest edit script with Myers' algorithm. Only the
+// diagonals reached in each round are kept for the backtrack, so memory
+// grows with the square of the number of edits rather than the line counts.
+func diffLines(a, b []lyricLine) []lineEdit {
+    n, m := len(a), len(b)
+    limit := n + m
+    offset := limit + 1
+    v := make([]int, 2*limit+3)
+    var trace [][]int
+
+    for d := 0; d <= limit; d++ {
+        done := false
+        for k := -d; k <= d; k += 2 {
+            var x int
+            if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+                x = v[offset+k+1]
+            } else {
+                x = v[offset+k-1] + 1
+            }
+            y := x - k
+            for x < n && y < m && a[x].text == b[y].text {
+                x++
+                y++
+            }
+            v[offset+k] = x
+            if x >= n && y >= m {
+                done = true
+                break
+            }
+        }
+        trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+        if done {
+            break
+        }
+    }
+
+    var edits []lineEdit
+    x, y := n, m
+    for d := len(trace) - 1; d > 0; d-- {
+        prev := trace[d-1]
+        at := func(k int) int { return prev[k+d-1] }
+
+        k := x - y
+        prevK := k - 1
+        if k == -d || (k != d && at(k-1) < at(k+1)) {
+            prevK = k + 1
+        }
+        prevX := at(prevK)
+        prevY := prevX - prevK
+
+        for x > prevX && y > prevY {
+            x--
+            y--
+            edits = append(edits, lineEdit{kind: models.DiffLineContext, old: x, new: y})
+        }
+        if prevK == k+1 {
+            edits = append(edits, lineEdit{kind: models.DiffLineAdded, old: -1, new: prevY})
+        } else {
+            edits = append(edits, lineEdit{kind: models.DiffLineRemoved, old: prevX, new: -1})
+        }
+        x, y = prevX, prevY
+    }
+    for x > 0 && y > 0 {
+        x--
+        y--
+        edits = append(edits, lineEdit{kind: models.DiffLineContext, old: x, new: y})
+    }
+
+    for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+        edits[i], edits[j] = edits[j], edits[i]
+    }
+    return edits
+}
+
+// lyricHunks groups the changes of an edit script into hunks with up to
+// contextLines unchanged lines around them. Changes closer together than
+// twice that share a hunk.
+func lyricHunks(a, b []lyricLine, edits []lineEdit, contextLines int) []models.DiffHunk {
+    hunks := []models.DiffHunk{}
+    oldBefore := make([]int, len(edits)+1)
+    newBefore := make([]int, len(edits)+1)
+    for i, edit := range edits {
+        oldBefore[i+1] = oldBefore[i]
+        newBefore[i+1] = newBefore[i]
+        if edit.old >= 0 {
+            oldBefore[i+1]++
+        }
+        if edit.new >= 0 {
+            newBefore[i+1]++
+        }
+    }
+
+    for i := 0; i < len(edits); {
+        if edits[i].kind == models.DiffLineContext {
+            i++
+            continue
+        }
+
+        // Extend the hunk over every change that follows within
+        // 2*contextLines unchanged lines.
+        first, last := i, i
+        for j := i + 1; j < len(edits) && j-last <= 2*contextLines+1; j++ {
+            if edits[j].kind != models.DiffLineContext {
+                last = j
+            }
+        }
+        start := max(first-contextLines, 0)
+        end := min(last+contextLines+1, len(edits))
+
+        hunk := models.DiffHunk{Lines: make([]models.DiffLine, 0, end-start)}
+        for _, edit := range edits[start:end] {
+            line := models.DiffLine{Kind: edit.kind}
+            if edit.old >= 0 {
+                line.Text = a[edit.old].text
+                line.OldLine = edit.old + 1
+                line.OldVerse = a[edit.old].verse
+                hunk.OldLines++
+            }
+            if edit.new >= 0 {
+                line.Text = b[edit.new].text
+                line.NewLine = edit.new + 1
+                line.NewVerse = b[edit.new].verse
+                hunk.NewLines++
+            }
+            hunk.Lines = append(hunk.Lines, line)
+        }
+
+        // As in a unified diff, an empty side starts at the line before it.
+        hunk.OldStart = oldBefore[start]
+        if hunk.OldLines > 0 {
+            hunk.OldStart++
+        }
+        hunk.NewStart = newBefore[start]
+        if hunk.NewLines > 0 {
+            hunk.NewStart++
+        }
+        if changed := hunk.Lines[first-start]; changed.Kind == models.DiffLineAdded {
+            hunk.Verse = changed.NewVerse
+        } else {
+            hunk.Verse = changed.OldVerse
+        }
+
+        hunks = append(hunks, hunk)
+        i = end
+    }
+    return hunks
+}
+
+// unifiedDiff renders the hunks of diff as a unified diff. Hunk headers name
+// the verse, the way git names the enclosing function.
+func unifiedDiff(diff *models.LyricDiff) string {
+    if len(diff.Hunks) == 0 {
+        return ""
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "--- %s\n+++ %s\n", lyricDiffLabel(&diff.From), lyricDiffLabel(&diff.To))
+    for _, hunk := range diff.Hunks {
+        fmt.Fprintf(&sb, "@@ -%s +%s @@", unifiedRange(hunk.OldStart, hunk.OldLines), unifiedRange(hunk.NewStart, hunk.NewLines))
+        if hunk.Verse > 0 {
+            fmt.Fprintf(&sb, " verse %d", hunk.Verse)
+        }
+        sb.WriteByte('\n')
+        for _, line := range hunk.Lines {
+            switch line.Kind {
+            case models.DiffLineAdded:
+                sb.WriteByte('+')
+            case models.DiffLineRemoved:
+                sb.WriteByte('-')
+            default:
+                sb.WriteByte(' ')
+            }
+            sb.WriteString(line.Text)
+            sb.WriteByte('\n')
+        }
+    }
+    return sb.String()
+}
+
+func lyricDiffLabel(side *models.LyricDiffSide) string {
+    switch side.Source {
+    case models.DiffSideRevision:
+        return "revision " + strconv.Itoa(side.Revision)
+    case models.DiffSideCandidate:
+        if side.Provider != "" {
+            return "candidate (" + side.Provider + ")"
+        }
+    }
+    return side.Source
+}
+
+// unifiedRange formats a hunk range, leaving out a count of one as diff
+// does.
+func unifiedRange(start, lines int) string {
+    if lines == 1 {
+        return strconv.Itoa(start)
+    }
+    return strconv.Itoa(start) + "," + strconv.Itoa(lines)
+}
@@ -0,0 +1,38 @@
+package service_test
+
+import (
+    "context"
+    "errors"
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "testing"
+)
+
+func TestDiffLyricsAgainstARevision(t *testing.T) {
+    s := testutil.NewServices().Songs
+    song := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    first := "Paranoia is in bloom\nThe PR transmissions will resume\n\nThey will not force us\nThey will stop degrading us"
+    for _, text := range []string{first, first + "\nThey will not control us"} {
+        song.Text = text
+        if err := s.UpdateSong(song, 0, models.ActorAnonymous); err != nil {
+            t.Fatalf("UpdateSong: %v", err)
+        }
+    }
+
+    diff, err := s.DiffLyrics(context.Background(), song.ID, &models.LyricDiffQuery{From: "2", To: models.DiffSideCurrent, Context: 1, Unified: true}, false)
+    if err != nil {
+        t.Fatalf("DiffLyrics: %v", err)
+    }
+    if diff.Added != 1 || diff.Removed != 0 || len(diff.Hunks) != 1 {
+        t.Fatalf("diff = +%d -%d in %d hunks, want +1 -0 in 1 hunk", diff.Added, diff.Removed, len(diff.Hunks))
+    }
+    want := "--- revision 2\n+++ current\n@@ -5 +5,2 @@ verse 2\n They will stop degrading us\n+They will not control us\n"
+    if diff.Unified != want {
+        t.Errorf("unified diff = %q, want %q", diff.Unified, want)
+    }
+
+    query := &models.LyricDiffQuery{From: "0", To: models.DiffSideCurrent}
+    if _, err := s.DiffLyrics(context.Background(), song.ID, query, false); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("DiffLyrics from revision 0: error = %v, want a validation error", err)
+    }
+}
@@ -1,46 +1,222 @@
 package service
 
 import (
+    "context"
     "encoding/json"
+    "errors"
     "fmt"
+    "io"
+    "math/rand"
     "music-library/internal/models"
     "net/http"
     "net/url"
+    "strconv"
+    "time"
 )
 
+// MusicAPIConfig tunes how MusicAPIClient talks to the music info API.
+type MusicAPIConfig struct {
+    // Timeout bounds each request, including reading the response.
+    Timeout time.Duration
+    // MaxRetries is the number of extra attempts after a 5xx, a 429 or a
+    // network error.
+    MaxRetries int
+    // BaseBackoff is the delay before the first retry; it doubles with each
+    // further retry, up to MaxBackoff. A Retry-After header overrides it,
+    // but a Retry-After longer than MaxBackoff ends the retries.
+    BaseBackoff time.Duration
+    MaxBackoff  time.Duration
+    // BreakerThreshold consecutive failed requests open the circuit breaker
+    // for BreakerCooldown.
+    BreakerThreshold int
+    BreakerCooldown  time.Duration
+}
+
+// DefaultMusicAPIConfig returns the settings used when none are configured.
+func DefaultMusicAPIConfig() MusicAPIConfig {
+    return MusicAPIConfig{
+        Timeout:          10 * time.Second,
+        MaxRetries:       3,
+        BaseBackoff:      200 * time.Millisecond,
+        MaxBackoff:       5 * time.Second,
+        BreakerThreshold: 5,
+        BreakerCooldown:  30 * time.Second,
+    }
+}
+
 type MusicAPIClient struct {
     baseURL string
     client  *http.Client
+    config  MusicAPIConfig
+    breaker *CircuitBreaker
 }
 
-func NewMusicAPIClient(baseURL string) *MusicAPIClient {
+func NewMusicAPIClient(baseURL string, config MusicAPIConfig) *MusicAPIClient {
     return &MusicAPIClient{
         baseURL: baseURL,
-        client:  &http.Client{},
+        client:  &http.Client{Timeout: config.Timeout},
+        config:  config,
+        breaker: NewCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
     }
 }
 
-func (c *MusicAPIClient) GetSongInfo(group, song string) (*models.SongDetail, error) {
+func (c *MusicAPIClient) Name() string {
+    return "music_api"
+}
+
+// retryableError is a failed attempt worth repeating. retryAfter is the
+// delay the upstream asked for, if any.
+type retryableError struct {
+    err        error
+    retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+func (c *MusicAPIClient) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
     params := url.Values{}
     params.Add("group", group)
     params.Add("song", song)
 
     url := fmt.Sprintf("%s/info?%s", c.baseURL, params.Encode())
-    
-    resp, err := c.client.Get(url)
+
+    for attempt := 0; ; attempt++ {
+        songDetail, err := c.fetch(ctx, url)
+        if err == nil {
+            return songDetail, nil
+        }
+
+        var retryable *retryableError
+        if !errors.As(err, &retryable) || attempt >= c.config.MaxRetries || ctx.Err() != nil {
+            return nil, err
+        }
+
+        delay := c.backoff(attempt)
+        if retryable.retryAfter > 0 {
+            if retryable.retryAfter > c.config.MaxBackoff {
+                return nil, err
+            }
+            delay = retryable.retryAfter
+        }
+
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return nil, fmt.Errorf("%w: %w", models.ErrUpstream, ctx.Err())
+        case <-timer.C:
+        }
+    }
+}
+
+// fetch makes a single request through the circuit breaker. Network errors,
+// timeouts and 5xx responses count as failures; a 429 means the upstream is
+// up, so it does not.
+func (c *MusicAPIClient) fetch(parent context.Context, url string) (*models.SongDetail, error) {
+    ctx, cancel := context.WithTimeout(parent, c.config.Timeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
     if err != nil {
-        return nil, fmt.Errorf("failed to make request: %w", err)
+        return nil, fmt.Errorf("%w: failed to create request: %w", models.ErrUpstream, err)
+    }
+
+    generation, err := c.breaker.Allow()
+    if err != nil {
+        return nil, fmt.Errorf("%w: %w", models.ErrUpstream, err)
+    }
+
+    resp, err := c.client.Do(req)
+    if err != nil && parent.Err() != nil {
+        // The caller gave up; that says nothing about the upstream.
+        c.breaker.Release(generation)
+        return nil, fmt.Errorf("%w: failed to make request: %w", models.ErrUpstream, err)
+    }
+    if err != nil {
+        c.breaker.Failure(generation)
+        return nil, &retryableError{err: fmt.Errorf("%w: failed to make request: %w", models.ErrUpstream, err)}
     }
     defer resp.Body.Close()
 
-    if resp.StatusCode != http.StatusOK {
-        return nil, fmt.Errorf("API returned non-200 status code: %d", resp.StatusCode)
+    switch {
+    case resp.StatusCode >= http.StatusInternalServerError:
+        c.breaker.Failure(generation)
+        return nil, &retryableError{
+            err:        fmt.Errorf("%w: API returned non-200 status code: %d", models.ErrUpstream, resp.StatusCode),
+            retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+        }
+    case resp.StatusCode == http.StatusTooManyRequests:
+        c.breaker.Success(generation)
+        return nil, &retryableError{
+            err:        fmt.Errorf("%w: API returned non-200 status code: %d", models.ErrUpstream, resp.StatusCode),
+            retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+        }
+    case resp.StatusCode == http.StatusNotFound:
+        c.breaker.Success(generation)
+        return nil, fmt.Errorf("%w: %w", models.ErrUpstream, ErrSongInfoNotFound)
+    case resp.StatusCode != http.StatusOK:
+        c.breaker.Success(generation)
+        return nil, fmt.Errorf("%w: API returned non-200 status code: %d", models.ErrUpstream, resp.StatusCode)
     }
 
     var songDetail models.SongDetail
-    if err := json.NewDecoder(resp.Body).Decode(&songDetail); err != nil {
-        return nil, fmt.Errorf("failed to decode response: %w", err)
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&songDetail); err != nil {
+        // A body cut off by the timeout is a failure of the upstream; a
+        // malformed one is not worth retrying.
+        if parent.Err() != nil {
+            c.breaker.Release(generation)
+            return nil, fmt.Errorf("%w: failed to read response: %w", models.ErrUpstream, err)
+        }
+        if ctx.Err() != nil {
+            c.breaker.Failure(generation)
+            return nil, &retryableError{err: fmt.Errorf("%w: failed to read response: %w", models.ErrUpstream, err)}
+        }
+        c.breaker.Success(generation)
+        return nil, fmt.Errorf("%w: failed to decode response: %w", models.ErrUpstream, err)
     }
 
+    c.breaker.Success(generation)
     return &songDetail, nil
 }
+
+// backoff is the delay before retry number attempt+1: exponential, with
+// up to half of it taken off at random.
+func (c *MusicAPIClient) backoff(attempt int) time.Duration {
+    backoff := c.config.MaxBackoff
+    if attempt < 20 {
+        backoff = min(c.config.BaseBackoff<<attempt, c.config.MaxBackoff)
+    }
+    return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+    if value == "" {
+        return 0
+    }
+    if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+        return time.Duration(seconds) * time.Second
+    }
+    if at, err := http.ParseTime(value); err == nil {
+        return max(time.Until(at), 0)
+    }
+    return 0
+}
+
+// CheckHealth reports the state of the circuit breaker in front of the
+// music info API.
+func (c *MusicAPIClient) CheckHealth(ctx context.Context) models.HealthCheck {
+    circuit := c.breaker.State()
+    check := models.HealthCheck{Status: models.HealthUp, Circuit: &circuit}
+    switch circuit.State {
+    case CircuitOpen:
+        check.Status = models.HealthDown
+        check.Error = ErrCircuitOpen.Error()
+    case CircuitHalfOpen:
+        check.Status = models.HealthDegraded
+    }
+    return check
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "music-library/internal/models"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// fakeMusicAPI serves the music info API from respond, which gets the
+// 1-based number of the request.
+type fakeMusicAPI struct {
+    server   *httptest.Server
+    requests atomic.Int32
+}
+
+func newFakeMusicAPI(t *testing.T, respond func(w http.ResponseWriter, r *http.Request, n int)) *fakeMusicAPI {
+    t.Helper()
+    api := &fakeMusicAPI{}
+    api.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        respond(w, r, int(api.requests.Add(1)))
+    }))
+    t.Cleanup(api.server.Close)
+    return api
+}
+
+func (api *fakeMusicAPI) count() int {
+    return int(api.requests.Load())
+}
+
+// testMusicAPIConfig retries quickly and keeps the breaker out of the way.
+func testMusicAPIConfig() MusicAPIConfig {
+    return MusicAPIConfig{
+        Timeout:          time.Second,
+        MaxRetries:       3,
+        BaseBackoff:      time.Millisecond,
+        MaxBackoff:       50 * time.Millisecond,
+        BreakerThreshold: 100,
+        BreakerCooldown:  time.Minute,
+    }
+}
+
+func writeSongDetail(w http.ResponseWriter) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Write([]byte(`{"releaseDate":"16.07.2006","text":"Ooh baby","link":"https://example.com/smbh"}`))
+}
+
+func TestGetSongInfo(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        if r.URL.Path != "/info" || r.URL.Query().Get("group") != "Muse" || r.URL.Query().Get("song") != "Supermassive Black Hole" {
+            t.Errorf("unexpected request %s", r.URL)
+        }
+        writeSongDetail(w)
+    })
+    client := NewMusicAPIClient(api.server.URL, testMusicAPIConfig())
+
+    detail, err := client.GetSongInfo(context.Background(), "Muse", "Supermassive Black Hole")
+    if err != nil {
+        t.Fatalf("GetSongInfo: %v", err)
+    }
+    if detail.Link != "https://example.com/smbh" || detail.Text != "Ooh baby" {
+        t.Errorf("detail = %+v", detail)
+    }
+}
+
+func TestGetSongInfoRetriesServerErrors(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        if n < 3 {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        writeSongDetail(w)
+    })
+    client := NewMusicAPIClient(api.server.URL, testMusicAPIConfig())
+
+    if _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising"); err != nil {
+        t.Fatalf("GetSongInfo: %v", err)
+    }
+    if api.count() != 3 {
+        t.Errorf("requests = %d, want 3", api.count())
+    }
+}
+
+func TestGetSongInfoGivesUpAfterMaxRetries(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        w.WriteHeader(http.StatusInternalServerError)
+    })
+    config := testMusicAPIConfig()
+    config.MaxRetries = 2
+    client := NewMusicAPIClient(api.server.URL, config)
+
+    _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising")
+    if !errors.Is(err, models.ErrUpstream) {
+        t.Fatalf("GetSongInfo error = %v, want an upstream error", err)
+    }
+    if api.count() != 3 {
+        t.Errorf("requests = %d, want 3", api.count())
+    }
+}
+
+func TestGetSongInfoDoesNotRetryClientErrors(t *testing.T) {
+    tests := []struct {
+        status   int
+        notFound bool
+    }{
+        {http.StatusBadRequest, false},
+        {http.StatusNotFound, true},
+    }
+    for _, tt := range tests {
+        api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+            w.WriteHeader(tt.status)
+        })
+        client := NewMusicAPIClient(api.server.URL, testMusicAPIConfig())
+
+        _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising")
+        if !errors.Is(err, models.ErrUpstream) {
+            t.Errorf("status %d: error = %v, want an upstream error", tt.status, err)
+        }
+        if got := errors.Is(err, ErrSongInfoNotFound); got != tt.notFound {
+            t.Errorf("status %d: not found = %v, want %v", tt.status, got, tt.notFound)
+        }
+        if api.count() != 1 {
+            t.Errorf("status %d: requests = %d, want 1", tt.status, api.count())
+        }
+    }
+}
+
+func TestGetSongInfoHonoursRetryAfter(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        if n == 1 {
+            w.Header().Set("Retry-After", "1")
+            w.WriteHeader(http.StatusTooManyRequests)
+            return
+        }
+        writeSongDetail(w)
+    })
+    config := testMusicAPIConfig()
+    config.MaxBackoff = 2 * time.Second
+    client := NewMusicAPIClient(api.server.URL, config)
+
+    start := time.Now()
+    if _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising"); err != nil {
+        t.Fatalf("GetSongInfo: %v", err)
+    }
+    if elapsed := time.Since(start); elapsed < time.Second {
+        t.Errorf("retried after %s, want at least the 1s Retry-After", elapsed)
+    }
+    if api.count() != 2 {
+        t.Errorf("requests = %d, want 2", api.count())
+    }
+}
+
+func TestGetSongInfoStopsOnLongRetryAfter(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        w.Header().Set("Retry-After", "60")
+        w.WriteHeader(http.StatusServiceUnavailable)
+    })
+    client := NewMusicAPIClient(api.server.URL, testMusicAPIConfig())
+
+    start := time.Now()
+    if _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising"); !errors.Is(err, models.ErrUpstream) {
+        t.Fatalf("GetSongInfo error = %v, want an upstream error", err)
+    }
+    if elapsed := time.Since(start); elapsed > 5*time.Second {
+        t.Errorf("gave up after %s, want no wait", elapsed)
+    }
+    if api.count() != 1 {
+        t.Errorf("requests = %d, want 1", api.count())
+    }
+}
+
+func TestGetSongInfoTimeout(t *testing.T) {
+    release := make(chan struct{})
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        select {
+        case <-release:
+        case <-r.Context().Done():
+        }
+    })
+    // Cleanups run last first, so the handlers are released before the
+    // server waits for them.
+    t.Cleanup(func() { close(release) })
+    config := testMusicAPIConfig()
+    config.Timeout = 50 * time.Millisecond
+    config.MaxRetries = 1
+    client := NewMusicAPIClient(api.server.URL, config)
+
+    start := time.Now()
+    _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising")
+    if !errors.Is(err, models.ErrUpstream) {
+        t.Fatalf("GetSongInfo error = %v, want an upstream error", err)
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("gave up after %s, want about two 50ms timeouts", elapsed)
+    }
+    if api.count() != 2 {
+        t.Errorf("requests = %d, want 2", api.count())
+    }
+    if state := client.breaker.State(); state.ConsecutiveFailures != 2 {
+        t.Errorf("consecutive failures = %d, want 2", state.ConsecutiveFailures)
+    }
+}
+
+func TestGetSongInfoCancelledByCaller(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        <-r.Context().Done()
+    })
+    client := NewMusicAPIClient(api.server.URL, testMusicAPIConfig())
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    if _, err := client.GetSongInfo(ctx, "Muse", "Uprising"); !errors.Is(err, models.ErrUpstream) {
+        t.Fatalf("GetSongInfo error = %v, want an upstream error", err)
+    }
+    if api.count() != 1 {
+        t.Errorf("requests = %d, want 1", api.count())
+    }
+    if state := client.breaker.State(); state.ConsecutiveFailures != 0 {
+        t.Errorf("consecutive failures = %d, want 0: the caller gave up, not the upstream", state.ConsecutiveFailures)
+    }
+}
+
+func TestMusicAPIBackoff(t *testing.T) {
+    client := NewMusicAPIClient("", MusicAPIConfig{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+    tests := []struct {
+        attempt int
+        full    time.Duration
+    }{
+        {0, 100 * time.Millisecond},
+        {1, 200 * time.Millisecond},
+        {3, 800 * time.Millisecond},
+        {4, time.Second},
+        {40, time.Second},
+    }
+    for _, tt := range tests {
+        for i := 0; i < 20; i++ {
+            if got := client.backoff(tt.attempt); got < tt.full/2 || got > tt.full {
+                t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.full/2, tt.full)
+            }
+        }
+    }
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    if got := parseRetryAfter("3"); got != 3*time.Second {
+        t.Errorf("parseRetryAfter(3) = %s, want 3s", got)
+    }
+    for _, value := range []string{"", "-1", "soon"} {
+        if got := parseRetryAfter(value); got != 0 {
+            t.Errorf("parseRetryAfter(%q) = %s, want 0", value, got)
+        }
+    }
+    at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+    if got := parseRetryAfter(at); got < 58*time.Second || got > time.Minute {
+        t.Errorf("parseRetryAfter(%q) = %s, want about a minute", at, got)
+    }
+}
+
+func TestMusicAPICircuitBreaker(t *testing.T) {
+    var healthy atomic.Bool
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        if !healthy.Load() {
+            w.WriteHeader(http.StatusBadGateway)
+            return
+        }
+        writeSongDetail(w)
+    })
+    config := testMusicAPIConfig()
+    config.MaxRetries = 0
+    config.BreakerThreshold = 2
+    config.BreakerCooldown = 100 * time.Millisecond
+    client := NewMusicAPIClient(api.server.URL, config)
+    ctx := context.Background()
+
+    // Two failures open the circuit; the next call fails without a request.
+    for i := 0; i < 2; i++ {
+        if _, err := client.GetSongInfo(ctx, "Muse", "Uprising"); errors.Is(err, ErrCircuitOpen) {
+            t.Fatalf("call %d: circuit open before the threshold", i+1)
+        }
+    }
+    if _, err := client.GetSongInfo(ctx, "Muse", "Uprising"); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, models.ErrUpstream) {
+        t.Fatalf("GetSongInfo error = %v, want an open circuit", err)
+    }
+    if api.count() != 2 {
+        t.Errorf("requests = %d, want 2", api.count())
+    }
+    if check := client.CheckHealth(ctx); check.Status != models.HealthDown {
+        t.Errorf("health = %s, want down", check.Status)
+    }
+
+    // After the cooldown a failed probe opens the circuit again.
+    time.Sleep(config.BreakerCooldown)
+    if _, err := client.GetSongInfo(ctx, "Muse", "Uprising"); errors.Is(err, ErrCircuitOpen) {
+        t.Fatal("probe rejected after the cooldown")
+    }
+    if state := client.breaker.State(); state.State != CircuitOpen {
+        t.Fatalf("state after a failed probe = %s, want open", state.State)
+    }
+
+    // A successful probe closes it.
+    healthy.Store(true)
+    time.Sleep(config.BreakerCooldown)
+    if _, err := client.GetSongInfo(ctx, "Muse", "Uprising"); err != nil {
+        t.Fatalf("probe: %v", err)
+    }
+    if state := client.breaker.State(); state.State != CircuitClosed || state.ConsecutiveFailures != 0 {
+        t.Errorf("state after a successful probe = %s with %d failures, want closed with 0", state.State, state.ConsecutiveFailures)
+    }
+    if api.count() != 4 {
+        t.Errorf("requests = %d, want 4", api.count())
+    }
+}
+
+func TestMusicAPIRateLimitDoesNotTripBreaker(t *testing.T) {
+    api := newFakeMusicAPI(t, func(w http.ResponseWriter, r *http.Request, n int) {
+        w.WriteHeader(http.StatusTooManyRequests)
+    })
+    config := testMusicAPIConfig()
+    config.BreakerThreshold = 2
+    client := NewMusicAPIClient(api.server.URL, config)
+
+    if _, err := client.GetSongInfo(context.Background(), "Muse", "Uprising"); !errors.Is(err, models.ErrUpstream) || errors.Is(err, ErrCircuitOpen) {
+        t.Fatalf("GetSongInfo error = %v, want a rate limit error", err)
+    }
+    if api.count() != config.MaxRetries+1 {
+        t.Errorf("requests = %d, want %d", api.count(), config.MaxRetries+1)
+    }
+    if state := client.breaker.State(); state.State != CircuitClosed {
+        t.Errorf("state = %s, want closed", state.State)
+    }
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+    breaker := NewCircuitBreaker(1, time.Hour)
+    slow, err := breaker.Allow()
+    if err != nil {
+        t.Fatalf("Allow: %v", err)
+    }
+    failed, err := breaker.Allow()
+    if err != nil {
+        t.Fatalf("Allow: %v", err)
+    }
+
+    breaker.Failure(failed)
+    if state := breaker.State(); state.State != CircuitOpen {
+        t.Fatalf("state after a failure = %s, want open", state.State)
+    }
+    // A call from before the breaker opened cannot close it.
+    breaker.Success(slow)
+    if state := breaker.State(); state.State != CircuitOpen {
+        t.Errorf("state after a stale success = %s, want open", state.State)
+    }
+    if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+        t.Errorf("Allow error = %v, want an open circuit", err)
+    }
+}
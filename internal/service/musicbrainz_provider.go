@@ -0,0 +1,130 @@
+package service
+
+import (
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "music-library/internal/models"
+    "os"
+    "strings"
+)
+
+// musicBrainzRecording holds the fields used from a recording in a
+// MusicBrainz JSON dump.
+type musicBrainzRecording struct {
+    Title        string `json:"title"`
+    ArtistCredit []struct {
+        Name       string `json:"name"`
+        JoinPhrase string `json:"joinphrase"`
+    } `json:"artist-credit"`
+    FirstReleaseDate string `json:"first-release-date"`
+    Relations        []struct {
+        Type string `json:"type"`
+        URL  struct {
+            Resource string `json:"resource"`
+        } `json:"url"`
+    } `json:"relations"`
+}
+
+// musicBrainzLinkTypes are the URL relation types used as a song's link,
+// most preferred first.
+var musicBrainzLinkTypes = []string{"free streaming", "streaming", "download for free", "purchase for download"}
+
+// MusicBrainzProvider serves release dates and links from a MusicBrainz
+// recording dump: one JSON recording per line, optionally gzip-compressed.
+// The dump has no lyrics, and only complete first release dates are used.
+// It is read once, when the provider is created.
+type MusicBrainzProvider struct {
+    songs map[string]models.SongDetail
+}
+
+func NewMusicBrainzProvider(path string) (*MusicBrainzProvider, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open MusicBrainz dump: %w", err)
+    }
+    defer file.Close()
+
+    var r io.Reader = file
+    if strings.HasSuffix(path, ".gz") {
+        gz, err := gzip.NewReader(file)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read MusicBrainz dump: %w", err)
+        }
+        defer gz.Close()
+        r = gz
+    }
+
+    provider := &MusicBrainzProvider{songs: make(map[string]models.SongDetail)}
+    decoder := json.NewDecoder(r)
+    for n := 1; ; n++ {
+        var recording musicBrainzRecording
+        if err := decoder.Decode(&recording); errors.Is(err, io.EOF) {
+            break
+        } else if err != nil {
+            return nil, fmt.Errorf("failed to parse MusicBrainz dump %s: recording %d: %w", path, n, err)
+        }
+        provider.add(&recording)
+    }
+    return provider, nil
+}
+
+// add indexes a recording. Recordings of the same song fill in each other's
+// missing fields, the first one in the dump winning.
+func (p *MusicBrainzProvider) add(recording *musicBrainzRecording) {
+    var artist strings.Builder
+    for _, credit := range recording.ArtistCredit {
+        artist.WriteString(credit.Name)
+        artist.WriteString(credit.JoinPhrase)
+    }
+    if strings.TrimSpace(artist.String()) == "" || strings.TrimSpace(recording.Title) == "" {
+        return
+    }
+
+    var detail models.SongDetail
+    // Partial dates such as "2006" or "2006-06" are not calendar dates.
+    if len(recording.FirstReleaseDate) == len(models.DateLayout) {
+        detail.ReleaseDate, _ = models.ParseDate(recording.FirstReleaseDate)
+    }
+    for _, linkType := range musicBrainzLinkTypes {
+        for _, relation := range recording.Relations {
+            if relation.Type == linkType && relation.URL.Resource != "" && detail.Link == "" {
+                detail.Link = relation.URL.Resource
+            }
+        }
+    }
+
+    key := models.SongInfoKey(artist.String(), recording.Title)
+    existing, ok := p.songs[key]
+    if !ok {
+        p.songs[key] = detail
+        return
+    }
+    if existing.ReleaseDate.IsZero() {
+        existing.ReleaseDate = detail.ReleaseDate
+    }
+    if existing.Link == "" {
+        existing.Link = detail.Link
+    }
+    p.songs[key] = existing
+}
+
+func (p *MusicBrainzProvider) Name() string {
+    return "musicbrainz"
+}
+
+func (p *MusicBrainzProvider) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    detail, ok := p.songs[models.SongInfoKey(group, song)]
+    if !ok {
+        return nil, ErrSongInfoNotFound
+    }
+    return &detail, nil
+}
+
+// Len is the number of songs in the dump.
+func (p *MusicBrainzProvider) Len() int {
+    return len(p.songs)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "strings"
+)
+
+// PlaylistService manages user-curated playlists. Deleting a song removes
+// it from every playlist it is on.
+type PlaylistService struct {
+    repo   repository.PlaylistStore
+    logger *zap.Logger
+}
+
+func NewPlaylistService(repo repository.PlaylistStore, logger *zap.Logger) *PlaylistService {
+    return &PlaylistService{
+        repo:   repo,
+        logger: logger,
+    }
+}
+
+func (s *PlaylistService) CreatePlaylist(playlist *models.Playlist) error {
+    s.logger.Info("Creating new playlist", zap.String("name", playlist.Name))
+
+    if err := validatePlaylistName(playlist); err != nil {
+        return err
+    }
+
+    if err := s.repo.Create(playlist); err != nil {
+        s.logger.Error("Failed to create playlist",
+            zap.Error(err),
+            zap.String("name", playlist.Name))
+        return fmt.Errorf("failed to create playlist: %w", err)
+    }
+
+    s.logger.Info("Successfully created playlist",
+        zap.Int("id", playlist.ID),
+        zap.String("name", playlist.Name))
+    return nil
+}
+
+// UpdatePlaylist renames a playlist and replaces its description.
+func (s *PlaylistService) UpdatePlaylist(playlist *models.Playlist) error {
+    s.logger.Info("Updating playlist",
+        zap.Int("id", playlist.ID),
+        zap.String("name", playlist.Name))
+
+    if err := validatePlaylistName(playlist); err != nil {
+        return err
+    }
+
+    if err := s.repo.Update(playlist); err != nil {
+        s.logger.Error("Failed to update playlist",
+            zap.Error(err),
+            zap.Int("id", playlist.ID))
+        return fmt.Errorf("failed to update playlist: %w", err)
+    }
+    return nil
+}
+
+func (s *PlaylistService) DeletePlaylist(id int) error {
+    s.logger.Info("Deleting playlist", zap.Int("id", id))
+
+    if err := s.repo.Delete(id); err != nil {
+        s.logger.Error("Failed to delete playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return fmt.Errorf("failed to delete playlist: %w", err)
+    }
+
+    s.logger.Info("Successfully deleted playlist", zap.Int("id", id))
+    return nil
+}
+
+// GetPlaylist returns a playlist with one page of its entries.
+func (s *PlaylistService) GetPlaylist(id int, pagination *models.PlaylistPagination) (*models.PlaylistWithEntries, error) {
+    s.logger.Debug("Getting playlist",
+        zap.Int("id", id),
+        zap.Int("page", pagination.Page),
+        zap.Int("page_size", pagination.PageSize))
+
+    if pagination.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if pagination.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    playlist, err := s.repo.GetByID(id)
+    if err != nil {
+        s.logger.Error("Failed to get playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get playlist: %w", err)
+    }
+
+    entries, err := s.repo.Entries(id, (pagination.Page-1)*pagination.PageSize, pagination.PageSize)
+    if err != nil {
+        s.logger.Error("Failed to get playlist entries",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get playlist entries: %w", err)
+    }
+    if entries == nil {
+        entries = []models.PlaylistEntry{}
+    }
+
+    totalPages := (playlist.TrackCount + pagination.PageSize - 1) / pagination.PageSize
+    return &models.PlaylistWithEntries{
+        Playlist: *playlist,
+        Entries:  entries,
+        PageInfo: models.PageInfo{
+            Page:       pagination.Page,
+            PageSize:   pagination.PageSize,
+            TotalPages: totalPages,
+            HasNext:    pagination.Page < totalPages,
+            HasPrev:    pagination.Page > 1,
+        },
+    }, nil
+}
+
+// GetPlaylistSongs returns a playlist with all of its songs, in playlist
+// order, for downloading it as a playlist file.
+func (s *PlaylistService) GetPlaylistSongs(id int) (*models.Playlist, []models.Song, error) {
+    s.logger.Debug("Getting playlist songs", zap.Int("id", id))
+
+    playlist, err := s.repo.GetByID(id)
+    if err != nil {
+        s.logger.Error("Failed to get playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, nil, fmt.Errorf("failed to get playlist: %w", err)
+    }
+
+    entries, err := s.repo.Entries(id, 0, playlist.TrackCount)
+    if err != nil {
+        s.logger.Error("Failed to get playlist entries",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, nil, fmt.Errorf("failed to get playlist entries: %w", err)
+    }
+
+    songs := make([]models.Song, len(entries))
+    for i, entry := range entries {
+        songs[i] = entry.Song
+    }
+    return playlist, songs, nil
+}
+
+func (s *PlaylistService) ListPlaylists(filter *models.PlaylistFilter) (*models.PlaylistList, error) {
+    s.logger.Debug("Listing playlists", zap.Any("filter", filter))
+
+    if filter.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if filter.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    playlists, err := s.repo.List(filter)
+    if err != nil {
+        s.logger.Error("Failed to list playlists", zap.Error(err))
+        return nil, fmt.Errorf("failed to list playlists: %w", err)
+    }
+
+    total, err := s.repo.Count(filter)
+    if err != nil {
+        s.logger.Error("Failed to count playlists", zap.Error(err))
+        return nil, fmt.Errorf("failed to count playlists: %w", err)
+    }
+
+    if playlists == nil {
+        playlists = []models.Playlist{}
+    }
+    totalPages := (total + filter.PageSize - 1) / filter.PageSize
+    return &models.PlaylistList{
+        Items: playlists,
+        Total: total,
+        PageInfo: models.PageInfo{
+            Page:       filter.Page,
+            PageSize:   filter.PageSize,
+            TotalPages: totalPages,
+            HasNext:    filter.Page < totalPages,
+            HasPrev:    filter.Page > 1,
+        },
+    }, nil
+}
+
+// AddSong adds a song to a playlist at add.Position, or at the end.
+func (s *PlaylistService) AddSong(id int, add *models.PlaylistAdd) (*models.PlaylistEntry, error) {
+    s.logger.Info("Adding song to playlist",
+        zap.Int("id", id),
+        zap.Int("song_id", add.SongID),
+        zap.Int("position", add.Position))
+
+    if add.Position < 0 {
+        return nil, &models.ValidationError{Field: "position", Message: "must be positive"}
+    }
+
+    entry, err := s.repo.AddEntry(id, add.SongID, add.Position)
+    if err != nil {
+        s.logger.Error("Failed to add song to playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to add song to playlist: %w", err)
+    }
+    return entry, nil
+}
+
+// RemoveSong removes the entry at position; later entries move up.
+func (s *PlaylistService) RemoveSong(id int, position int) error {
+    s.logger.Info("Removing song from playlist",
+        zap.Int("id", id),
+        zap.Int("position", position))
+
+    if err := s.repo.RemoveEntry(id, position); err != nil {
+        s.logger.Error("Failed to remove song from playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return fmt.Errorf("failed to remove song from playlist: %w", err)
+    }
+    return nil
+}
+
+func (s *PlaylistService) MoveSong(id int, move *models.PlaylistMove) (*models.PlaylistEntry, error) {
+    s.logger.Info("Moving song in playlist",
+        zap.Int("id", id),
+        zap.Int("from", move.From),
+        zap.Int("to", move.To))
+
+    entry, err := s.repo.MoveEntry(id, move.From, move.To)
+    if err != nil {
+        s.logger.Error("Failed to move song in playlist",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to move song in playlist: %w", err)
+    }
+    return entry, nil
+}
+
+func validatePlaylistName(playlist *models.Playlist) error {
+    playlist.Name = strings.TrimSpace(playlist.Name)
+    if playlist.Name == "" {
+        return &models.ValidationError{Field: "name", Message: "must not be empty"}
+    }
+    return nil
+}
@@ -0,0 +1,42 @@
+package service_test
+
+import (
+    "music-library/internal/models"
+    "music-library/internal/testutil"
+    "testing"
+)
+
+func TestPlaylistAddAndMove(t *testing.T) {
+    services := testutil.NewServices()
+    uprising := createTestSong(t, services.Songs, "Muse", "Uprising", models.Date{})
+    resistance := createTestSong(t, services.Songs, "Muse", "Resistance", models.Date{})
+
+    playlist := &models.Playlist{Name: "Road trip"}
+    if err := services.Playlists.CreatePlaylist(playlist); err != nil {
+        t.Fatalf("CreatePlaylist: %v", err)
+    }
+    // The same song may be on a playlist twice.
+    for _, id := range []int{uprising.ID, resistance.ID, uprising.ID} {
+        if _, err := services.Playlists.AddSong(playlist.ID, &models.PlaylistAdd{SongID: id}); err != nil {
+            t.Fatalf("AddSong(%d): %v", id, err)
+        }
+    }
+    if _, err := services.Playlists.MoveSong(playlist.ID, &models.PlaylistMove{From: 3, To: 1}); err != nil {
+        t.Fatalf("MoveSong: %v", err)
+    }
+
+    got, err := services.Playlists.GetPlaylist(playlist.ID, &models.PlaylistPagination{Page: 1, PageSize: 50})
+    if err != nil {
+        t.Fatalf("GetPlaylist: %v", err)
+    }
+    var ids []int
+    for i, entry := range got.Entries {
+        if entry.Position != i+1 {
+            t.Errorf("entry %d has position %d", i, entry.Position)
+        }
+        ids = append(ids, entry.Song.ID)
+    }
+    if want := []int{uprising.ID, uprising.ID, resistance.ID}; !equalIDs(ids, want) {
+        t.Errorf("playlist songs = %v, want %v", ids, want)
+    }
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "go.uber.org/zap"
+    "io"
+    "music-library/internal/models"
+    "net/url"
+    "strings"
+    "unicode/utf8"
+)
+
+// MaxImportRows caps the number of rows in one import request.
+const MaxImportRows = 50000
+
+// maxImportNameLength is the length, in characters, of the VARCHAR(255)
+// columns the group, song and link are stored in.
+const maxImportNameLength = 255
+
+// csvImportColumns maps accepted CSV header names onto ImportRow fields.
+var csvImportColumns = map[string]func(row *models.ImportRow) *string{
+    "group":        func(row *models.ImportRow) *string { return &row.GroupName },
+    "group_name":   func(row *models.ImportRow) *string { return &row.GroupName },
+    "song":         func(row *models.ImportRow) *string { return &row.SongName },
+    "song_name":    func(row *models.ImportRow) *string { return &row.SongName },
+    "releasedate":  func(row *models.ImportRow) *string { return &row.ReleaseDate },
+    "release_date": func(row *models.ImportRow) *string { return &row.ReleaseDate },
+    "text":         func(row *models.ImportRow) *string { return &row.Text },
+    "link":         func(row *models.ImportRow) *string { return &row.Link },
+    "language":     func(row *models.ImportRow) *string { return &row.Language },
+}
+
+// parsedRow is an import row after decoding: either the row or the reason it
+// could not be decoded.
+type parsedRow struct {
+    row models.ImportRow
+    err error
+}
+
+// ImportSongs validates and stores the songs in r, which holds a CSV file,
+// a JSON array or NDJSON according to format. Rows are not enriched from
+// the music API. Invalid rows and songs already in the library are reported
+// and skipped; with dryRun nothing is stored. actor is recorded as the
+// creator of the new songs.
+func (s *SongService) ImportSongs(r io.Reader, format string, dryRun bool, actor string) (*models.ImportReport, error) {
+    s.logger.Info("Importing songs",
+        zap.String("format", format),
+        zap.Bool("dry_run", dryRun))
+
+    rows, err := parseImport(r, format)
+    if err != nil {
+        return nil, err
+    }
+    if len(rows) > MaxImportRows {
+        return nil, &models.ValidationError{Field: "file", Message: fmt.Sprintf("has more than %d rows", MaxImportRows)}
+    }
+
+    report := &models.ImportReport{
+        DryRun:  dryRun,
+        Total:   len(rows),
+        Results: make([]models.ImportResult, len(rows)),
+    }
+
+    var songs []models.Song
+    var songRows []int
+    for i, parsed := range rows {
+        result := &report.Results[i]
+        result.Row = i + 1
+        result.Group = parsed.row.GroupName
+        result.Song = parsed.row.SongName
+
+        song, errs := importSong(parsed)
+        if len(errs) > 0 {
+            result.Status = models.ImportFailed
+            result.Errors = errs
+            continue
+        }
+        songs = append(songs, *song)
+        songRows = append(songRows, i)
+    }
+
+    if len(songs) > 0 {
+        statuses, err := s.repo.Import(songs, dryRun, actor)
+        if err != nil {
+            s.logger.Error("Failed to import songs", zap.Error(err))
+            return nil, fmt.Errorf("failed to import songs: %w", err)
+        }
+        for i, status := range statuses {
+            result := &report.Results[songRows[i]]
+            result.Status = status
+            result.SongID = songs[i].ID
+            result.Group = songs[i].GroupName
+        }
+    }
+
+    for _, result := range report.Results {
+        switch result.Status {
+        case models.ImportCreated:
+            report.Created++
+        case models.ImportDuplicate:
+            report.Duplicates++
+        case models.ImportFailed:
+            report.Failed++
+        }
+    }
+
+    s.logger.Info("Imported songs",
+        zap.Bool("dry_run", dryRun),
+        zap.Int("created", report.Created),
+        zap.Int("duplicates", report.Duplicates),
+        zap.Int("failed", report.Failed))
+
+    return report, nil
+}
+
+// importSong validates a decoded row and turns it into a song, or returns
+// every problem found with it.
+func importSong(parsed parsedRow) (*models.Song, []string) {
+    if parsed.err != nil {
+        return nil, []string{parsed.err.Error()}
+    }
+    row := parsed.row
+
+    var errs []string
+    fail := func(field, message string) {
+        errs = append(errs, (&models.ValidationError{Field: field, Message: message}).Error())
+    }
+
+    song := &models.Song{
+        GroupName: models.NormalizeArtistName(row.GroupName),
+        SongName:  strings.TrimSpace(row.SongName),
+        Text:      row.Text,
+        Link:      strings.TrimSpace(row.Link),
+        Language:  row.Language,
+    }
+    if song.GroupName == "" {
+        fail("group", "is required")
+    }
+    if song.SongName == "" {
+        fail("song", "is required")
+    }
+    if value := strings.TrimSpace(row.ReleaseDate); value != "" {
+        date, err := models.ParseDate(value)
+        if err != nil {
+            fail("releaseDate", "must be a date like 2006-07-16 or 16.07.2006")
+        }
+        song.ReleaseDate = date
+    }
+    if song.Link != "" {
+        if u, err := url.Parse(song.Link); err != nil || u.Scheme == "" || u.Host == "" {
+            fail("link", "must be an absolute URL")
+        }
+    }
+    if err := normalizeLanguage(&song.Language); err != nil {
+        fail("language", "is not a supported text search language")
+    }
+
+    // The whole import is copied in one transaction, so a row the database
+    // would reject has to be caught here rather than fail every row.
+    for _, field := range []struct {
+        name, value string
+        limited     bool
+    }{
+        {"group", song.GroupName, true},
+        {"song", song.SongName, true},
+        {"link", song.Link, true},
+        {"text", song.Text, false},
+    } {
+        switch {
+        case !utf8.ValidString(field.value) || strings.ContainsRune(field.value, 0):
+            fail(field.name, "must be valid UTF-8 text without NUL characters")
+        case field.limited && utf8.RuneCountInString(field.value) > maxImportNameLength:
+            fail(field.name, fmt.Sprintf("must be at most %d characters", maxImportNameLength))
+        }
+    }
+
+    if len(errs) > 0 {
+        return nil, errs
+    }
+    return song, nil
+}
+
+func parseImport(r io.Reader, format string) ([]parsedRow, error) {
+    switch format {
+    case models.ImportFormatCSV:
+        return parseImportCSV(r)
+    case models.ImportFormatJSON:
+        return parseImportJSON(r)
+    case models.ImportFormatNDJSON:
+        return parseImportNDJSON(r)
+    }
+    return nil, &models.ValidationError{Field: "format", Message: "must be csv, json or ndjson"}
+}
+
+// parseImportCSV reads a CSV file whose header names the columns. Unknown
+// columns are ignored.
+func parseImportCSV(r io.Reader) ([]parsedRow, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+
+    header, err := reader.Read()
+    if errors.Is(err, io.EOF) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, &models.ValidationError{Field: "file", Message: "is not valid CSV: " + err.Error()}
+    }
+
+    columns := make([]func(row *models.ImportRow) *string, len(header))
+    var hasGroup, hasSong bool
+    for i, name := range header {
+        name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+        columns[i] = csvImportColumns[name]
+        hasGroup = hasGroup || name == "group" || name == "group_name"
+        hasSong = hasSong || name == "song" || name == "song_name"
+    }
+    if !hasGroup || !hasSong {
+        return nil, &models.ValidationError{Field: "file", Message: "CSV header must name the group and song columns"}
+    }
+
+    var rows []parsedRow
+    for {
+        record, err := reader.Read()
+        if errors.Is(err, io.EOF) {
+            return rows, nil
+        }
+        if err != nil {
+            var parseErr *csv.ParseError
+            if errors.As(err, &parseErr) {
+                rows = append(rows, parsedRow{err: fmt.Errorf("invalid CSV: %w", parseErr.Err)})
+                continue
+            }
+            return nil, err
+        }
+
+        var row models.ImportRow
+        for i, value := range record {
+            if i < len(columns) && columns[i] != nil {
+                *columns[i](&row) = value
+            }
+        }
+        rows = append(rows, parsedRow{row: row})
+    }
+}
+
+// parseImportJSON reads a JSON array of rows. Each element is decoded on its
+// own, so a malformed element only fails its row.
+func parseImportJSON(r io.Reader) ([]parsedRow, error) {
+    var elements []json.RawMessage
+    if err := json.NewDecoder(r).Decode(&elements); err != nil {
+        return nil, &models.ValidationError{Field: "file", Message: "must be a JSON array of songs"}
+    }
+
+    rows := make([]parsedRow, len(elements))
+    for i, element := range elements {
+        rows[i] = decodeImportRow(element)
+    }
+    return rows, nil
+}
+
+// parseImportNDJSON reads one JSON row per line, skipping blank lines.
+func parseImportNDJSON(r io.Reader) ([]parsedRow, error) {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+    var rows []parsedRow
+    for scanner.Scan() {
+        line := bytes.TrimSpace(scanner.Bytes())
+        if len(line) == 0 {
+            continue
+        }
+        rows = append(rows, decodeImportRow(line))
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, &models.ValidationError{Field: "file", Message: "is not valid NDJSON: " + err.Error()}
+    }
+    return rows, nil
+}
+
+func decodeImportRow(data []byte) parsedRow {
+    var row models.ImportRow
+    if err := json.Unmarshal(data, &row); err != nil {
+        return parsedRow{err: errors.New("row is not a valid song object")}
+    }
+    return parsedRow{row: row}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+    "container/list"
+    "context"
+    "errors"
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// ErrSongInfoNotFound is returned when a provider does not know a song.
+// Unlike other upstream errors it is cached.
+var ErrSongInfoNotFound = errors.New("song info not found")
+
+type refreshSongInfoKey struct{}
+
+// WithSongInfoRefresh marks ctx so that CachedSongInfoClient fetches fresh
+// details and replaces whatever it has cached.
+func WithSongInfoRefresh(ctx context.Context) context.Context {
+    return context.WithValue(ctx, refreshSongInfoKey{}, true)
+}
+
+func songInfoRefresh(ctx context.Context) bool {
+    refresh, _ := ctx.Value(refreshSongInfoKey{}).(bool)
+    return refresh
+}
+
+// CachedSongInfoClient caches the lookups of a SongInfoProvider in an
+// in-process LRU and, optionally, in a store shared with other instances.
+// Details are kept for ttl, and songs the upstream does not know for
+// negativeTTL. Other errors are not cached.
+type CachedSongInfoClient struct {
+    next        SongInfoProvider
+    lru         *songInfoLRU
+    shared      repository.SongInfoCacheStore
+    ttl         time.Duration
+    negativeTTL time.Duration
+    logger      *zap.Logger
+
+    hits         atomic.Int64
+    negativeHits atomic.Int64
+    misses       atomic.Int64
+}
+
+// NewCachedSongInfoClient wraps next. shared may be nil.
+func NewCachedSongInfoClient(next SongInfoProvider, size int, ttl, negativeTTL time.Duration, shared repository.SongInfoCacheStore, logger *zap.Logger) *CachedSongInfoClient {
+    return &CachedSongInfoClient{
+        next:        next,
+        lru:         newSongInfoLRU(size),
+        shared:      shared,
+        ttl:         ttl,
+        negativeTTL: negativeTTL,
+        logger:      logger,
+    }
+}
+
+// Name is the name of the cached provider.
+func (c *CachedSongInfoClient) Name() string {
+    return c.next.Name()
+}
+
+func (c *CachedSongInfoClient) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    key := models.SongInfoKey(group, song)
+
+    if !songInfoRefresh(ctx) {
+        if entry := c.lookup(key); entry != nil {
+            if entry.NotFound {
+                c.negativeHits.Add(1)
+                return nil, fmt.Errorf("%w: %w", models.ErrUpstream, ErrSongInfoNotFound)
+            }
+            c.hits.Add(1)
+            detail := *entry.Detail
+            return &detail, nil
+        }
+    }
+    c.misses.Add(1)
+
+    detail, err := c.next.GetSongInfo(ctx, group, song)
+    switch {
+    case err == nil:
+        stored := *detail
+        c.store(key, &models.CachedSongInfo{Detail: &stored, ExpiresAt: time.Now().Add(c.ttl)})
+    case errors.Is(err, ErrSongInfoNotFound):
+        c.store(key, &models.CachedSongInfo{NotFound: true, ExpiresAt: time.Now().Add(c.negativeTTL)})
+    }
+    return detail, err
+}
+
+// lookup checks the LRU, then the shared store. A failing shared store is
+// treated as a miss.
+func (c *CachedSongInfoClient) lookup(key string) *models.CachedSongInfo {
+    if entry, ok := c.lru.get(key); ok {
+        return entry
+    }
+    if c.shared == nil {
+        return nil
+    }
+
+    entry, err := c.shared.Get(key)
+    if err != nil {
+        if !errors.Is(err, models.ErrNotFound) {
+            c.logger.Error("Failed to read song info cache", zap.Error(err))
+        }
+        return nil
+    }
+    c.lru.add(key, entry)
+    return entry
+}
+
+func (c *CachedSongInfoClient) store(key string, entry *models.CachedSongInfo) {
+    c.lru.add(key, entry)
+    if c.shared == nil {
+        return
+    }
+    if err := c.shared.Put(key, entry); err != nil {
+        c.logger.Error("Failed to write song info cache", zap.Error(err))
+    }
+}
+
+func (c *CachedSongInfoClient) Stats() models.CacheStats {
+    entries, capacity := c.lru.size()
+    return models.CacheStats{
+        Hits:         c.hits.Load(),
+        NegativeHits: c.negativeHits.Load(),
+        Misses:       c.misses.Load(),
+        Entries:      entries,
+        Capacity:     capacity,
+    }
+}
+
+// CheckHealth reports the cache's hit and miss counts. The cache itself is
+// always up.
+func (c *CachedSongInfoClient) CheckHealth(ctx context.Context) models.HealthCheck {
+    stats := c.Stats()
+    return models.HealthCheck{Status: models.HealthUp, Cache: &stats}
+}
+
+// songInfoLRU holds up to capacity entries, evicting the least recently
+// used one when full. Expired entries are dropped when they are read.
+type songInfoLRU struct {
+    mu       sync.Mutex
+    capacity int
+    order    *list.List // front is the most recently used
+    items    map[string]*list.Element
+}
+
+type songInfoLRUItem struct {
+    key   string
+    entry *models.CachedSongInfo
+}
+
+func newSongInfoLRU(capacity int) *songInfoLRU {
+    return &songInfoLRU{
+        capacity: capacity,
+        order:    list.New(),
+        items:    make(map[string]*list.Element),
+    }
+}
+
+func (l *songInfoLRU) get(key string) (*models.CachedSongInfo, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    elem, ok := l.items[key]
+    if !ok {
+        return nil, false
+    }
+    item := elem.Value.(*songInfoLRUItem)
+    if !time.Now().Before(item.entry.ExpiresAt) {
+        l.order.Remove(elem)
+        delete(l.items, key)
+        return nil, false
+    }
+    l.order.MoveToFront(elem)
+    return item.entry, true
+}
+
+func (l *songInfoLRU) add(key string, entry *models.CachedSongInfo) {
+    if l.capacity <= 0 {
+        return
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if elem, ok := l.items[key]; ok {
+        elem.Value.(*songInfoLRUItem).entry = entry
+        l.order.MoveToFront(elem)
+        return
+    }
+
+    l.items[key] = l.order.PushFront(&songInfoLRUItem{key: key, entry: entry})
+    if l.order.Len() > l.capacity {
+        oldest := l.order.Back()
+        l.order.Remove(oldest)
+        delete(l.items, oldest.Value.(*songInfoLRUItem).key)
+    }
+}
+
+func (l *songInfoLRU) size() (int, int) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.order.Len(), l.capacity
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "testing"
+    "time"
+)
+
+// countingSongInfo knows only Muse's Uprising and counts its lookups.
+type countingSongInfo struct {
+    calls int
+}
+
+func (c *countingSongInfo) Name() string {
+    return "counting"
+}
+
+func (c *countingSongInfo) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    c.calls++
+    if group != "Muse" || song != "Uprising" {
+        return nil, ErrSongInfoNotFound
+    }
+    return &models.SongDetail{Link: "https://example.com/uprising"}, nil
+}
+
+func TestCachedSongInfoClient(t *testing.T) {
+    next := &countingSongInfo{}
+    client := NewCachedSongInfoClient(next, 10, time.Hour, time.Hour, nil, zap.NewNop())
+    ctx := context.Background()
+
+    for i := 0; i < 2; i++ {
+        detail, err := client.GetSongInfo(ctx, "Muse", "Uprising")
+        if err != nil || detail.Link != "https://example.com/uprising" {
+            t.Fatalf("GetSongInfo = %+v, %v", detail, err)
+        }
+        if _, err := client.GetSongInfo(ctx, "Muse", "Unknown"); !errors.Is(err, ErrSongInfoNotFound) {
+            t.Fatalf("GetSongInfo of an unknown song: error = %v, want not found", err)
+        }
+    }
+    if next.calls != 2 {
+        t.Errorf("upstream lookups = %d, want 2", next.calls)
+    }
+
+    if _, err := client.GetSongInfo(WithSongInfoRefresh(ctx), "Muse", "Uprising"); err != nil {
+        t.Fatalf("GetSongInfo with refresh: %v", err)
+    }
+    if next.calls != 3 {
+        t.Errorf("upstream lookups after a refresh = %d, want 3", next.calls)
+    }
+
+    stats := client.Stats()
+    if stats.Hits != 1 || stats.NegativeHits != 1 || stats.Misses != 3 || stats.Entries != 2 {
+        t.Errorf("stats = %+v", stats)
+    }
+}
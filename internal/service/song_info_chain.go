@@ -0,0 +1,167 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "music-library/internal/models"
+    "strings"
+    "sync"
+)
+
+// SongInfoProvider is a named source of song details. It returns
+// ErrSongInfoNotFound for songs it does not know.
+type SongInfoProvider interface {
+    SongInfoClient
+    Name() string
+}
+
+// SongInfoChain asks several providers for a song and merges their answers
+// field by field. For every field the value of the first provider in that
+// field's precedence that has one wins; the default precedence is the order
+// of the providers.
+type SongInfoChain struct {
+    providers  []SongInfoProvider
+    precedence map[string][]int // field -> provider indexes, most preferred first
+}
+
+// NewSongInfoChain builds a chain over providers. precedence lists, per
+// field, provider names in the order they are preferred; providers left out
+// follow in chain order.
+func NewSongInfoChain(providers []SongInfoProvider, precedence map[string][]string) (*SongInfoChain, error) {
+    if len(providers) == 0 {
+        return nil, errors.New("no song info providers configured")
+    }
+
+    index := make(map[string]int, len(providers))
+    for i, provider := range providers {
+        if _, ok := index[provider.Name()]; ok {
+            return nil, fmt.Errorf("song info provider %q is listed twice", provider.Name())
+        }
+        index[provider.Name()] = i
+    }
+
+    chain := &SongInfoChain{providers: providers, precedence: make(map[string][]int)}
+    for field, names := range precedence {
+        if !models.IsEnrichableField(field) {
+            return nil, fmt.Errorf("unknown song info field %q", field)
+        }
+        for _, name := range names {
+            i, ok := index[name]
+            if !ok {
+                return nil, fmt.Errorf("unknown song info provider %q in precedence of %s", name, field)
+            }
+            chain.precedence[field] = append(chain.precedence[field], i)
+        }
+    }
+    for _, field := range models.EnrichableFields {
+        for i := range providers {
+            if !containsInt(chain.precedence[field], i) {
+                chain.precedence[field] = append(chain.precedence[field], i)
+            }
+        }
+    }
+    return chain, nil
+}
+
+// ParseSongInfoPrecedence parses a precedence setting such as
+// "text=catalogue,music_api;releaseDate=musicbrainz".
+func ParseSongInfoPrecedence(spec string) (map[string][]string, error) {
+    precedence := make(map[string][]string)
+    for _, rule := range strings.Split(spec, ";") {
+        rule = strings.TrimSpace(rule)
+        if rule == "" {
+            continue
+        }
+        field, names, ok := strings.Cut(rule, "=")
+        if !ok {
+            return nil, fmt.Errorf("invalid song info precedence %q: want field=provider,...", rule)
+        }
+        field = strings.TrimSpace(field)
+        for _, name := range strings.Split(names, ",") {
+            if name = strings.TrimSpace(name); name != "" {
+                precedence[field] = append(precedence[field], name)
+            }
+        }
+    }
+    return precedence, nil
+}
+
+type providerAnswer struct {
+    detail *models.SongDetail
+    err    error
+}
+
+// GetSongInfo queries all providers at once. A field is taken from the
+// most preferred provider that has a value for it. If a provider failed
+// before a field could be decided, the lookup fails as a whole, so that
+// enrichment retries rather than settling for a less preferred value.
+// detail.Sources records the provider of each field.
+func (c *SongInfoChain) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    answers := make([]providerAnswer, len(c.providers))
+    var wg sync.WaitGroup
+    for i, provider := range c.providers {
+        wg.Add(1)
+        go func(i int, provider SongInfoProvider) {
+            defer wg.Done()
+            detail, err := provider.GetSongInfo(ctx, group, song)
+            answers[i] = providerAnswer{detail: detail, err: err}
+        }(i, provider)
+    }
+    wg.Wait()
+
+    merged := &models.SongDetail{Sources: make(map[string]string)}
+    found := false
+    for _, field := range models.EnrichableFields {
+        for _, i := range c.precedence[field] {
+            answer := answers[i]
+            if errors.Is(answer.err, ErrSongInfoNotFound) {
+                continue
+            }
+            if answer.err != nil {
+                return nil, fmt.Errorf("%s: %w", c.providers[i].Name(), answer.err)
+            }
+            found = true
+            if setSongInfoField(merged, answer.detail, field) {
+                merged.Sources[field] = c.providers[i].Name()
+                break
+            }
+        }
+    }
+
+    if !found {
+        return nil, ErrSongInfoNotFound
+    }
+    return merged, nil
+}
+
+// setSongInfoField copies field from src to dst if src has a value for it.
+func setSongInfoField(dst, src *models.SongDetail, field string) bool {
+    switch field {
+    case models.FieldReleaseDate:
+        if src.ReleaseDate.IsZero() {
+            return false
+        }
+        dst.ReleaseDate = src.ReleaseDate
+    case models.FieldText:
+        if src.Text == "" {
+            return false
+        }
+        dst.Text = src.Text
+    case models.FieldLink:
+        if src.Link == "" {
+            return false
+        }
+        dst.Link = src.Link
+    }
+    return true
+}
+
+func containsInt(values []int, value int) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "music-library/internal/models"
+    "testing"
+    "time"
+)
+
+// staticProvider answers every lookup with detail and err.
+type staticProvider struct {
+    name   string
+    detail *models.SongDetail
+    err    error
+}
+
+func (p *staticProvider) Name() string {
+    return p.name
+}
+
+func (p *staticProvider) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    return p.detail, p.err
+}
+
+func TestSongInfoChainMergesByPrecedence(t *testing.T) {
+    api := &staticProvider{name: "music_api", detail: &models.SongDetail{
+        ReleaseDate: models.NewDate(2009, time.September, 7),
+        Text:        "Paranoia is in bloom",
+    }}
+    catalogue := &staticProvider{name: "catalogue", detail: &models.SongDetail{
+        Text: "Paranoia is in bloom, the PR transmissions will resume",
+        Link: "https://example.com/uprising",
+    }}
+    missing := &staticProvider{name: "musicbrainz", err: ErrSongInfoNotFound}
+
+    precedence, err := ParseSongInfoPrecedence("text=catalogue")
+    if err != nil {
+        t.Fatalf("ParseSongInfoPrecedence: %v", err)
+    }
+    chain, err := NewSongInfoChain([]SongInfoProvider{api, catalogue, missing}, precedence)
+    if err != nil {
+        t.Fatalf("NewSongInfoChain: %v", err)
+    }
+
+    detail, err := chain.GetSongInfo(context.Background(), "Muse", "Uprising")
+    if err != nil {
+        t.Fatalf("GetSongInfo: %v", err)
+    }
+    if detail.Text != catalogue.detail.Text || detail.ReleaseDate != api.detail.ReleaseDate || detail.Link != catalogue.detail.Link {
+        t.Errorf("merged detail = %+v", detail)
+    }
+    want := map[string]string{models.FieldReleaseDate: "music_api", models.FieldText: "catalogue", models.FieldLink: "catalogue"}
+    for field, source := range want {
+        if detail.Sources[field] != source {
+            t.Errorf("source of %s = %q, want %q", field, detail.Sources[field], source)
+        }
+    }
+
+    // A failing provider that could still decide a field fails the lookup.
+    catalogue.err = errors.New("catalogue unavailable")
+    if _, err := chain.GetSongInfo(context.Background(), "Muse", "Uprising"); err == nil {
+        t.Error("GetSongInfo with a failing preferred provider succeeded")
+    }
+
+    if _, err := NewSongInfoChain([]SongInfoProvider{api}, map[string][]string{"text": {"catalogue"}}); err == nil {
+        t.Error("NewSongInfoChain with an unknown provider in the precedence succeeded")
+    }
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "music-library/internal/models"
+    "reflect"
+    "strings"
+)
+
+// JSONPatchOperation is a single RFC 6902 operation.
+type JSONPatchOperation struct {
+    Op    string          `json:"op"`
+    Path  string          `json:"path"`
+    From  string          `json:"from,omitempty"`
+    Value json.RawMessage `json:"value,omitempty"`
+}
+
+// requiredSongFields cannot be removed or set to null, matching the
+// binding rules on models.Song.
+var requiredSongFields = map[string]bool{"group": true, "song": true}
+
+var jsonNull = json.RawMessage("null")
+
+// ParseMergePatch decodes an RFC 7396 merge patch document. Setting an
+// optional field to null clears it.
+func ParseMergePatch(data []byte) (*models.SongPatch, error) {
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+        return nil, &models.ValidationError{Field: "body", Message: "must be a JSON object"}
+    }
+
+    patch := &models.SongPatch{}
+    for name, raw := range doc {
+        if err := setPatchField(patch, name, raw); err != nil {
+            return nil, err
+        }
+    }
+
+    return patch, nil
+}
+
+// ParseJSONPatch decodes an RFC 6902 JSON Patch document.
+func ParseJSONPatch(data []byte) ([]JSONPatchOperation, error) {
+    var ops []JSONPatchOperation
+    if err := json.Unmarshal(data, &ops); err != nil {
+        return nil, &models.ValidationError{Field: "body", Message: "must be an array of patch operations"}
+    }
+    return ops, nil
+}
+
+// jsonPatchToSongPatch applies ops to the patchable fields of song and
+// returns the fields they touched.
+func jsonPatchToSongPatch(song *models.Song, ops []JSONPatchOperation) (*models.SongPatch, error) {
+    doc := map[string]json.RawMessage{
+        "group":       mustMarshal(song.GroupName),
+        "song":        mustMarshal(song.SongName),
+        "releaseDate": mustMarshal(song.ReleaseDate.String()),
+        "text":        mustMarshal(song.Text),
+        "link":        mustMarshal(song.Link),
+        "language":    mustMarshal(song.Language),
+    }
+    touched := make(map[string]bool)
+
+    for i, op := range ops {
+        path, err := patchPointer(op.Path, doc)
+        if err != nil {
+            return nil, err
+        }
+
+        switch op.Op {
+        case "add", "replace":
+            if op.Value == nil {
+                return nil, &models.ValidationError{Field: fmt.Sprintf("[%d].value", i), Message: "is required"}
+            }
+            doc[path] = op.Value
+            touched[path] = true
+        case "remove":
+            doc[path] = jsonNull
+            touched[path] = true
+        case "move", "copy":
+            from, err := patchPointer(op.From, doc)
+            if err != nil {
+                return nil, err
+            }
+            doc[path] = doc[from]
+            touched[path] = true
+            if op.Op == "move" && from != path {
+                doc[from] = jsonNull
+                touched[from] = true
+            }
+        case "test":
+            if !jsonEqual(doc[path], op.Value) {
+                return nil, fmt.Errorf("test operation on %s failed: %w", op.Path, models.ErrConflict)
+            }
+        default:
+            return nil, &models.ValidationError{Field: fmt.Sprintf("[%d].op", i), Message: "must be one of: add remove replace move copy test"}
+        }
+    }
+
+    patch := &models.SongPatch{}
+    for name := range touched {
+        if err := setPatchField(patch, name, doc[name]); err != nil {
+            return nil, err
+        }
+    }
+
+    return patch, nil
+}
+
+// patchPointer resolves a JSON pointer to a top-level song field.
+func patchPointer(pointer string, doc map[string]json.RawMessage) (string, error) {
+    if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+        return "", &models.ValidationError{Field: pointer, Message: "is not a patchable song field"}
+    }
+
+    name := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+    if _, ok := doc[name]; !ok {
+        return "", &models.ValidationError{Field: pointer, Message: "is not a patchable song field"}
+    }
+
+    return name, nil
+}
+
+func setPatchField(patch *models.SongPatch, name string, raw json.RawMessage) error {
+    var value string
+    if bytes.Equal(bytes.TrimSpace(raw), jsonNull) {
+        if requiredSongFields[name] {
+            return &models.ValidationError{Field: name, Message: "is required"}
+        }
+    } else if err := json.Unmarshal(raw, &value); err != nil {
+        return &models.ValidationError{Field: name, Message: "must be a string"}
+    }
+
+    if requiredSongFields[name] && value == "" {
+        return &models.ValidationError{Field: name, Message: "is required"}
+    }
+
+    switch name {
+    case "group":
+        patch.GroupName = &value
+    case "song":
+        patch.SongName = &value
+    case "releaseDate":
+        date, err := models.ParseDate(value)
+        if err != nil {
+            return &models.ValidationError{Field: name, Message: "must be a date in DD.MM.YYYY or YYYY-MM-DD format"}
+        }
+        patch.ReleaseDate = &date
+    case "text":
+        patch.Text = &value
+    case "link":
+        patch.Link = &value
+    case "language":
+        if err := normalizeLanguage(&value); err != nil {
+            return err
+        }
+        patch.Language = &value
+    default:
+        return &models.ValidationError{Field: name, Message: "is not a patchable song field"}
+    }
+
+    return nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+    var va, vb interface{}
+    if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+        return false
+    }
+    return reflect.DeepEqual(va, vb)
+}
+
+func mustMarshal(value string) json.RawMessage {
+    data, _ := json.Marshal(value)
+    return data
+}
@@ -1,48 +1,49 @@
 package service
 
 import (
+    "context"
+    "errors"
     "fmt"
     "go.uber.org/zap"
     "music-library/internal/models"
     "music-library/internal/repository"
     "strings"
+    "time"
 )
 
+// SongInfoClient fetches song details from an external source.
+type SongInfoClient interface {
+    GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error)
+}
+
 type SongService struct {
-    repo      *repository.SongRepository
-    apiClient *MusicAPIClient
-    logger    *zap.Logger
+    repo       repository.SongStore
+    enrichment *EnrichmentService
+    logger     *zap.Logger
 }
 
-func NewSongService(repo *repository.SongRepository, apiClient *MusicAPIClient, logger *zap.Logger) *SongService {
+func NewSongService(repo repository.SongStore, enrichment *EnrichmentService, logger *zap.Logger) *SongService {
     return &SongService{
-        repo:      repo,
-        apiClient: apiClient,
-        logger:    logger,
+        repo:       repo,
+        enrichment: enrichment,
+        logger:     logger,
     }
 }
 
-func (s *SongService) CreateSong(song *models.Song) error {
+// CreateSong stores a new song; actor is recorded as who created it.
+func (s *SongService) CreateSong(song *models.Song, actor string) error {
     s.logger.Info("Creating new song",
         zap.String("group", song.GroupName),
         zap.String("song", song.SongName))
 
-    // Get additional info from external API
-    songDetail, err := s.apiClient.GetSongInfo(song.GroupName, song.SongName)
-    if err != nil {
-        s.logger.Error("Failed to get song info from API",
-            zap.Error(err),
-            zap.String("group", song.GroupName),
-            zap.String("song", song.SongName))
-        return fmt.Errorf("failed to get song info: %w", err)
+    if err := normalizeLanguage(&song.Language); err != nil {
+        return err
     }
 
-    // Enrich song with API data
-    song.ReleaseDate = songDetail.ReleaseDate
-    song.Text = songDetail.Text
-    song.Link = songDetail.Link
+    // The details are fetched from the music info API in the background.
+    song.EnrichmentStatus = models.EnrichmentPending
 
-    if err := s.repo.Create(song); err != nil {
+    if err := s.repo.Create(song, actor); err != nil {
         s.logger.Error("Failed to create song in database",
             zap.Error(err),
             zap.String("group", song.GroupName),
@@ -55,16 +56,23 @@ func (s *SongService) CreateSong(song *models.Song) error {
         zap.String("group", song.GroupName),
         zap.String("song", song.SongName))
 
+    s.enrichment.Wake()
     return nil
 }
 
-func (s *SongService) UpdateSong(song *models.Song) error {
+// UpdateSong replaces a song. A non-zero ifVersion makes the update
+// conditional on the stored version.
+func (s *SongService) UpdateSong(song *models.Song, ifVersion int, actor string) error {
     s.logger.Info("Updating song",
         zap.Int("id", song.ID),
         zap.String("group", song.GroupName),
         zap.String("song", song.SongName))
 
-    if err := s.repo.Update(song); err != nil {
+    if err := normalizeLanguage(&song.Language); err != nil {
+        return err
+    }
+
+    if err := s.repo.Update(song, ifVersion, actor); err != nil {
         s.logger.Error("Failed to update song",
             zap.Error(err),
             zap.Int("id", song.ID))
@@ -75,10 +83,59 @@ func (s *SongService) UpdateSong(song *models.Song) error {
     return nil
 }
 
-func (s *SongService) DeleteSong(id int) error {
+// PatchSong updates only the fields set in patch.
+func (s *SongService) PatchSong(id int, patch *models.SongPatch, ifVersion int, actor string) (*models.Song, error) {
+    s.logger.Info("Patching song", zap.Int("id", id))
+
+    if patch.IsEmpty() {
+        song, err := s.GetSong(id, false)
+        if err == nil && ifVersion != 0 && song.Version != ifVersion {
+            return nil, fmt.Errorf("song with id %d has changed: %w", id, models.ErrPreconditionFailed)
+        }
+        return song, err
+    }
+
+    song, err := s.repo.Patch(id, patch, ifVersion, actor)
+    if err != nil {
+        s.logger.Error("Failed to patch song",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to patch song: %w", err)
+    }
+
+    s.logger.Info("Successfully patched song", zap.Int("id", id))
+    return song, nil
+}
+
+// ApplyJSONPatch applies RFC 6902 operations to the stored song.
+func (s *SongService) ApplyJSONPatch(id int, ops []JSONPatchOperation, ifVersion int, actor string) (*models.Song, error) {
+    song, err := s.GetSong(id, false)
+    if err != nil {
+        return nil, err
+    }
+
+    patch, err := jsonPatchToSongPatch(song, ops)
+    if err != nil {
+        return nil, err
+    }
+
+    // The operations were checked against this copy of the song, so without
+    // If-Match the write must still fail if the song has changed since.
+    if ifVersion != 0 {
+        return s.PatchSong(id, patch, ifVersion, actor)
+    }
+    patched, err := s.PatchSong(id, patch, song.Version, actor)
+    if errors.Is(err, models.ErrPreconditionFailed) {
+        return nil, fmt.Errorf("song with id %d changed while it was patched: %w", id, models.ErrConflict)
+    }
+    return patched, err
+}
+
+// DeleteSong moves a song to the trash.
+func (s *SongService) DeleteSong(id int, ifVersion int, actor string) error {
     s.logger.Info("Deleting song", zap.Int("id", id))
 
-    if err := s.repo.Delete(id); err != nil {
+    if err := s.repo.Delete(id, ifVersion, actor); err != nil {
         s.logger.Error("Failed to delete song",
             zap.Error(err),
             zap.Int("id", id))
@@ -89,10 +146,12 @@ func (s *SongService) DeleteSong(id int) error {
     return nil
 }
 
-func (s *SongService) GetSong(id int) (*models.Song, error) {
+// GetSong returns a song; songs in the trash are only found with
+// includeDeleted.
+func (s *SongService) GetSong(id int, includeDeleted bool) (*models.Song, error) {
     s.logger.Debug("Getting song by ID", zap.Int("id", id))
 
-    song, err := s.repo.GetByID(id)
+    song, err := s.repo.GetByID(id, includeDeleted)
     if err != nil {
         s.logger.Error("Failed to get song",
             zap.Error(err),
@@ -109,7 +168,14 @@ func (s *SongService) GetSongWithVerses(id int, pagination *models.VersePaginati
         zap.Int("page", pagination.Page),
         zap.Int("page_size", pagination.PageSize))
 
-    song, err := s.repo.GetByID(id)
+    if pagination.Page < 1 {
+        return nil, &models.ValidationError{Field: "verse_page", Message: "must be positive"}
+    }
+    if pagination.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "verse_size", Message: "must be positive"}
+    }
+
+    song, err := s.repo.GetByID(id, false)
     if err != nil {
         s.logger.Error("Failed to get song",
             zap.Error(err),
@@ -130,7 +196,7 @@ func (s *SongService) GetSongWithVerses(id int, pagination *models.VersePaginati
 
     // Check if page is valid
     if startIdx >= totalVerses {
-        return nil, fmt.Errorf("page number exceeds total verses")
+        return nil, fmt.Errorf("page number exceeds total verses: %w", models.ErrNotFound)
     }
 
     result := &models.SongWithVerses{
@@ -143,11 +209,41 @@ func (s *SongService) GetSongWithVerses(id int, pagination *models.VersePaginati
     return result, nil
 }
 
-func (s *SongService) ListSongs(filter *models.SongFilter) ([]models.Song, error) {
+// ListSongs returns one page of songs. filter.Cursor, when set, selects
+// keyset pagination; otherwise filter.Page is used.
+func (s *SongService) ListSongs(filter *models.SongFilter) (*models.SongList, error) {
     s.logger.Debug("Listing songs with filter",
         zap.Any("filter", filter))
 
-    songs, err := s.repo.List(filter)
+    if filter.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if filter.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+    if err := resolveSongFilter(filter); err != nil {
+        return nil, err
+    }
+    keys := filter.SortKeys
+
+    // Fuzzy matches are ranked by similarity, which cursors cannot encode.
+    rankedBySimilarity := filter.Fuzzy && len(keys) == 0
+    if rankedBySimilarity && filter.Cursor != "" {
+        return nil, &models.ValidationError{Field: "cursor", Message: "requires a sort order in fuzzy mode"}
+    }
+
+    if filter.Cursor != "" {
+        cursor, err := decodeCursor(filter.Cursor, keys)
+        if err != nil {
+            return nil, err
+        }
+        filter.Keyset = cursor
+    }
+
+    // Fetch one extra row to learn whether another page follows.
+    query := *filter
+    query.Limit = filter.PageSize + 1
+    songs, err := s.repo.List(&query)
     if err != nil {
         s.logger.Error("Failed to list songs",
             zap.Error(err),
@@ -155,5 +251,370 @@ func (s *SongService) ListSongs(filter *models.SongFilter) ([]models.Song, error
         return nil, fmt.Errorf("failed to list songs: %w", err)
     }
 
-    return songs, nil
+    total, err := s.repo.Count(filter)
+    if err != nil {
+        s.logger.Error("Failed to count songs",
+            zap.Error(err),
+            zap.Any("filter", filter))
+        return nil, fmt.Errorf("failed to count songs: %w", err)
+    }
+
+    hasMore := len(songs) > filter.PageSize
+    if hasMore {
+        if filter.Keyset != nil && filter.Keyset.Before {
+            songs = songs[1:]
+        } else {
+            songs = songs[:filter.PageSize]
+        }
+    }
+    if songs == nil {
+        songs = []models.Song{}
+    }
+
+    list := &models.SongList{
+        Items: songs,
+        Total: total,
+        PageInfo: models.PageInfo{
+            PageSize:   filter.PageSize,
+            TotalPages: (total + filter.PageSize - 1) / filter.PageSize,
+        },
+    }
+
+    switch {
+    case filter.Keyset == nil:
+        list.PageInfo.Page = filter.Page
+        list.PageInfo.HasNext = hasMore
+        list.PageInfo.HasPrev = filter.Page > 1
+    case filter.Keyset.Before:
+        list.PageInfo.HasNext = true
+        list.PageInfo.HasPrev = hasMore
+    default:
+        list.PageInfo.HasNext = hasMore
+        list.PageInfo.HasPrev = true
+    }
+
+    if len(songs) > 0 && !rankedBySimilarity {
+        if list.PageInfo.HasNext {
+            list.NextCursor = encodeCursor(&songs[len(songs)-1], keys, false)
+        }
+        if list.PageInfo.HasPrev {
+            list.PrevCursor = encodeCursor(&songs[0], keys, true)
+        }
+    }
+
+    return list, nil
+}
+
+// ExportSongs calls fn with every song matching filter, in filter.Sort
+// order or by id. Paging fields are ignored. Songs are streamed from the
+// store, so memory use does not grow with the library.
+func (s *SongService) ExportSongs(filter *models.SongFilter, fn func(song *models.Song) error) error {
+    s.logger.Info("Exporting songs", zap.Any("filter", filter))
+
+    if err := resolveSongFilter(filter); err != nil {
+        return err
+    }
+    filter.Cursor = ""
+    filter.Keyset = nil
+
+    count := 0
+    err := s.repo.Export(filter, func(song *models.Song) error {
+        count++
+        return fn(song)
+    })
+    if err != nil {
+        s.logger.Error("Failed to export songs",
+            zap.Error(err),
+            zap.Int("exported", count))
+        return fmt.Errorf("failed to export songs: %w", err)
+    }
+
+    s.logger.Info("Successfully exported songs", zap.Int("count", count))
+    return nil
+}
+
+// ListRevisions returns one page of a song's history, newest first. The
+// history of a song that is not live is only listed with includeDeleted.
+func (s *SongService) ListRevisions(id int, filter *models.RevisionFilter, includeDeleted bool) (*models.SongRevisionList, error) {
+    s.logger.Debug("Listing song revisions",
+        zap.Int("id", id),
+        zap.Any("filter", filter))
+
+    if filter.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if filter.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    revisions, total, err := s.repo.Revisions(id, filter, includeDeleted)
+    if err != nil {
+        s.logger.Error("Failed to list song revisions",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to list song revisions: %w", err)
+    }
+    if revisions == nil {
+        revisions = []models.SongRevision{}
+    }
+
+    totalPages := (total + filter.PageSize - 1) / filter.PageSize
+    return &models.SongRevisionList{
+        Items: revisions,
+        Total: total,
+        PageInfo: models.PageInfo{
+            Page:       filter.Page,
+            PageSize:   filter.PageSize,
+            TotalPages: totalPages,
+            HasNext:    filter.Page < totalPages,
+            HasPrev:    filter.Page > 1,
+        },
+    }, nil
+}
+
+func (s *SongService) GetRevision(id int, revision int, includeDeleted bool) (*models.SongRevision, error) {
+    s.logger.Debug("Getting song revision",
+        zap.Int("id", id),
+        zap.Int("revision", revision))
+
+    rev, err := s.repo.Revision(id, revision, includeDeleted)
+    if err != nil {
+        s.logger.Error("Failed to get song revision",
+            zap.Error(err),
+            zap.Int("id", id),
+            zap.Int("revision", revision))
+        return nil, fmt.Errorf("failed to get song revision: %w", err)
+    }
+    return rev, nil
+}
+
+// RestoreRevision sets a song back to how the given revision left it,
+// recreating the song if it was deleted.
+func (s *SongService) RestoreRevision(id int, revision int, ifVersion int, actor string) (*models.Song, error) {
+    s.logger.Info("Restoring song revision",
+        zap.Int("id", id),
+        zap.Int("revision", revision),
+        zap.String("actor", actor))
+
+    song, err := s.repo.RestoreRevision(id, revision, ifVersion, actor)
+    if err != nil {
+        s.logger.Error("Failed to restore song revision",
+            zap.Error(err),
+            zap.Int("id", id),
+            zap.Int("revision", revision))
+        return nil, fmt.Errorf("failed to restore song revision: %w", err)
+    }
+
+    s.logger.Info("Successfully restored song revision",
+        zap.Int("id", id),
+        zap.Int("revision", revision),
+        zap.Int("version", song.Version))
+
+    if song.EnrichmentStatus == models.EnrichmentPending {
+        s.enrichment.Wake()
+    }
+    return song, nil
+}
+
+// GetProvenance reports where each field of a song came from and which
+// fields are locked. Songs in the trash are only found with includeDeleted.
+func (s *SongService) GetProvenance(id int, includeDeleted bool) (*models.SongProvenance, error) {
+    provenance, err := s.repo.Provenance(id, includeDeleted)
+    if err != nil {
+        s.logger.Error("Failed to get song provenance",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to get song provenance: %w", err)
+    }
+    return provenance, nil
+}
+
+// SetFieldLock locks or unlocks a field against enrichment. Locked fields
+// keep their value when the song is enriched; users can still edit them.
+func (s *SongService) SetFieldLock(id int, field string, locked bool) (*models.SongProvenance, error) {
+    s.logger.Info("Setting song field lock",
+        zap.Int("id", id),
+        zap.String("field", field),
+        zap.Bool("locked", locked))
+
+    if !models.IsEnrichableField(field) {
+        return nil, &models.ValidationError{Field: "field", Message: "must be one of " + strings.Join(models.EnrichableFields, ", ")}
+    }
+
+    provenance, err := s.repo.SetFieldLock(id, field, locked)
+    if err != nil {
+        s.logger.Error("Failed to set song field lock",
+            zap.Error(err),
+            zap.Int("id", id),
+            zap.String("field", field))
+        return nil, fmt.Errorf("failed to set song field lock: %w", err)
+    }
+    return provenance, nil
+}
+
+// SearchSongs runs a full-text search over song names and lyrics.
+func (s *SongService) SearchSongs(query *models.SearchQuery) (*models.SearchResults, error) {
+    s.logger.Debug("Searching songs", zap.Any("query", query))
+
+    if strings.TrimSpace(query.Query) == "" {
+        return nil, &models.ValidationError{Field: "q", Message: "is required"}
+    }
+    // Without lang each song is matched with the configuration its search
+    // vector was built with, so stemmed lyrics match unstemmed queries.
+    if query.Language != "" {
+        if err := normalizeLanguage(&query.Language); err != nil {
+            return nil, &models.ValidationError{Field: "lang", Message: "is not a supported search language"}
+        }
+    }
+    if query.Page < 1 {
+        return nil, &models.ValidationError{Field: "page", Message: "must be positive"}
+    }
+    if query.PageSize < 1 {
+        return nil, &models.ValidationError{Field: "page_size", Message: "must be positive"}
+    }
+
+    hits, total, err := s.repo.Search(query)
+    if err != nil {
+        s.logger.Error("Failed to search songs",
+            zap.Error(err),
+            zap.Any("query", query))
+        return nil, fmt.Errorf("failed to search songs: %w", err)
+    }
+
+    for i := range hits {
+        hits[i].Snippets = matchingVerses(hits[i].Headline)
+    }
+    if hits == nil {
+        hits = []models.SearchHit{}
+    }
+
+    totalPages := (total + query.PageSize - 1) / query.PageSize
+    return &models.SearchResults{
+        Items: hits,
+        Total: total,
+        PageInfo: models.PageInfo{
+            Page:       query.Page,
+            PageSize:   query.PageSize,
+            TotalPages: totalPages,
+            HasNext:    query.Page < totalPages,
+            HasPrev:    query.Page > 1,
+        },
+    }, nil
+}
+
+// SuggestSongs returns group and song names for autocompletion.
+func (s *SongService) SuggestSongs(query *models.SuggestQuery) ([]models.Suggestion, error) {
+    query.Prefix = strings.TrimSpace(query.Prefix)
+    if query.Prefix == "" {
+        return nil, &models.ValidationError{Field: "prefix", Message: "is required"}
+    }
+    if query.Limit < 1 || query.Limit > 50 {
+        return nil, &models.ValidationError{Field: "limit", Message: "must be between 1 and 50"}
+    }
+
+    suggestions, err := s.repo.Suggest(query)
+    if err != nil {
+        s.logger.Error("Failed to suggest songs",
+            zap.Error(err),
+            zap.String("prefix", query.Prefix))
+        return nil, fmt.Errorf("failed to suggest songs: %w", err)
+    }
+    if suggestions == nil {
+        suggestions = []models.Suggestion{}
+    }
+
+    return suggestions, nil
+}
+
+// matchingVerses returns the verses of a highlighted text that contain at
+// least one <mark>ed match.
+func matchingVerses(headline string) []string {
+    snippets := []string{}
+    for _, verse := range strings.Split(strings.TrimSpace(headline), "\n\n") {
+        if strings.Contains(verse, "<mark>") {
+            snippets = append(snippets, verse)
+        }
+    }
+    return snippets
+}
+
+// normalizeLanguage defaults an empty text search language and rejects
+// unknown ones.
+func normalizeLanguage(language *string) error {
+    *language = strings.ToLower(strings.TrimSpace(*language))
+    if *language == "" {
+        *language = models.DefaultSearchLanguage
+    }
+    if !models.SearchLanguages[*language] {
+        return &models.ValidationError{Field: "language", Message: "is not a supported text search language"}
+    }
+    return nil
+}
+
+// resolveSongFilter checks the filters shared by listing and exporting and
+// fills in the release range and sort keys.
+func resolveSongFilter(filter *models.SongFilter) error {
+    if err := resolveReleaseRange(filter); err != nil {
+        return err
+    }
+
+    keys, err := models.ParseSongSort(filter.Sort)
+    if err != nil {
+        return err
+    }
+    filter.SortKeys = keys
+
+    if filter.Fuzzy && filter.GroupName == "" && filter.SongName == "" {
+        return &models.ValidationError{Field: "fuzzy", Message: "requires a group or song filter"}
+    }
+    return nil
+}
+
+// resolveReleaseRange folds the release_date, released_after,
+// released_before and year filters into filter.ReleasedFrom and
+// filter.ReleasedUntil. released_after and released_before are exclusive.
+func resolveReleaseRange(filter *models.SongFilter) error {
+    narrow := func(from, until models.Date) {
+        if !from.IsZero() && (filter.ReleasedFrom.IsZero() || from.After(filter.ReleasedFrom.Time)) {
+            filter.ReleasedFrom = from
+        }
+        if !until.IsZero() && (filter.ReleasedUntil.IsZero() || until.Before(filter.ReleasedUntil.Time)) {
+            filter.ReleasedUntil = until
+        }
+    }
+
+    dates := []struct {
+        field string
+        value string
+    }{
+        {"release_date", filter.ReleaseDate},
+        {"released_after", filter.ReleasedAfter},
+        {"released_before", filter.ReleasedBefore},
+    }
+    for _, d := range dates {
+        if d.value == "" {
+            continue
+        }
+        date, err := models.ParseDate(d.value)
+        if err != nil {
+            return &models.ValidationError{Field: d.field, Message: "must be a date in DD.MM.YYYY or YYYY-MM-DD format"}
+        }
+        switch d.field {
+        case "release_date":
+            narrow(date, date.AddDays(1))
+        case "released_after":
+            narrow(date.AddDays(1), models.Date{})
+        case "released_before":
+            narrow(models.Date{}, date)
+        }
+    }
+
+    if filter.Year != 0 {
+        if filter.Year < 1 || filter.Year > 9999 {
+            return &models.ValidationError{Field: "year", Message: "must be between 1 and 9999"}
+        }
+        narrow(models.NewDate(filter.Year, time.January, 1), models.NewDate(filter.Year+1, time.January, 1))
+    }
+
+    return nil
 }
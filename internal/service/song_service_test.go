@@ -0,0 +1,606 @@
+package service_test
+
+import (
+    "encoding/json"
+    "errors"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "music-library/internal/service"
+    "music-library/internal/testutil"
+    "strings"
+    "testing"
+    "time"
+)
+
+func createTestSong(t *testing.T, s *service.SongService, group, name string, released models.Date) *models.Song {
+    t.Helper()
+    song := &models.Song{GroupName: group, SongName: name, ReleaseDate: released}
+    if err := s.CreateSong(song, models.ActorAnonymous); err != nil {
+        t.Fatalf("CreateSong(%q, %q): %v", group, name, err)
+    }
+    return song
+}
+
+func songIDs(songs []models.Song) []int {
+    ids := make([]int, len(songs))
+    for i := range songs {
+        ids[i] = songs[i].ID
+    }
+    return ids
+}
+
+func equalIDs(a, b []int) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
+
+func TestSongLifecycleOnMemoryStore(t *testing.T) {
+    songs := testutil.NewServices().Songs
+    song := createTestSong(t, songs, "Muse", "Supermassive Black Hole", models.Date{})
+    if song.ID == 0 {
+        t.Fatal("CreateSong did not assign an id")
+    }
+
+    song.SongName = "Uprising"
+    if err := songs.UpdateSong(song, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("UpdateSong: %v", err)
+    }
+    listed, err := songs.ListSongs(&models.SongFilter{SongName: "upris", Page: 1, PageSize: 10})
+    if err != nil {
+        t.Fatalf("ListSongs: %v", err)
+    }
+    if len(listed.Items) != 1 || listed.Items[0].ID != song.ID {
+        t.Fatalf("ListSongs = %+v, want the updated song", listed)
+    }
+
+    if err := songs.DeleteSong(song.ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+    if _, err := songs.GetSong(song.ID, false); err == nil {
+        t.Fatal("GetSong found a deleted song")
+    }
+}
+
+func TestListSongsCursorPages(t *testing.T) {
+    s := testutil.NewServices().Songs
+    var want []int
+    for _, name := range []string{"Uprising", "Resistance", "Undisclosed Desires"} {
+        want = append(want, createTestSong(t, s, "Muse", name, models.Date{}).ID)
+    }
+
+    var got []int
+    cursor := ""
+    for {
+        page, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 2, Cursor: cursor})
+        if err != nil {
+            t.Fatalf("ListSongs(cursor=%q): %v", cursor, err)
+        }
+        got = append(got, songIDs(page.Items)...)
+        if page.NextCursor == "" {
+            break
+        }
+        cursor = page.NextCursor
+    }
+    if !equalIDs(got, want) {
+        t.Errorf("cursor pages = %v, want %v", got, want)
+    }
+
+    if _, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 2, Cursor: "not-a-cursor"}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("ListSongs with a bad cursor: error = %v, want a validation error", err)
+    }
+}
+
+func TestListSongsSortsByName(t *testing.T) {
+    s := testutil.NewServices().Songs
+    uprising := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    resistance := createTestSong(t, s, "Muse", "Resistance", models.Date{})
+    hysteria := createTestSong(t, s, "Muse", "Hysteria", models.Date{})
+
+    list, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, Sort: "-song"})
+    if err != nil {
+        t.Fatalf("ListSongs(sort=-song): %v", err)
+    }
+    want := []int{uprising.ID, resistance.ID, hysteria.ID}
+    if got := songIDs(list.Items); !equalIDs(got, want) {
+        t.Errorf("ListSongs(sort=-song) = %v, want %v", got, want)
+    }
+}
+
+func TestListSongsRejectsBadSort(t *testing.T) {
+    s := testutil.NewServices().Songs
+    _, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, Sort: "text"})
+    if !errors.Is(err, models.ErrValidation) {
+        t.Errorf("ListSongs(sort=text) error = %v, want a validation error", err)
+    }
+}
+
+func TestListSongsFiltersByReleaseDate(t *testing.T) {
+    s := testutil.NewServices().Songs
+    var ids []int
+    for _, date := range []models.Date{
+        models.NewDate(2001, time.March, 1),
+        models.NewDate(2006, time.July, 3),
+        models.NewDate(2009, time.September, 7),
+    } {
+        ids = append(ids, createTestSong(t, s, "Muse", date.String(), date).ID)
+    }
+
+    tests := []struct {
+        filter models.SongFilter
+        want   []int
+    }{
+        {models.SongFilter{Year: 2006}, []int{ids[1]}},
+        {models.SongFilter{ReleasedAfter: "2006-07-03"}, []int{ids[2]}},
+        {models.SongFilter{ReleasedBefore: "07.09.2009"}, []int{ids[0], ids[1]}},
+        {models.SongFilter{ReleaseDate: "2001-03-01"}, []int{ids[0]}},
+    }
+    for _, tt := range tests {
+        filter := tt.filter
+        filter.Page, filter.PageSize = 1, 10
+        list, err := s.ListSongs(&filter)
+        if err != nil {
+            t.Fatalf("ListSongs(%+v): %v", tt.filter, err)
+        }
+        if got := songIDs(list.Items); !equalIDs(got, tt.want) {
+            t.Errorf("ListSongs(%+v) = %v, want %v", tt.filter, got, tt.want)
+        }
+    }
+
+    if _, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, ReleasedAfter: "yesterday"}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("ListSongs(released_after=yesterday) error = %v, want a validation error", err)
+    }
+}
+
+func TestSearchSongsStemsWithTheSongLanguage(t *testing.T) {
+    s := testutil.NewServices().Songs
+    song := &models.Song{GroupName: "Muse", SongName: "Knights of Cydonia", Text: "No one's gonna take me alive\nKeep running", Language: "english"}
+    if err := s.CreateSong(song, models.ActorAnonymous); err != nil {
+        t.Fatalf("CreateSong: %v", err)
+    }
+
+    tests := []struct {
+        lang string
+        want int
+    }{
+        {"", 1},
+        {"english", 1},
+        {"simple", 0},
+    }
+    for _, tt := range tests {
+        results, err := s.SearchSongs(&models.SearchQuery{Query: "runs", Language: tt.lang, Page: 1, PageSize: 10})
+        if err != nil {
+            t.Fatalf("SearchSongs(runs, lang=%q): %v", tt.lang, err)
+        }
+        if results.Total != tt.want {
+            t.Errorf("SearchSongs(runs, lang=%q) total = %d, want %d", tt.lang, results.Total, tt.want)
+        }
+    }
+}
+
+func TestSearchSongsEscapesSnippets(t *testing.T) {
+    s := testutil.NewServices().Songs
+    song := &models.Song{GroupName: "Muse", SongName: "Uprising", Text: "They will not force us\n\n<img src=x onerror=alert(1)> will not force us\x02"}
+    if err := s.CreateSong(song, models.ActorAnonymous); err != nil {
+        t.Fatalf("CreateSong: %v", err)
+    }
+
+    results, err := s.SearchSongs(&models.SearchQuery{Query: "force", Page: 1, PageSize: 10})
+    if err != nil {
+        t.Fatalf("SearchSongs: %v", err)
+    }
+    if results.Total != 1 {
+        t.Fatalf("SearchSongs(force) total = %d, want 1", results.Total)
+    }
+    want := []string{
+        "They will not <mark>force</mark> us",
+        "&lt;img src=x onerror=alert(1)&gt; will not <mark>force</mark> us",
+    }
+    snippets := results.Items[0].Snippets
+    if len(snippets) != len(want) || snippets[0] != want[0] || snippets[1] != want[1] {
+        t.Errorf("snippets = %q, want %q", snippets, want)
+    }
+}
+
+func TestListSongsFuzzyMatchesTypos(t *testing.T) {
+    s := testutil.NewServices().Songs
+    uprising := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    createTestSong(t, s, "Queen", "Bohemian Rhapsody", models.Date{})
+
+    list, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, SongName: "uprisin", Fuzzy: true})
+    if err != nil {
+        t.Fatalf("ListSongs(fuzzy): %v", err)
+    }
+    if got := songIDs(list.Items); !equalIDs(got, []int{uprising.ID}) {
+        t.Errorf("ListSongs(song=uprisin, fuzzy) = %v, want %v", got, []int{uprising.ID})
+    }
+
+    if _, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, Fuzzy: true}); !errors.Is(err, models.ErrValidation) {
+        t.Errorf("ListSongs(fuzzy) without a name: error = %v, want a validation error", err)
+    }
+}
+
+func TestSuggestSongs(t *testing.T) {
+    s := testutil.NewServices().Songs
+    supermassive := createTestSong(t, s, "Muse", "Supermassive Black Hole", models.Date{})
+    createTestSong(t, s, "Supergrass", "Alright", models.Date{})
+    createTestSong(t, s, "Queen", "Bohemian Rhapsody", models.Date{})
+
+    suggestions, err := s.SuggestSongs(&models.SuggestQuery{Prefix: "super", Limit: 10})
+    if err != nil {
+        t.Fatalf("SuggestSongs: %v", err)
+    }
+    var groups, songs int
+    for _, suggestion := range suggestions {
+        switch {
+        case suggestion.Kind == "group" && suggestion.Text == "Supergrass":
+            groups++
+        case suggestion.Kind == "song" && suggestion.SongID == supermassive.ID:
+            songs++
+        default:
+            t.Errorf("unexpected suggestion %+v", suggestion)
+        }
+    }
+    if groups != 1 || songs != 1 {
+        t.Errorf("SuggestSongs(super) = %+v, want Supergrass and Supermassive Black Hole", suggestions)
+    }
+}
+
+func TestSuggestSongsSkipsArtistsWithoutLiveSongs(t *testing.T) {
+    services := testutil.NewServices()
+    createTestSong(t, services.Songs, "Muse", "Uprising", models.Date{})
+    if err := services.Artists.CreateArtist(&models.Artist{Name: "Mumford & Sons"}); err != nil {
+        t.Fatalf("CreateArtist: %v", err)
+    }
+    trashed := createTestSong(t, services.Songs, "Muzz", "Bad Feeling", models.Date{})
+    if err := services.Songs.DeleteSong(trashed.ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+
+    suggestions, err := services.Songs.SuggestSongs(&models.SuggestQuery{Prefix: "mu", Limit: 10})
+    if err != nil {
+        t.Fatalf("SuggestSongs: %v", err)
+    }
+    if len(suggestions) != 1 || suggestions[0].Text != "Muse" {
+        t.Errorf("SuggestSongs(mu) = %+v, want only Muse", suggestions)
+    }
+}
+
+func TestImportSongsSkipsDuplicates(t *testing.T) {
+    s := testutil.NewServices().Songs
+    createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    trashed := createTestSong(t, s, "Muse", "Resistance", models.Date{})
+    if err := s.DeleteSong(trashed.ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+
+    file := `{"group":"muse","song":" uprising "}
+{"group":"Muse","song":"Resistance"}
+{"group":"Muse","song":"Starlight"}
+{"group":"MUSE","song":"starlight"}
+{"group":"Muse"}
+`
+    // A song in the trash is not a duplicate.
+    want := []models.ImportStatus{models.ImportDuplicate, models.ImportCreated, models.ImportCreated, models.ImportDuplicate, models.ImportFailed}
+    for _, dryRun := range []bool{true, false} {
+        report, err := s.ImportSongs(strings.NewReader(file), models.ImportFormatNDJSON, dryRun, models.ActorAnonymous)
+        if err != nil {
+            t.Fatalf("ImportSongs(dry run %t): %v", dryRun, err)
+        }
+        if len(report.Results) != len(want) {
+            t.Fatalf("results = %v, want %d rows", report.Results, len(want))
+        }
+        for i, result := range report.Results {
+            if result.Status != want[i] {
+                t.Errorf("dry run %t: row %d status = %s, want %s", dryRun, result.Row, result.Status, want[i])
+            }
+        }
+    }
+
+    list, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10})
+    if err != nil {
+        t.Fatalf("ListSongs: %v", err)
+    }
+    if list.Total != 3 {
+        t.Errorf("songs after import = %d, want 3", list.Total)
+    }
+}
+
+func TestCreateSongWithoutReleaseDate(t *testing.T) {
+    s := testutil.NewServices().Songs
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+
+    song, err := s.GetSong(created.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if !song.ReleaseDate.IsZero() {
+        t.Errorf("release date = %s, want none", song.ReleaseDate)
+    }
+    if song.Version != 1 {
+        t.Errorf("version = %d, want 1", song.Version)
+    }
+}
+
+func TestListSongsSortsMissingReleaseDatesLast(t *testing.T) {
+    s := testutil.NewServices().Songs
+    undated := createTestSong(t, s, "Muse", "Undated", models.Date{})
+    older := createTestSong(t, s, "Muse", "Older", models.NewDate(2001, time.March, 1))
+    newer := createTestSong(t, s, "Muse", "Newer", models.NewDate(2009, time.June, 1))
+
+    tests := []struct {
+        sort string
+        want []int
+    }{
+        {"release_date", []int{older.ID, newer.ID, undated.ID}},
+        {"-release_date", []int{newer.ID, older.ID, undated.ID}},
+    }
+    for _, tt := range tests {
+        list, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, Sort: tt.sort})
+        if err != nil {
+            t.Fatalf("ListSongs(sort=%s): %v", tt.sort, err)
+        }
+        if got := songIDs(list.Items); !equalIDs(got, tt.want) {
+            t.Errorf("ListSongs(sort=%s) = %v, want %v", tt.sort, got, tt.want)
+        }
+    }
+}
+
+func TestListSongsCursorPagesOverMissingReleaseDates(t *testing.T) {
+    s := testutil.NewServices().Songs
+    dates := []models.Date{
+        {},
+        models.NewDate(2005, time.May, 1),
+        {},
+        models.NewDate(2001, time.January, 1),
+        models.NewDate(2005, time.May, 1),
+    }
+    for i, date := range dates {
+        createTestSong(t, s, "Muse", string(rune('A'+i)), date)
+    }
+
+    for _, sort := range []string{"release_date", "-release_date"} {
+        all, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10, Sort: sort})
+        if err != nil {
+            t.Fatalf("ListSongs(sort=%s): %v", sort, err)
+        }
+        want := songIDs(all.Items)
+
+        // Page forwards one song at a time, then back again.
+        var forward []int
+        var last *models.SongList
+        cursor := ""
+        for {
+            page, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 1, Sort: sort, Cursor: cursor})
+            if err != nil {
+                t.Fatalf("ListSongs(sort=%s, cursor=%q): %v", sort, cursor, err)
+            }
+            forward = append(forward, songIDs(page.Items)...)
+            last = page
+            if page.NextCursor == "" {
+                break
+            }
+            cursor = page.NextCursor
+        }
+        if !equalIDs(forward, want) {
+            t.Errorf("forward pages (sort=%s) = %v, want %v", sort, forward, want)
+        }
+
+        backward := songIDs(last.Items)
+        cursor = last.PrevCursor
+        for cursor != "" {
+            page, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 1, Sort: sort, Cursor: cursor})
+            if err != nil {
+                t.Fatalf("ListSongs(sort=%s, cursor=%q): %v", sort, cursor, err)
+            }
+            backward = append(songIDs(page.Items), backward...)
+            cursor = page.PrevCursor
+        }
+        if !equalIDs(backward, want) {
+            t.Errorf("backward pages (sort=%s) = %v, want %v", sort, backward, want)
+        }
+    }
+}
+
+func TestGetMissingSongIsNotFound(t *testing.T) {
+    songs := testutil.NewServices().Songs
+
+    if _, err := songs.GetSong(42, false); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("GetSong error = %v, want ErrNotFound", err)
+    }
+    if err := songs.DeleteSong(42, 0, models.ActorAnonymous); !errors.Is(err, models.ErrNotFound) {
+        t.Fatalf("DeleteSong error = %v, want ErrNotFound", err)
+    }
+}
+
+func TestUpdateSongChecksVersion(t *testing.T) {
+    s := testutil.NewServices().Songs
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+
+    stale := *created
+    stale.Text = "They will not force us"
+    if err := s.UpdateSong(&stale, created.Version+1, models.ActorAnonymous); !errors.Is(err, models.ErrPreconditionFailed) {
+        t.Fatalf("UpdateSong with a stale version: error = %v, want precondition failed", err)
+    }
+
+    update := *created
+    update.Link = "https://example.com/uprising"
+    if err := s.UpdateSong(&update, created.Version, models.ActorAnonymous); err != nil {
+        t.Fatalf("UpdateSong: %v", err)
+    }
+    song, err := s.GetSong(created.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if song.Version != created.Version+1 || song.Link != update.Link {
+        t.Errorf("song = version %d, link %q; want version %d, link %q",
+            song.Version, song.Link, created.Version+1, update.Link)
+    }
+}
+
+func TestDeleteSongMovesItToTheTrash(t *testing.T) {
+    s := testutil.NewServices().Songs
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+
+    if err := s.DeleteSong(created.ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+    if _, err := s.GetSong(created.ID, false); !errors.Is(err, models.ErrNotFound) {
+        t.Errorf("GetSong after delete: error = %v, want not found", err)
+    }
+    song, err := s.GetSong(created.ID, true)
+    if err != nil {
+        t.Fatalf("GetSong including deleted: %v", err)
+    }
+    if song.DeletedAt == nil {
+        t.Error("deleted song has no deleted_at")
+    }
+
+    list, err := s.ListSongs(&models.SongFilter{Page: 1, PageSize: 10})
+    if err != nil {
+        t.Fatalf("ListSongs: %v", err)
+    }
+    if list.Total != 0 {
+        t.Errorf("ListSongs total = %d, want 0", list.Total)
+    }
+}
+
+func TestDeleteAndRestoreBumpTheVersion(t *testing.T) {
+    s := testutil.NewServices().Songs
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+
+    if err := s.DeleteSong(created.ID, created.Version, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+    deleted, err := s.GetSong(created.ID, true)
+    if err != nil {
+        t.Fatalf("GetSong including deleted: %v", err)
+    }
+    if deleted.Version != created.Version+1 {
+        t.Errorf("version after delete = %d, want %d", deleted.Version, created.Version+1)
+    }
+
+    if _, err := s.RestoreSong(created.ID, created.Version, models.ActorAnonymous); !errors.Is(err, models.ErrPreconditionFailed) {
+        t.Errorf("RestoreSong with the version from before the delete: error = %v, want precondition failed", err)
+    }
+    restored, err := s.RestoreSong(created.ID, deleted.Version, models.ActorAnonymous)
+    if err != nil {
+        t.Fatalf("RestoreSong: %v", err)
+    }
+    if restored.Version != created.Version+2 {
+        t.Errorf("version after restore = %d, want %d", restored.Version, created.Version+2)
+    }
+
+    update := *restored
+    update.Text = "They will not force us"
+    if err := s.UpdateSong(&update, deleted.Version, models.ActorAnonymous); !errors.Is(err, models.ErrPreconditionFailed) {
+        t.Errorf("UpdateSong with the version from the trash: error = %v, want precondition failed", err)
+    }
+}
+
+func TestSetFieldLockOnTrashedSong(t *testing.T) {
+    s := testutil.NewServices().Songs
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    if err := s.DeleteSong(created.ID, 0, models.ActorAnonymous); err != nil {
+        t.Fatalf("DeleteSong: %v", err)
+    }
+
+    if _, err := s.SetFieldLock(created.ID, models.FieldText, true); !errors.Is(err, models.ErrNotFound) {
+        t.Errorf("SetFieldLock on a trashed song: error = %v, want not found", err)
+    }
+}
+
+// racingSongStore runs race once, right after the first song is read, as if
+// another client wrote to it in between.
+type racingSongStore struct {
+    repository.SongStore
+    race func()
+}
+
+func (r *racingSongStore) GetByID(id int, includeDeleted bool) (*models.Song, error) {
+    song, err := r.SongStore.GetByID(id, includeDeleted)
+    if race := r.race; race != nil {
+        r.race = nil
+        race()
+    }
+    return song, err
+}
+
+func TestApplyJSONPatchFailsIfTheSongChanges(t *testing.T) {
+    services := testutil.NewServices()
+    store := &racingSongStore{SongStore: repository.NewMemorySongRepository(services.DB)}
+    s := service.NewSongService(store, services.Enrichment, services.Logger)
+    created := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+
+    store.race = func() {
+        update := *created
+        update.Text = "They will not force us"
+        if err := store.SongStore.Update(&update, 0, models.ActorAnonymous); err != nil {
+            t.Errorf("Update: %v", err)
+        }
+    }
+    ops := []service.JSONPatchOperation{
+        {Op: "test", Path: "/text", Value: json.RawMessage(`""`)},
+        {Op: "replace", Path: "/text", Value: json.RawMessage(`"Paranoia is in bloom"`)},
+    }
+    if _, err := s.ApplyJSONPatch(created.ID, ops, 0, models.ActorAnonymous); !errors.Is(err, models.ErrConflict) {
+        t.Fatalf("ApplyJSONPatch after a concurrent write: error = %v, want a conflict", err)
+    }
+
+    song, err := s.GetSong(created.ID, false)
+    if err != nil {
+        t.Fatalf("GetSong: %v", err)
+    }
+    if song.Text != "They will not force us" {
+        t.Errorf("text = %q, want the concurrent write kept", song.Text)
+    }
+}
+
+func TestRestoreRevision(t *testing.T) {
+    s := testutil.NewServices().Songs
+    song := createTestSong(t, s, "Muse", "Uprising", models.Date{})
+    update := *song
+    update.Text = "Paranoia is in bloom"
+    if err := s.UpdateSong(&update, 0, "alice"); err != nil {
+        t.Fatalf("UpdateSong: %v", err)
+    }
+
+    restored, err := s.RestoreRevision(song.ID, 1, update.Version, "bob")
+    if err != nil {
+        t.Fatalf("RestoreRevision: %v", err)
+    }
+    if restored.Text != "" || restored.Version != update.Version+1 {
+        t.Errorf("restored song = version %d, text %q; want version %d and no text",
+            restored.Version, restored.Text, update.Version+1)
+    }
+
+    list, err := s.ListRevisions(song.ID, &models.RevisionFilter{Page: 1, PageSize: 10}, false)
+    if err != nil {
+        t.Fatalf("ListRevisions: %v", err)
+    }
+    var actions []models.RevisionAction
+    for _, rev := range list.Items {
+        actions = append(actions, rev.Action)
+    }
+    want := []models.RevisionAction{models.RevisionRestore, models.RevisionUpdate, models.RevisionCreate}
+    if len(actions) != len(want) || actions[0] != want[0] || actions[1] != want[1] || actions[2] != want[2] {
+        t.Fatalf("revision actions = %v, want %v", actions, want)
+    }
+    if list.Items[0].Actor != "bob" || list.Items[0].RestoredFrom != 1 || list.Items[1].Actor != "alice" {
+        t.Errorf("revisions = %+v", list.Items)
+    }
+
+    rev, err := s.GetRevision(song.ID, 2, false)
+    if err != nil {
+        t.Fatalf("GetRevision: %v", err)
+    }
+    if len(rev.Changes) != 1 || rev.Changes[0] != (models.FieldChange{Field: models.FieldText, New: update.Text}) {
+        t.Errorf("changes of revision 2 = %+v", rev.Changes)
+    }
+}
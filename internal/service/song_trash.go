@@ -0,0 +1,71 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "time"
+)
+
+// purgeBatchSize is the number of songs removed per transaction.
+const purgeBatchSize = 100
+
+// RestoreSong takes a song out of the trash.
+func (s *SongService) RestoreSong(id int, ifVersion int, actor string) (*models.Song, error) {
+    s.logger.Info("Restoring song",
+        zap.Int("id", id),
+        zap.String("actor", actor))
+
+    song, err := s.repo.Restore(id, ifVersion, actor)
+    if err != nil {
+        s.logger.Error("Failed to restore song",
+            zap.Error(err),
+            zap.Int("id", id))
+        return nil, fmt.Errorf("failed to restore song: %w", err)
+    }
+
+    s.logger.Info("Successfully restored song", zap.Int("id", id))
+    return song, nil
+}
+
+// PurgeTrash permanently removes the songs that have been in the trash for
+// longer than retention and returns how many it removed.
+func (s *SongService) PurgeTrash(retention time.Duration) (int, error) {
+    cutoff := time.Now().Add(-retention)
+    total := 0
+    for {
+        n, err := s.repo.PurgeDeleted(cutoff, purgeBatchSize)
+        total += n
+        if err != nil {
+            return total, fmt.Errorf("failed to purge trash: %w", err)
+        }
+        if n < purgeBatchSize {
+            return total, nil
+        }
+    }
+}
+
+// RunTrashPurge calls PurgeTrash every interval until ctx is done.
+func (s *SongService) RunTrashPurge(ctx context.Context, retention, interval time.Duration) {
+    s.logger.Info("Starting trash purge",
+        zap.Duration("retention", retention),
+        zap.Duration("interval", interval))
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        n, err := s.PurgeTrash(retention)
+        if err != nil {
+            s.logger.Error("Failed to purge trash", zap.Error(err), zap.Int("purged", n))
+        } else if n > 0 {
+            s.logger.Info("Purged songs from the trash", zap.Int("count", n))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
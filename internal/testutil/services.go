@@ -0,0 +1,52 @@
+// Package testutil wires the services on an in-memory store for tests.
+package testutil
+
+import (
+    "context"
+    "go.uber.org/zap"
+    "music-library/internal/models"
+    "music-library/internal/repository"
+    "music-library/internal/service"
+)
+
+// NoSongInfo is a song info client that never finds anything.
+type NoSongInfo struct{}
+
+func (NoSongInfo) GetSongInfo(ctx context.Context, group, song string) (*models.SongDetail, error) {
+    return nil, service.ErrSongInfoNotFound
+}
+
+// Services holds every service on one empty memory store. The enrichment
+// workers are not started, so new songs stay pending unless a test works
+// off their jobs through EnrichmentStore.
+type Services struct {
+    DB              *repository.MemoryDB
+    EnrichmentStore repository.EnrichmentStore
+    Logger          *zap.Logger
+
+    Songs      *service.SongService
+    Enrichment *service.EnrichmentService
+    Artists    *service.ArtistService
+    Albums     *service.AlbumService
+    Playlists  *service.PlaylistService
+    Health     *service.HealthService
+}
+
+func NewServices() *Services {
+    db := repository.NewMemoryDB()
+    logger := zap.NewNop()
+    enrichmentStore := repository.NewMemoryEnrichmentRepository(db)
+    enrichment := service.NewEnrichmentService(enrichmentStore, NoSongInfo{}, 1, 1, logger)
+    songs := service.NewSongService(repository.NewMemorySongRepository(db), enrichment, logger)
+    return &Services{
+        DB:              db,
+        EnrichmentStore: enrichmentStore,
+        Logger:          logger,
+        Songs:           songs,
+        Enrichment:      enrichment,
+        Artists:         service.NewArtistService(repository.NewMemoryArtistRepository(db), songs, logger),
+        Albums:          service.NewAlbumService(repository.NewMemoryAlbumRepository(db), logger),
+        Playlists:       service.NewPlaylistService(repository.NewMemoryPlaylistRepository(db), logger),
+        Health:          service.NewHealthService(logger),
+    }
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_songs_updated_at_id;
+DROP INDEX IF EXISTS idx_songs_created_at_id;
+DROP INDEX IF EXISTS idx_songs_release_date_id;
+DROP INDEX IF EXISTS idx_songs_song_name_id;
+DROP INDEX IF EXISTS idx_songs_group_name_id;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS language;
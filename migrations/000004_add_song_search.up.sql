@@ -0,0 +1,10 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS language REGCONFIG NOT NULL DEFAULT 'simple';
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, group_name), 'A') ||
+        setweight(to_tsvector(language, song_name), 'A') ||
+        setweight(to_tsvector(language, text), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
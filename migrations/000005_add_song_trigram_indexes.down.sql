@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_songs_song_name_trgm;
+DROP INDEX IF EXISTS idx_songs_group_name_trgm;
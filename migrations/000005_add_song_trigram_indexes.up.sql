@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_trgm ON songs USING GIN (group_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_trgm ON songs USING GIN (song_name gin_trgm_ops);
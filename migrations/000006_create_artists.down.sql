@@ -0,0 +1,26 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS group_name VARCHAR(255);
+
+UPDATE songs
+SET group_name = artists.name
+FROM artists
+WHERE artists.id = songs.artist_id;
+
+ALTER TABLE songs ALTER COLUMN group_name SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE songs ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, group_name), 'A') ||
+        setweight(to_tsvector(language, song_name), 'A') ||
+        setweight(to_tsvector(language, text), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_id ON songs (group_name, id);
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_trgm ON songs USING GIN (group_name gin_trgm_ops);
+
+DROP INDEX IF EXISTS idx_songs_artist_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS artist_id;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS artists (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    name_key TEXT GENERATED ALWAYS AS (lower(regexp_replace(btrim(name), '\s+', ' ', 'g'))) STORED,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT artists_name_key_unique UNIQUE (name_key)
+);
+
+-- One artist per distinct group name, ignoring case and surrounding or
+-- repeated whitespace. The spelling of the oldest song wins.
+INSERT INTO artists (name)
+SELECT DISTINCT ON (lower(regexp_replace(btrim(group_name), '\s+', ' ', 'g')))
+    regexp_replace(btrim(group_name), '\s+', ' ', 'g')
+FROM songs
+ORDER BY lower(regexp_replace(btrim(group_name), '\s+', ' ', 'g')), id
+ON CONFLICT (name_key) DO NOTHING;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist_id INTEGER REFERENCES artists (id);
+
+UPDATE songs
+SET artist_id = artists.id
+FROM artists
+WHERE artists.name_key = lower(regexp_replace(btrim(songs.group_name), '\s+', ' ', 'g'));
+
+ALTER TABLE songs ALTER COLUMN artist_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_songs_artist_id ON songs (artist_id, id);
+
+-- The song search vector included group_name; artist names are now searched
+-- through artists.search_vector instead.
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS group_name;
+
+ALTER TABLE songs ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, song_name), 'A') ||
+        setweight(to_tsvector(language, text), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_artists_name_id ON artists (name, id);
+CREATE INDEX IF NOT EXISTS idx_artists_name_trgm ON artists USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_artists_search_vector ON artists USING GIN (search_vector);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS albums (
+    id SERIAL PRIMARY KEY,
+    artist_id INTEGER NOT NULL REFERENCES artists (id),
+    title VARCHAR(255) NOT NULL,
+    release_date DATE,
+    cover_link TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_albums_artist_id ON albums (artist_id, id);
+CREATE INDEX IF NOT EXISTS idx_albums_title_id ON albums (title, id);
+
+-- The ordered track listing. A song can appear on several albums, but only
+-- once per album.
+CREATE TABLE IF NOT EXISTS album_tracks (
+    album_id INTEGER NOT NULL REFERENCES albums (id) ON DELETE CASCADE,
+    song_id INTEGER NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL CHECK (position > 0),
+    PRIMARY KEY (album_id, song_id),
+    CONSTRAINT album_tracks_position_unique UNIQUE (album_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_tracks_song_id ON album_tracks (song_id);
@@ -0,0 +1,2 @@
+-- Bulk import finds duplicates by artist and case-insensitive song name.
+CREATE INDEX IF NOT EXISTS idx_songs_artist_name_key ON songs (artist_id, lower(btrim(song_name)));
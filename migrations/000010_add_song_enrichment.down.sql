@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS enrichment_jobs;
+
+-- Fails while songs without a release date remain; fill them in or delete
+-- them first.
+ALTER TABLE songs ALTER COLUMN release_date SET NOT NULL;
+
+ALTER TABLE songs
+    DROP COLUMN IF EXISTS enrichment_error,
+    DROP COLUMN IF EXISTS enrichment_status;
@@ -0,0 +1,25 @@
+-- Songs are stored right away and filled in from the music info API in the
+-- background. Songs created before this migration were enriched on create.
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR(16) NOT NULL DEFAULT 'enriched'
+        CONSTRAINT songs_enrichment_status_check CHECK (enrichment_status IN ('pending', 'enriched', 'failed')),
+    ADD COLUMN IF NOT EXISTS enrichment_error TEXT NOT NULL DEFAULT '';
+
+-- New songs are stored before enrichment looks up their release date.
+ALTER TABLE songs ALTER COLUMN release_date DROP NOT NULL;
+
+-- One job per song. Workers claim due jobs with FOR UPDATE SKIP LOCKED and
+-- lease them until locked_until, so a job held by a crashed worker is picked
+-- up again once its lease runs out.
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT enrichment_jobs_song_id_unique UNIQUE (song_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_jobs_run_at ON enrichment_jobs (run_at);
@@ -0,0 +1,3 @@
+ALTER TABLE enrichment_jobs DROP COLUMN IF EXISTS refresh;
+
+DROP TABLE IF EXISTS song_info_cache;
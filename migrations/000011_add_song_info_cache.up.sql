@@ -0,0 +1,15 @@
+-- Lookups in the music info API, shared by all server instances. Rows with
+-- not_found set record that the API does not know the song. Expired rows
+-- are overwritten by the next lookup of the same song.
+CREATE TABLE IF NOT EXISTS song_info_cache (
+    key TEXT PRIMARY KEY,
+    release_date DATE,
+    text TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    not_found BOOLEAN NOT NULL DEFAULT false,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Set by a Cache-Control: no-cache request to bypass the cache.
+ALTER TABLE enrichment_jobs ADD COLUMN IF NOT EXISTS refresh BOOLEAN NOT NULL DEFAULT false;
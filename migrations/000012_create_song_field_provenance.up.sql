@@ -0,0 +1,8 @@
+-- The provider that supplied each enriched song field, keyed by the field's
+-- JSON name, e.g. (1, 'text', 'catalogue').
+CREATE TABLE IF NOT EXISTS song_field_provenance (
+    song_id INTEGER NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    field VARCHAR(32) NOT NULL,
+    source VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (song_id, field)
+);
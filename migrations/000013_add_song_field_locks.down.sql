@@ -0,0 +1,6 @@
+-- Only the sources of enriched fields were recorded before.
+DELETE FROM song_field_provenance WHERE source IN ('', 'user', 'import');
+
+ALTER TABLE song_field_provenance
+    DROP COLUMN IF EXISTS locked,
+    DROP COLUMN IF EXISTS updated_at;
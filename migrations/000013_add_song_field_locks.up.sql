@@ -0,0 +1,11 @@
+-- Sources are recorded for every field now: "user", "import" or the name of
+-- a song info provider. A locked field is never overwritten by enrichment; a
+-- lock can exist before the field has a recorded source.
+ALTER TABLE song_field_provenance
+    ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE song_field_provenance p
+SET updated_at = s.updated_at
+FROM songs s
+WHERE s.id = p.song_id;
@@ -0,0 +1,36 @@
+-- Every create, update, delete and restore of a song, written in the same
+-- transaction as the change. song holds the song JSON as the revision left
+-- it. There is no foreign key, so the history outlives the song.
+CREATE TABLE IF NOT EXISTS song_revisions (
+    song_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL
+        CONSTRAINT song_revisions_action_check CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    actor VARCHAR(128) NOT NULL,
+    version INTEGER NOT NULL,
+    restored_from INTEGER,
+    changes JSONB NOT NULL DEFAULT '[]',
+    song JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (song_id, revision)
+);
+
+-- Existing songs start their history with their current state.
+INSERT INTO song_revisions (song_id, revision, action, actor, version, song, created_at)
+SELECT s.id, 1, 'create', 'migration', s.version,
+    jsonb_build_object(
+        'id', s.id,
+        'artist_id', s.artist_id,
+        'group', a.name,
+        'song', s.song_name,
+        'releaseDate', COALESCE(to_char(s.release_date, 'YYYY-MM-DD'), ''),
+        'text', s.text,
+        'link', s.link,
+        'language', s.language,
+        'version', s.version,
+        'created_at', s.created_at,
+        'updated_at', s.updated_at,
+        'enrichment_status', s.enrichment_status),
+    s.updated_at
+FROM songs s JOIN artists a ON a.id = s.artist_id
+ON CONFLICT DO NOTHING;
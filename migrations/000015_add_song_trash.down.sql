@@ -0,0 +1,12 @@
+-- Songs in the trash would otherwise come back.
+DELETE FROM songs WHERE deleted_at IS NOT NULL;
+
+DELETE FROM song_revisions WHERE action = 'purge';
+
+ALTER TABLE song_revisions
+    DROP CONSTRAINT IF EXISTS song_revisions_action_check,
+    ADD CONSTRAINT song_revisions_action_check CHECK (action IN ('create', 'update', 'delete', 'restore'));
+
+DROP INDEX IF EXISTS idx_songs_deleted_at;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Deleted songs go to the trash: deleted_at is set and they are hidden until
+-- restored, or purged once they have been there long enough.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_songs_deleted_at ON songs (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE song_revisions
+    DROP CONSTRAINT IF EXISTS song_revisions_action_check,
+    ADD CONSTRAINT song_revisions_action_check CHECK (action IN ('create', 'update', 'delete', 'restore', 'purge'));